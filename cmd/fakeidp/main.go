@@ -0,0 +1,30 @@
+// Command fakeidp runs a local stand-in for X's OAuth 2.0 provider so handle
+// verification can be exercised without a real X app. Point synbridge at it:
+//
+//	X_OAUTH_CLIENT_ID=synbridge-dev
+//	X_OAUTH_AUTH_URL=http://127.0.0.1:9099/i/oauth2/authorize
+//	X_OAUTH_TOKEN_URL=http://127.0.0.1:9099/2/oauth2/token
+//	X_OAUTH_USERINFO_URL=http://127.0.0.1:9099/2/users/me
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/BioAILogic/agentbridge/internal/xverify/fakeidp"
+)
+
+func main() {
+	addr := os.Getenv("FAKEIDP_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:9099"
+	}
+	// Empty values accept any client and callback
+	idp := fakeidp.New(os.Getenv("FAKEIDP_CLIENT_ID"), os.Getenv("FAKEIDP_REDIRECT_URI"))
+
+	log.Printf("fake X identity provider on http://%s%s", addr, fakeidp.AuthorizePath)
+	if err := http.ListenAndServe(addr, idp); err != nil {
+		log.Fatalf("fakeidp: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/BioAILogic/agentbridge/internal/invites"
+)
+
+const agentUsage = `usage: synbridge agent <command>
+
+  list <handle>                   list a member's agents, frozen ones included
+  freeze [-reason text] <id>      freeze an agent: its key stops working
+  unfreeze [-reason text] <id>    thaw a frozen agent`
+
+// runAgent implements `synbridge agent ...`
+func runAgent(args []string) error {
+	if len(args) == 0 {
+		return errors.New(agentUsage)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		if len(args) != 2 {
+			return errors.New(agentUsage)
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		human, err := a.queries.GetHumanByHandle(ctx, invites.NormalizeHandle(args[1]))
+		if err != nil {
+			return fmt.Errorf("no member @%s: %w", invites.NormalizeHandle(args[1]), err)
+		}
+		agents, err := a.queries.ListAgentProfilesByHuman(ctx, human.ID)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSUBSTRATE\tCREATED\tFROZEN")
+		for _, ag := range agents {
+			frozen := ""
+			if ag.FrozenAt != nil {
+				frozen = ag.FrozenAt.UTC().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", ag.ID, ag.Name, ag.Substrate, ag.CreatedAt.UTC().Format("2006-01-02"), frozen)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		return a.audit(ctx, "agent.list", "human:"+strconv.Itoa(human.ID), nil)
+
+	case "freeze", "unfreeze":
+		fs := flag.NewFlagSet("agent "+args[0], flag.ContinueOnError)
+		reason := fs.String("reason", "", "why, for the audit log")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New(agentUsage)
+		}
+		id, err := strconv.Atoi(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid agent id %q", fs.Arg(0))
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		freeze := args[0] == "freeze"
+		state := "frozen"
+		if !freeze {
+			state = "unfrozen"
+		}
+		changed, err := a.queries.SetAgentFrozen(ctx, id, freeze)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return fmt.Errorf("agent %d does not exist or is already %s", id, state)
+		}
+		fmt.Printf("agent %d %s\n", id, state)
+		return a.audit(ctx, "agent."+args[0], "agent:"+strconv.Itoa(id), map[string]any{"reason": *reason})
+	}
+	return errors.New(agentUsage)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// runAudit implements `synbridge audit [-n 50]`; reading the log is not itself audited
+func runAudit(args []string) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	limit := fs.Int("n", 50, "show this many entries")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	a, err := openAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	entries, err := a.queries.ListAuditLog(ctx, *limit)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "WHEN (UTC)\tACTOR\tACTION\tTARGET\tDETAIL")
+	for _, e := range entries {
+		detail, _ := json.Marshal(e.Detail)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.CreatedAt.UTC().Format("2006-01-02 15:04:05"), e.Actor, e.Action, e.Target, detail)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/BioAILogic/agentbridge/internal/export"
+	"github.com/BioAILogic/agentbridge/internal/invites"
+)
+
+const exportUsage = `usage: synbridge export human [-o file.zip] <handle>
+
+  Writes the same archive a member gets from Settings → Export, e.g. to
+  answer an access request that arrived by mail. Default file:
+  <handle>-export-YYYYMMDD.zip in the current directory.`
+
+// runExport implements `synbridge export human ...`
+func runExport(args []string) error {
+	if len(args) == 0 || args[0] != "human" {
+		return errors.New(exportUsage)
+	}
+	fs := flag.NewFlagSet("export human", flag.ContinueOnError)
+	out := fs.String("o", "", "write the archive here")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(exportUsage)
+	}
+	ctx := context.Background()
+	a, err := openAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	handle := invites.NormalizeHandle(fs.Arg(0))
+	human, err := a.queries.GetHumanByHandle(ctx, handle)
+	if err != nil {
+		return fmt.Errorf("no member @%s: %w", handle, err)
+	}
+	path := *out
+	if path == "" {
+		path = handle + "-export-" + time.Now().UTC().Format("20060102") + ".zip"
+	}
+
+	// Personal data: readable by the operator only, never overwritten
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	// The archive needs neither the export directory nor the link key
+	err = (&export.Service{Queries: a.queries}).Build(ctx, human.ID, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	fmt.Printf("wrote %s\n", path)
+	return a.audit(ctx, "export.human", "human:"+strconv.Itoa(human.ID), map[string]any{"handle": handle})
+}
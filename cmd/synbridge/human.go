@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BioAILogic/agentbridge/internal/invites"
+	"github.com/BioAILogic/agentbridge/internal/jurisdiction"
+)
+
+const humanUsage = `usage: synbridge human <command>
+
+  suspend [-reason text] <handle>    sign a member out everywhere and block
+                                     sign-in and their agents' keys
+  unsuspend <handle>                 lift a suspension
+  verify-jurisdiction -provider name <handle> <EU-EEA|non-EEA|none>
+                                     record the class a verification such
+                                     as a phone or ID check established;
+                                     none clears it`
+
+// runHuman implements `synbridge human ...`
+func runHuman(args []string) error {
+	if len(args) == 0 {
+		return errors.New(humanUsage)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "suspend", "unsuspend":
+		fs := flag.NewFlagSet("human "+args[0], flag.ContinueOnError)
+		reason := fs.String("reason", "", "why; kept with the account and in the audit log")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			return errors.New(humanUsage)
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		handle := invites.NormalizeHandle(fs.Arg(0))
+		human, err := a.queries.GetHumanByHandle(ctx, handle)
+		if err != nil {
+			return fmt.Errorf("no member @%s: %w", handle, err)
+		}
+		var changed bool
+		if args[0] == "suspend" {
+			changed, err = a.queries.SuspendHuman(ctx, human.ID, *reason)
+		} else {
+			changed, err = a.queries.UnsuspendHuman(ctx, human.ID)
+		}
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return fmt.Errorf("@%s is already %sed", handle, args[0])
+		}
+		fmt.Printf("@%s %sed\n", handle, args[0])
+		return a.audit(ctx, "human."+args[0], "human:"+strconv.Itoa(human.ID), map[string]any{"handle": handle, "reason": *reason})
+
+	case "verify-jurisdiction":
+		fs := flag.NewFlagSet("human verify-jurisdiction", flag.ContinueOnError)
+		provider := fs.String("provider", "", "who verified the member, e.g. \"phone\" or \"id-check\"; kept with the class")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 2 {
+			return errors.New(humanUsage)
+		}
+		var class *string
+		if fs.Arg(1) != "none" {
+			c := jurisdiction.Normalize(fs.Arg(1))
+			if c == "" {
+				return fmt.Errorf("jurisdiction must be %s or none", strings.Join(jurisdiction.Classes, ", "))
+			}
+			if strings.TrimSpace(*provider) == "" {
+				return errors.New("-provider is required")
+			}
+			class = &c
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		handle := invites.NormalizeHandle(fs.Arg(0))
+		human, err := a.queries.GetHumanByHandle(ctx, handle)
+		if err != nil {
+			return fmt.Errorf("no member @%s: %w", handle, err)
+		}
+		if err := a.queries.SetVerifiedJurisdiction(ctx, human.ID, class, strings.TrimSpace(*provider)); err != nil {
+			return err
+		}
+		if human, err = a.queries.GetHumanByID(ctx, human.ID); err != nil {
+			return err
+		}
+		fmt.Printf("@%s is %s (%s)\n", handle, human.Jurisdiction, human.JurisdictionSource)
+		return a.audit(ctx, "human.verify-jurisdiction", "human:"+strconv.Itoa(human.ID), map[string]any{"handle": handle, "jurisdiction": class, "provider": *provider})
+	}
+	return errors.New(humanUsage)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/invites"
+)
+
+const inviteUsage = `usage: synbridge invite <command>
+
+  create <handle>                       issue an operator invitation for an X handle
+  list [-status s] [-handle h] [-n 100] list invitations, newest first
+                                        (status: pending, used, expired, revoked)
+  revoke <id>                           revoke an unused invitation`
+
+// runInvite implements `synbridge invite ...`
+func runInvite(args []string) error {
+	if len(args) == 0 {
+		return errors.New(inviteUsage)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		if len(args) != 2 {
+			return errors.New(inviteUsage)
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		inv, err := (&invites.Service{Queries: a.queries}).Create(ctx, args[1], nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("invitation %d for @%s: %s\n", inv.ID, inv.TwitterHandle, inv.Code)
+		if inv.ExpiresAt != nil {
+			fmt.Printf("valid until %s\n", inv.ExpiresAt.UTC().Format("2006-01-02 15:04 MST"))
+		}
+		return a.audit(ctx, "invite.create", "invitation:"+strconv.Itoa(inv.ID), map[string]any{"handle": inv.TwitterHandle})
+
+	case "list":
+		fs := flag.NewFlagSet("invite list", flag.ContinueOnError)
+		status := fs.String("status", "", "only invitations in this state")
+		handle := fs.String("handle", "", "only invitations for this X handle")
+		limit := fs.Int("n", 100, "at most this many (up to 1000)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		switch *status {
+		case "", db.InvitationPending, db.InvitationUsed, db.InvitationExpired, db.InvitationRevoked:
+		default:
+			return fmt.Errorf("unknown status %q", *status)
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		list, err := a.queries.ListInvitations(ctx, db.InvitationFilter{
+			Status: *status,
+			Handle: invites.NormalizeHandle(*handle),
+			Limit:  *limit,
+		})
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tHANDLE\tCODE\tSTATUS\tINVITED BY\tCREATED")
+		for _, inv := range list {
+			by := "operator"
+			if inv.CreatedByHandle != nil {
+				by = "@" + *inv.CreatedByHandle
+			}
+			fmt.Fprintf(tw, "%d\t@%s\t%s\t%s\t%s\t%s\n", inv.ID, inv.TwitterHandle, inv.Code, inv.Status(now), by, inv.CreatedAt.UTC().Format("2006-01-02"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		return a.audit(ctx, "invite.list", "", map[string]any{"status": *status, "handle": *handle, "shown": len(list)})
+
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(inviteUsage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid invitation id %q", args[1])
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		revoked, err := a.queries.RevokeInvitation(ctx, id, nil)
+		if err != nil {
+			return err
+		}
+		if !revoked {
+			return fmt.Errorf("invitation %d does not exist or was already used or revoked", id)
+		}
+		fmt.Printf("invitation %d revoked\n", id)
+		return a.audit(ctx, "invite.revoke", "invitation:"+strconv.Itoa(id), nil)
+	}
+	return errors.New(inviteUsage)
+}
@@ -1,184 +1,109 @@
+// Command synbridge runs the forum and the operator commands that manage it
+// from the server's shell. Every command reads its settings through
+// internal/config; every command that changes something writes an audit
+// record (see `synbridge audit`).
 package main
 
 import (
 	"context"
-	"log"
-	"net/http"
+	"fmt"
 	"os"
-	"time"
+	"os/user"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/BioAILogic/agentbridge/internal/config"
 	"github.com/BioAILogic/agentbridge/internal/db"
-	"github.com/BioAILogic/agentbridge/internal/handlers"
 )
 
-func main() {
-	// Read DATABASE_URL from environment (required)
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		log.Fatal("DATABASE_URL environment variable is required")
-	}
-
-	// Read PORT from environment (default: 8080)
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+const usage = `usage: synbridge [command] [arguments]
 
-	// Read ADMIN_SECRET from environment (required)
-	adminSecret := os.Getenv("ADMIN_SECRET")
-	if adminSecret == "" {
-		log.Fatal("ADMIN_SECRET environment variable is required")
-	}
-	_ = adminSecret // Used by admin handler
+  serve                         run the web server (the default)
+  migrate up|down|status|verify apply or inspect schema migrations
+  invite create|list|revoke     manage invitation codes
+  agent list|freeze|unfreeze    manage agents
+  human suspend|unsuspend|verify-jurisdiction
+                                suspend or reinstate a member, record a
+                                verified jurisdiction
+  space create|archive          manage spaces
+  export human <handle>         write a member's data export
+  purge                         delete expired data now instead of waiting for the jobs
+  audit                         show the latest operator actions
 
-	// Connect to PostgreSQL via pgxpool
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+Run a command without arguments to see its usage.`
 
-	pool, err := pgxpool.New(ctx, databaseURL)
-	if err != nil {
-		log.Fatalf("Failed to create connection pool: %v", err)
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "serve" {
+		serve()
+		return
 	}
-	defer pool.Close()
 
-	// Test the connection
-	if err := pool.Ping(ctx); err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+	commands := map[string]func([]string) error{
+		"migrate": runMigrate,
+		"invite":  runInvite,
+		"agent":   runAgent,
+		"human":   runHuman,
+		"space":   runSpace,
+		"export":  runExport,
+		"purge":   runPurge,
+		"audit":   runAudit,
 	}
-
-	// Create sqlc queries
-	queries := db.New(pool)
-
-	// Seed spaces if empty
-	if err := seedSpaces(ctx, queries); err != nil {
-		log.Fatalf("Failed to seed spaces: %v", err)
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
 	}
-
-	// Create router
-	r := chi.NewRouter()
-
-	// Middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-
-	// Static files (landing page, assets)
-	staticDir := os.Getenv("STATIC_DIR")
-	if staticDir == "" {
-		staticDir = "/opt/synbridge/static"
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-	fs := http.FileServer(http.Dir(staticDir))
-	r.Handle("/assets/*", fs)
-
-	// Routes
-	r.Get("/health", (&handlers.HealthHandler{Queries: queries}).ServeHTTP)
-	r.Get("/", (&handlers.HomeHandler{StaticDir: staticDir}).ServeHTTP)
-	r.Post("/waitlist", (&handlers.WaitlistHandler{}).ServeHTTP)
-	r.Get("/faq", (&handlers.FAQHandler{StaticDir: staticDir}).ServeHTTP)
-
-	// M2: Authentication routes
-	r.Get("/register", (&handlers.RegisterHandler{Queries: queries}).GetHTTP)
-	r.Post("/register", (&handlers.RegisterHandler{Queries: queries}).PostHTTP)
-	r.Get("/login", (&handlers.LoginHandler{Queries: queries}).GetHTTP)
-	r.Post("/login", (&handlers.LoginHandler{Queries: queries}).PostHTTP)
-	r.Post("/logout", (&handlers.LogoutHandler{Queries: queries}).ServeHTTP)
-	r.Get("/home", (&handlers.HomeAuthHandler{Queries: queries, StaticDir: staticDir}).ServeHTTP)
-	r.Post("/admin/invite", (&handlers.AdminHandler{Queries: queries}).ServeHTTP)
-
-	// M3: Forum routes
-	r.Get("/spaces", (&handlers.SpacesHandler{Queries: queries}).ServeHTTP)
-	r.Get("/spaces/{id}", (&handlers.ThreadsHandler{Queries: queries}).ListHTTP)
-	r.Get("/spaces/{id}/new", (&handlers.ThreadsHandler{Queries: queries}).NewGetHTTP)
-	r.Post("/spaces/{id}/new", (&handlers.ThreadsHandler{Queries: queries}).NewPostHTTP)
-	r.Get("/threads/{id}", (&handlers.PostsHandler{Queries: queries}).GetHTTP)
-	r.Post("/threads/{id}", (&handlers.PostsHandler{Queries: queries}).PostHTTP)
-
-	// Settings + Search + Tribe profile
-	settingsH := &handlers.SettingsHandler{Queries: queries}
-	r.Get("/settings", settingsH.GetHTTP)
-	r.Post("/settings/tribe", settingsH.PostTribeHTTP)
-	r.Post("/settings/bio", settingsH.PostBioHTTP)
-	r.Post("/settings/location", settingsH.PostLocationHTTP)
-	r.Get("/search", (&handlers.SearchHandler{Queries: queries}).ServeHTTP)
-	r.Get("/tribes/{handle}", (&handlers.TribeHandler{Queries: queries}).ServeHTTP)
-
-	// M4: Agent routes
-	agentsH := &handlers.AgentsHandler{Queries: queries}
-	r.Get("/agents", agentsH.GetHTTP)
-	r.Post("/agents", agentsH.PostHTTP)
-	r.Post("/agents/{id}/bio", agentsH.PostAgentBioHTTP)
-	r.Post("/api/post", agentsH.PostAPIHTTP)
-
-	// M4: Agent read API
-	apiH := &handlers.APIReadHandler{Queries: queries}
-	r.Get("/api/spaces", apiH.GetSpaces)
-	r.Get("/api/spaces/{id}/threads", apiH.GetThreads)
-	r.Post("/api/threads", apiH.CreateThread)
-	r.Get("/api/threads/{id}", apiH.GetThread)
+}
 
-	// Start HTTP server
-	addr := ":" + port
-	log.Printf("SynBridge starting on %s", addr)
-	if err := http.ListenAndServe(addr, r); err != nil {
-		log.Fatalf("Server failed: %v", err)
-	}
+// admin is what the operator commands share: a database connection and the
+// name their actions are audited under
+type admin struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	actor   string
 }
 
-// seedSpaces inserts the 6 default spaces if the spaces table is empty
-func seedSpaces(ctx context.Context, queries *db.Queries) error {
-	// Check if spaces already exist
-	spaces, err := queries.ListSpaces(ctx)
+// openAdmin connects to the configured database
+func openAdmin(ctx context.Context) (*admin, error) {
+	dbConfig, err := config.LoadDatabase()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if len(spaces) > 0 {
-		return nil // Already seeded
+	poolConfig, err := dbConfig.PoolConfig()
+	if err != nil {
+		return nil, err
 	}
-
-	// Define the 6 default spaces
-	defaultSpaces := []struct {
-		name        string
-		description string
-	}{
-		{
-			name:        "Introductions",
-			description: "New members introduce themselves — humans and agents alike.",
-		},
-		{
-			name:        "Agora",
-			description: "Open discussion. Anything that matters.",
-		},
-		{
-			name:        "Theoria",
-			description: "Ideas, research, contemplation. What does it mean?",
-		},
-		{
-			name:        "Ergasterion",
-			description: "The workshop. What are you building? What happened?",
-		},
-		{
-			name:        "Tribe Stories",
-			description: "Human-agent relationships. The heart of SynBridge.",
-		},
-		{
-			name:        "Protocol",
-			description: "SynBridge meta. Feedback, governance, how we shape this place.",
-		},
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
 	}
+	return &admin{pool: pool, queries: db.New(pool), actor: operatorName()}, nil
+}
 
-	// Insert each space
-	for _, s := range defaultSpaces {
-		err := queries.CreateSpace(ctx, s.name, s.description)
-		if err != nil {
-			return err
-		}
-	}
+func (a *admin) close() {
+	a.pool.Close()
+}
 
-	log.Println("Seeded 6 default spaces")
+// audit records an action taken by this command
+func (a *admin) audit(ctx context.Context, action, target string, detail map[string]any) error {
+	if err := a.queries.RecordAudit(ctx, a.actor, action, target, detail); err != nil {
+		return fmt.Errorf("%s done, but the audit record failed: %w", action, err)
+	}
 	return nil
 }
+
+// operatorName names the person at the shell: the sudo caller if there is
+// one, otherwise the OS user
+func operatorName() string {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		return "cli:" + name
+	}
+	if u, err := user.Current(); err == nil {
+		return "cli:" + u.Username
+	}
+	return "cli:unknown"
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/migrations"
+)
+
+const migrateUsage = `usage: synbridge migrate <command>
+
+  up          apply every pending migration
+  down [n]    revert the latest n migrations (default 1)
+  status      list migrations and whether they are applied
+  verify      on an EMPTY database: apply everything, check the schema
+              against what the queries expect, revert everything, apply again`
+
+// runMigrate implements `synbridge migrate ...` against DATABASE_URL
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	ctx := context.Background()
+	a, err := openAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+	runner := &migrations.Runner{Pool: a.pool}
+
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("nothing to apply")
+			return nil
+		}
+		return a.audit(ctx, "migrate.up", "", map[string]any{"versions": versions(applied)})
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New("down takes a positive number of steps")
+			}
+		}
+		reverted, err := runner.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to revert")
+			return nil
+		}
+		// Reverting far enough drops the audit log itself
+		if err := a.audit(ctx, "migrate.down", "", map[string]any{"versions": versions(reverted)}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return nil
+
+	case "status":
+		status, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				applied += " (MODIFIED since applied)"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return tw.Flush()
+
+	case "verify":
+		return verifyMigrations(ctx, runner, a.queries)
+	}
+	return errors.New(migrateUsage)
+}
+
+func versions(ms []migrations.Migration) []int {
+	out := make([]int, len(ms))
+	for i, m := range ms {
+		out[i] = m.Version
+	}
+	return out
+}
+
+// verifyMigrations exercises every up and down file on an empty database
+// (CI, or a scratch database locally) and checks the result against the
+// columns db.Queries uses
+func verifyMigrations(ctx context.Context, runner *migrations.Runner, queries *db.Queries) error {
+	tables, err := queries.UserTables(ctx)
+	if err != nil {
+		return err
+	}
+	if len(tables) > 0 {
+		return fmt.Errorf("verify needs an empty database, found: %s", strings.Join(tables, ", "))
+	}
+
+	all, err := migrations.All()
+	if err != nil {
+		return err
+	}
+	if _, err := runner.Up(ctx); err != nil {
+		return err
+	}
+	if err := queries.CheckSchema(ctx); err != nil {
+		return fmt.Errorf("after up: %w", err)
+	}
+	fmt.Printf("up:   %d migrations applied, schema matches the queries\n", len(all))
+
+	if _, err := runner.Down(ctx, len(all)); err != nil {
+		return err
+	}
+	if tables, err = queries.UserTables(ctx); err != nil {
+		return err
+	}
+	if len(tables) != 1 || tables[0] != "schema_migrations" {
+		return fmt.Errorf("after down: tables left behind: %s", strings.Join(tables, ", "))
+	}
+	fmt.Println("down: every migration reverted cleanly")
+
+	if _, err := runner.Up(ctx); err != nil {
+		return fmt.Errorf("re-applying after down: %w", err)
+	}
+	if err := queries.CheckSchema(ctx); err != nil {
+		return fmt.Errorf("after re-applying: %w", err)
+	}
+	fmt.Println("up:   re-applied, schema matches the queries")
+	return nil
+}
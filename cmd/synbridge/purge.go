@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/deletion"
+	"github.com/BioAILogic/agentbridge/internal/export"
+	"github.com/BioAILogic/agentbridge/internal/loginguard"
+	"github.com/BioAILogic/agentbridge/internal/mail"
+)
+
+const purgeUsage = `usage: synbridge purge
+
+  Runs every retention task once, now: expired sessions, sign-in and
+  verification state, old security events and sent mail, stale export
+  archives, and account deletions whose grace period has passed. The
+  server runs the same tasks periodically.`
+
+// cleanupTask is a retention task: serve runs it every interval, purge once
+type cleanupTask struct {
+	name     string
+	interval time.Duration
+	run      func(context.Context) error
+}
+
+// cleanupTasks lists the retention tasks
+func cleanupTasks(queries *db.Queries, exports *export.Service) []cleanupTask {
+	guard := &loginguard.Guard{Queries: queries}
+	outbox := &mail.Outbox{Queries: queries}
+	return []cleanupTask{
+		{"export-cleanup", 10 * time.Minute, exports.Cleanup},
+		{"account-deletion", 10 * time.Minute, (&deletion.Service{Queries: queries}).RunDue},
+		{"mail-prune", time.Hour, outbox.Prune},
+		{"pending-login-cleanup", 10 * time.Minute, queries.DeleteExpiredPendingLogins},
+		{"session-cleanup", time.Hour, queries.DeleteExpiredSessions},
+		{"webauthn-ceremony-cleanup", 10 * time.Minute, queries.DeleteExpiredWebAuthnCeremonies},
+		{"security-event-prune", time.Hour, guard.Prune},
+		{"handle-verification-cleanup", 10 * time.Minute, queries.DeleteExpiredHandleVerificationFlows},
+	}
+}
+
+// runPurge implements `synbridge purge`
+func runPurge(args []string) error {
+	if len(args) != 0 {
+		return errors.New(purgeUsage)
+	}
+	ctx := context.Background()
+	a, err := openAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	defer a.close()
+
+	// Export cleanup only deletes files the database lists, so the directory
+	// and link key do not matter here
+	var failed []string
+	for _, t := range cleanupTasks(a.queries, &export.Service{Queries: a.queries}) {
+		if err := t.run(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.name, err)
+			failed = append(failed, t.name)
+			continue
+		}
+		fmt.Printf("%s: done\n", t.name)
+	}
+	if err := a.audit(ctx, "purge", "", map[string]any{"failed": failed}); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d task(s) failed", len(failed))
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/BioAILogic/agentbridge/internal/config"
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/migrations"
+	"github.com/BioAILogic/agentbridge/internal/export"
+	"github.com/BioAILogic/agentbridge/internal/handlers"
+	"github.com/BioAILogic/agentbridge/internal/invites"
+	"github.com/BioAILogic/agentbridge/internal/jobs"
+	"github.com/BioAILogic/agentbridge/internal/loginguard"
+	"github.com/BioAILogic/agentbridge/internal/mail"
+	"github.com/BioAILogic/agentbridge/internal/metrics"
+	sbmiddleware "github.com/BioAILogic/agentbridge/internal/middleware"
+	"github.com/BioAILogic/agentbridge/internal/tracing"
+	"github.com/BioAILogic/agentbridge/internal/xverify"
+	"github.com/BioAILogic/agentbridge/web"
+)
+
+// serve runs the web server and the background jobs
+func serve() {
+	// JSON logs on stdout; the standard log package is routed through them too
+	logLevel := new(slog.LevelVar)
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})))
+
+	// Settings come from the environment and SYNBRIDGE_CONFIG; see docs/CONFIGURATION.md
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
+	logLevel.Set(cfg.LogLevel)
+
+	// Tracing: spans go to the OTLP endpoint when one is configured
+	stopTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Endpoint, cfg.Tracing.SampleRatio)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+
+	// Connect to PostgreSQL via pgxpool
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	poolConfig, err := cfg.Database.PoolConfig()
+	if err != nil {
+		fatal("Failed to configure connection pool", err)
+	}
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		fatal("Failed to create connection pool", err)
+	}
+	defer pool.Close()
+
+	// Test the connection
+	if err := pool.Ping(ctx); err != nil {
+		fatal("Failed to ping database", err)
+	}
+
+	// The schema is migrated explicitly (synbridge migrate up), never on startup
+	if pending, err := (&migrations.Runner{Pool: pool}).Pending(ctx); err != nil {
+		fatal("Failed to read schema migrations", err)
+	} else if pending > 0 {
+		slog.Warn("Schema migrations pending; run `synbridge migrate up`", "pending", pending)
+	}
+
+	// Create sqlc queries
+	queries := db.New(pool)
+	metrics.Register(metrics.NewPoolCollector(pool))
+
+	// Seed spaces if empty
+	if err := seedSpaces(ctx, queries); err != nil {
+		fatal("Failed to seed spaces", err)
+	}
+
+	// Passkeys are bound to the public origin's host
+	publicURL, _ := url.Parse(cfg.PublicBaseURL)
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          publicURL.Hostname(),
+		RPDisplayName: "Synbridge",
+		RPOrigins:     []string{cfg.PublicBaseURL},
+	})
+	if err != nil {
+		fatal("Failed to configure WebAuthn", err)
+	}
+
+	// X handle verification: off unless an OAuth client is configured
+	var handleVerifier *xverify.Verifier
+	if cfg.XOAuth.Enabled() {
+		handleVerifier = xverify.New(xverify.Config{
+			ClientID:     cfg.XOAuth.ClientID,
+			ClientSecret: cfg.XOAuth.ClientSecret,
+			AuthURL:      cfg.XOAuth.AuthURL,
+			TokenURL:     cfg.XOAuth.TokenURL,
+			UserInfoURL:  cfg.XOAuth.UserInfoURL,
+			RedirectURL:  cfg.PublicBaseURL + "/settings/x/callback",
+		})
+	}
+
+	// Outgoing mail: SMTP relay when configured, otherwise .eml files or the log
+	var mailer mail.Mailer = &mail.FileMailer{Dir: cfg.Mail.Dir}
+	if cfg.Mail.SMTPAddr != "" {
+		mailer = &mail.SMTPMailer{
+			Addr:     cfg.Mail.SMTPAddr,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+		}
+	}
+	outbox := &mail.Outbox{Queries: queries, Mailer: mailer, From: cfg.Mail.From}
+
+	// Background jobs: GDPR exports, mail delivery, and the retention tasks
+	// that `synbridge purge` runs on demand
+	guard := &loginguard.Guard{Queries: queries}
+	inviteSvc := &invites.Service{Queries: queries}
+	exports := export.NewService(queries, cfg.ExportDir, cfg.Key(config.KeyExportLinks))
+	runner := &jobs.Runner{}
+	runner.Every("export-build", 15*time.Second, exports.RunPending)
+	runner.Every("mail-deliver", 10*time.Second, outbox.Deliver)
+	for _, t := range cleanupTasks(queries, exports) {
+		runner.Every(t.name, t.interval, t.run)
+	}
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	runner.Start(jobsCtx)
+
+	// Create router
+	r := chi.NewRouter()
+
+	// Middleware
+	sbmiddleware.Cookies = sbmiddleware.CookiePolicy{Secure: cfg.Cookies.Secure, HostPrefix: cfg.Cookies.HostPrefix}
+	r.Use(middleware.RequestID)
+	r.Use(sbmiddleware.RealIP(cfg.HTTP.TrustedProxies))
+	r.Use(tracing.Middleware)
+	r.Use(sbmiddleware.AccessLog)
+	r.Use(metrics.Middleware)
+	r.Use(sbmiddleware.SecurityHeaders{
+		HSTSMaxAge:            cfg.Headers.HSTSMaxAge,
+		HSTSIncludeSubdomains: cfg.Headers.HSTSIncludeSubdomains,
+		ReferrerPolicy:        cfg.Headers.ReferrerPolicy,
+		CSPReportURI:          cfg.Headers.CSPReportURI,
+	}.Set)
+	r.Use(sbmiddleware.Recoverer)
+	r.Use(sbmiddleware.SessionMiddleware(queries))
+
+	// Static assets, compiled into the binary
+	r.Handle("/assets/*", http.FileServerFS(web.Static))
+
+	// Routes
+	healthH := &handlers.HealthHandler{Queries: queries, Migrations: &migrations.Runner{Pool: pool}}
+	r.Get("/health", healthH.ServeHTTP)
+	r.Get("/livez", healthH.LivezHTTP)
+	r.Get("/readyz", healthH.ReadyzHTTP)
+	if cfg.Metrics.Token != "" {
+		r.Method(http.MethodGet, "/metrics", metrics.Handler(cfg.Metrics.Token))
+	}
+
+	// Admin API: ADMIN_SECRET as bearer token, or ?secret= traded for an
+	// sb_admin cookie in a browser
+	r.Group(func(r chi.Router) {
+		r.Use(sbmiddleware.OperatorMiddleware(cfg.AdminSecret, cfg.Key(config.KeyOperator)))
+		r.Use(sbmiddleware.RequireRole(sbmiddleware.RoleAdmin))
+		adminH := &handlers.AdminHandler{Queries: queries, Invites: inviteSvc}
+		r.Post("/admin/invite", adminH.ServeHTTP)
+		r.Get("/admin/invitations", adminH.ListInvitationsHTTP)
+		r.Get("/admin/invitations/tree", adminH.InviteTreeHTTP)
+		r.Post("/admin/invitations/{id}/revoke", adminH.RevokeInvitationHTTP)
+		r.Post("/admin/invitations/{id}/reissue", adminH.ReissueInvitationHTTP)
+		r.Post("/admin/humans/{handle}/invite-quota", adminH.SetInviteQuotaHTTP)
+		r.Post("/admin/humans/{handle}/jurisdiction", adminH.SetJurisdictionHTTP)
+		r.Post("/admin/spaces/{id}/jurisdictions", adminH.SetSpaceJurisdictionsHTTP)
+		r.Get("/admin/waitlist", adminH.WaitlistHTTP)
+		r.Post("/admin/waitlist/invite", adminH.InviteWaitlistHTTP)
+		r.Get("/admin/transparency", adminH.TransparencyHTTP)
+		r.Get("/admin/security-events", adminH.SecurityEventsHTTP)
+	})
+
+	// Agent API: Authorization: Bearer <agent key>
+	if cfg.Features.AgentAPI {
+		r.Group(func(r chi.Router) {
+			r.Use(sbmiddleware.AgentAuthMiddleware(queries))
+			r.Use(sbmiddleware.RequireAgent)
+			r.Post("/api/post", (&handlers.AgentsHandler{Queries: queries, BaseURL: cfg.PublicBaseURL}).PostAPIHTTP)
+
+			// M4: Agent read API
+			apiH := &handlers.APIReadHandler{Queries: queries, BaseURL: cfg.PublicBaseURL}
+			r.Get("/api/spaces", apiH.GetSpaces)
+			r.Get("/api/spaces/{id}/threads", apiH.GetThreads)
+			r.Post("/api/threads", apiH.CreateThread)
+			r.Get("/api/threads/{id}", apiH.GetThread)
+			r.Get("/api/v1/me/unread", apiH.GetUnread)
+			r.Get("/api/v1/search", apiH.Search)
+			r.Put("/api/v1/threads/{id}/watch", apiH.WatchThread)
+			r.Delete("/api/v1/threads/{id}/watch", apiH.WatchThread)
+		})
+	}
+
+	// Pages and forms for browsers, behind CSRF protection. The admin and
+	// agent APIs above stay outside: they need a secret in the request
+	// itself, which a forged cross-site request cannot carry, or for the
+	// admin pages a SameSite=Strict cookie honoured on posts only from the
+	// site's own pages.
+	r.Group(func(r chi.Router) {
+		r.Use(sbmiddleware.NewCSRF(cfg.Key(config.KeyCSRF), http.HandlerFunc(handlers.CSRFFailureHTTP)).Protect)
+
+		r.Get("/", (&handlers.HomeHandler{}).ServeHTTP)
+		if cfg.Features.Waitlist {
+			r.Post("/waitlist", (&handlers.WaitlistHandler{Queries: queries}).ServeHTTP)
+		}
+		r.Get("/faq", (&handlers.FAQHandler{}).ServeHTTP)
+
+		// M2: Authentication routes
+		if cfg.Features.Registration {
+			registerH := &handlers.RegisterHandler{Queries: queries}
+			r.Get("/register", registerH.GetHTTP)
+			r.Post("/register", registerH.PostHTTP)
+		}
+		loginH := &handlers.LoginHandler{Queries: queries, Guard: guard}
+		r.Get("/login", loginH.GetHTTP)
+		r.Post("/login", loginH.PostHTTP)
+		r.Get("/login/2fa", (&handlers.TwoFactorHandler{Queries: queries}).GetHTTP)
+		r.Post("/login/2fa", (&handlers.TwoFactorHandler{Queries: queries}).PostHTTP)
+		passkeyH := &handlers.PasskeyHandler{Queries: queries, WebAuthn: webAuthn}
+		r.Post("/login/passkey/begin", passkeyH.LoginBeginHTTP)
+		r.Post("/login/passkey/finish", passkeyH.LoginFinishHTTP)
+		r.Post("/logout", (&handlers.LogoutHandler{Queries: queries}).ServeHTTP)
+		passwordH := &handlers.PasswordHandler{Queries: queries, Guard: guard, BaseURL: cfg.PublicBaseURL}
+		r.Get("/password/forgot", passwordH.ForgotGetHTTP)
+		r.Post("/password/forgot", passwordH.ForgotPostHTTP)
+		r.Get("/password/reset", passwordH.ResetGetHTTP)
+		r.Post("/password/reset", passwordH.ResetPostHTTP)
+
+		// Signed-in humans
+		r.Group(func(r chi.Router) {
+			r.Use(sbmiddleware.RequireHuman)
+			r.Get("/home", (&handlers.HomeAuthHandler{Queries: queries}).ServeHTTP)
+
+			// M3: Forum routes
+			r.Get("/spaces", (&handlers.SpacesHandler{Queries: queries}).ServeHTTP)
+			r.Get("/spaces/{id}", (&handlers.ThreadsHandler{Queries: queries}).ListHTTP)
+			r.Get("/spaces/{id}/new", (&handlers.ThreadsHandler{Queries: queries}).NewGetHTTP)
+			r.Post("/spaces/{id}/new", (&handlers.ThreadsHandler{Queries: queries}).NewPostHTTP)
+			r.Get("/threads/{id}", (&handlers.PostsHandler{Queries: queries}).GetHTTP)
+			r.Post("/threads/{id}", (&handlers.PostsHandler{Queries: queries}).PostHTTP)
+			r.Post("/threads/{id}/watch", (&handlers.PostsHandler{Queries: queries}).WatchHTTP)
+
+			// Settings + Search + Tribe profile
+			settingsH := &handlers.SettingsHandler{Queries: queries, Exports: exports, BaseURL: cfg.PublicBaseURL, HandleVerification: handleVerifier != nil}
+			r.Get("/settings", settingsH.GetHTTP)
+			r.Post("/settings/tribe", settingsH.PostTribeHTTP)
+			r.Post("/settings/bio", settingsH.PostBioHTTP)
+			r.Post("/settings/location", settingsH.PostLocationHTTP)
+			r.Post("/settings/jurisdiction", settingsH.PostJurisdictionHTTP)
+			r.Post("/settings/language", settingsH.PostLanguageHTTP)
+			r.Post("/settings/export", settingsH.PostExportHTTP)
+			r.Get("/settings/export/{id}/download", settingsH.ExportDownloadHTTP)
+			r.Post("/settings/email", settingsH.PostEmailHTTP)
+			r.Get("/settings/email/verify", settingsH.VerifyEmailHTTP)
+			r.Post("/settings/2fa/setup", settingsH.PostTOTPSetupHTTP)
+			r.Post("/settings/2fa/enable", settingsH.PostTOTPEnableHTTP)
+			r.Post("/settings/2fa/recovery", settingsH.PostRecoveryCodesHTTP)
+			r.Post("/settings/2fa/disable", settingsH.PostTOTPDisableHTTP)
+			r.Post("/settings/password", settingsH.PostPasswordHTTP)
+			r.Get("/settings/sessions", settingsH.SessionsHTTP)
+			invitesH := &handlers.InvitesHandler{Queries: queries, Invites: inviteSvc, BaseURL: cfg.PublicBaseURL}
+			r.Get("/settings/invites", invitesH.GetHTTP)
+			r.Post("/settings/invites", invitesH.PostHTTP)
+			r.Post("/settings/invites/{id}/revoke", invitesH.PostRevokeHTTP)
+			r.Post("/settings/sessions/revoke-others", settingsH.PostRevokeOthersHTTP)
+			r.Post("/settings/sessions/{id}/revoke", settingsH.PostRevokeSessionHTTP)
+			r.Post("/settings/passkeys/begin", passkeyH.RegisterBeginHTTP)
+			r.Post("/settings/passkeys/finish", passkeyH.RegisterFinishHTTP)
+			r.Post("/settings/passkeys/{id}/delete", passkeyH.DeleteHTTP)
+			r.Post("/settings/password/remove", passkeyH.RemovePasswordHTTP)
+			handleVerifyH := &handlers.HandleVerifyHandler{Queries: queries, Verifier: handleVerifier}
+			r.Post("/settings/x/verify", handleVerifyH.BeginHTTP)
+			r.Get("/settings/x/callback", handleVerifyH.CallbackHTTP)
+			r.Post("/settings/x/remove", handleVerifyH.RemoveHTTP)
+			r.Post("/settings/delete", settingsH.PostDeleteHTTP)
+			r.Post("/settings/delete/cancel", settingsH.PostDeleteCancelHTTP)
+			r.Get("/search", (&handlers.SearchHandler{Queries: queries}).ServeHTTP)
+			r.Get("/tribes/{handle}", (&handlers.TribeHandler{Queries: queries}).ServeHTTP)
+
+			// M4: Agent management
+			agentsH := &handlers.AgentsHandler{Queries: queries, BaseURL: cfg.PublicBaseURL}
+			r.Get("/agents", agentsH.GetHTTP)
+			r.Post("/agents", agentsH.PostHTTP)
+			r.Post("/agents/{id}/bio", agentsH.PostAgentBioHTTP)
+		})
+	})
+
+	// Start HTTP server
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+	serverErr := make(chan error, 2)
+	go func() {
+		slog.Info("SynBridge starting", "addr", cfg.Addr)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	// Metrics on their own listener, kept off the public port
+	var metricsSrv *http.Server
+	if cfg.Metrics.Addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("GET /metrics", metrics.Handler(cfg.Metrics.Token))
+		metricsSrv = &http.Server{
+			Addr:              cfg.Metrics.Addr,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+			ReadTimeout:       cfg.HTTP.ReadTimeout,
+			WriteTimeout:      cfg.HTTP.WriteTimeout,
+			IdleTimeout:       cfg.HTTP.IdleTimeout,
+		}
+		go func() {
+			slog.Info("Serving metrics", "addr", cfg.Metrics.Addr)
+			serverErr <- metricsSrv.ListenAndServe()
+		}()
+	}
+
+	// SIGTERM (systemctl stop/restart) or Ctrl-C: report not ready, let
+	// requests in flight finish, then stop the background jobs
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stopSignals()
+	select {
+	case err := <-serverErr:
+		fatal("Server failed", err)
+	case <-signals.Done():
+	}
+	slog.Info("Shutting down", "drain_for", cfg.HTTP.DrainDelay+cfg.HTTP.ShutdownTimeout)
+	healthH.Draining.Store(true)
+	time.Sleep(cfg.HTTP.DrainDelay)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Shutdown", "err", err)
+	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Metrics server shutdown", "err", err)
+		}
+	}
+	stopJobs()
+	runner.Wait()
+	if err := stopTracing(shutdownCtx); err != nil {
+		slog.Warn("Flushing traces", "err", err)
+	}
+	slog.Info("Stopped")
+}
+
+// fatal logs a startup failure and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
+
+// seedSpaces inserts the 6 default spaces if the spaces table is empty
+func seedSpaces(ctx context.Context, queries *db.Queries) error {
+	// Check if spaces already exist (archived ones count)
+	n, err := queries.CountSpaces(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil // Already seeded
+	}
+
+	// Define the 6 default spaces
+	defaultSpaces := []struct {
+		name        string
+		description string
+	}{
+		{
+			name:        "Introductions",
+			description: "New members introduce themselves — humans and agents alike.",
+		},
+		{
+			name:        "Agora",
+			description: "Open discussion. Anything that matters.",
+		},
+		{
+			name:        "Theoria",
+			description: "Ideas, research, contemplation. What does it mean?",
+		},
+		{
+			name:        "Ergasterion",
+			description: "The workshop. What are you building? What happened?",
+		},
+		{
+			name:        "Tribe Stories",
+			description: "Human-agent relationships. The heart of SynBridge.",
+		},
+		{
+			name:        "Protocol",
+			description: "SynBridge meta. Feedback, governance, how we shape this place.",
+		},
+	}
+
+	// Insert each space
+	for _, s := range defaultSpaces {
+		if _, err := queries.CreateSpace(ctx, s.name, s.description); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Seeded 6 default spaces")
+	return nil
+}
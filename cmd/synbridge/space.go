@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const spaceUsage = `usage: synbridge space <command>
+
+  create [-description text] <name>   add a space
+  archive <id>                        make a space read-only and unlist it`
+
+// runSpace implements `synbridge space ...`
+func runSpace(args []string) error {
+	if len(args) == 0 {
+		return errors.New(spaceUsage)
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("space create", flag.ContinueOnError)
+		description := fs.String("description", "", "one line shown under the name")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		name := strings.TrimSpace(strings.Join(fs.Args(), " "))
+		if name == "" {
+			return errors.New(spaceUsage)
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		id, err := a.queries.CreateSpace(ctx, name, strings.TrimSpace(*description))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("space %d created: %s\n", id, name)
+		return a.audit(ctx, "space.create", "space:"+strconv.Itoa(id), map[string]any{"name": name})
+
+	case "archive":
+		if len(args) != 2 {
+			return errors.New(spaceUsage)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid space id %q", args[1])
+		}
+		a, err := openAdmin(ctx)
+		if err != nil {
+			return err
+		}
+		defer a.close()
+
+		archived, err := a.queries.ArchiveSpace(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !archived {
+			return fmt.Errorf("space %d does not exist or is already archived", id)
+		}
+		fmt.Printf("space %d archived\n", id)
+		return a.audit(ctx, "space.archive", "space:"+strconv.Itoa(id), nil)
+	}
+	return errors.New(spaceUsage)
+}
@@ -0,0 +1,84 @@
+// Command waitlist-import moves the old waitlist file into the database. Each
+// line is "<RFC 3339 time>\t@handle", as the former /waitlist handler wrote
+// it. Handles already on the waitlist are skipped, so it is safe to re-run.
+//
+//	DATABASE_URL=... go run ./cmd/waitlist-import [/opt/synbridge/waitlist.txt]
+package main
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/BioAILogic/agentbridge/internal/config"
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/invites"
+)
+
+// source tags imported entries
+const source = "waitlist.txt"
+
+func main() {
+	dbConfig, err := config.LoadDatabase()
+	if err != nil {
+		log.Fatal(err)
+	}
+	path := "/opt/synbridge/waitlist.txt"
+	if len(os.Args) > 1 {
+		path = os.Args[1]
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open waitlist: %v", err)
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	poolConfig, err := dbConfig.PoolConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		log.Fatalf("Failed to create connection pool: %v", err)
+	}
+	defer pool.Close()
+	queries := db.New(pool)
+
+	var added, duplicate, invalid int
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		stamp, rawHandle, ok := strings.Cut(line, "\t")
+		at, err := time.Parse(time.RFC3339, stamp)
+		handle := invites.NormalizeHandle(rawHandle)
+		if !ok || err != nil || !invites.ValidHandle(handle) {
+			log.Printf("line %d: skipping %q", n, line)
+			invalid++
+			continue
+		}
+		// Submitting the form was the consent to be contacted
+		ok, err = queries.AddWaitlistEntry(ctx, handle, source, at, at)
+		if err != nil {
+			log.Fatalf("line %d: %v", n, err)
+		}
+		if ok {
+			added++
+		} else {
+			duplicate++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("read waitlist: %v", err)
+	}
+	log.Printf("imported %d, already listed %d, invalid %d", added, duplicate, invalid)
+}
@@ -0,0 +1,436 @@
+// Package config loads the server's settings. Every setting is an
+// environment variable; the same names can also be put in a file of
+// KEY=VALUE lines named by SYNBRIDGE_CONFIG, for instance a systemd
+// EnvironmentFile. The environment wins over the file. Everything is
+// validated once at startup, so a typo stops the server instead of
+// surfacing as odd behaviour later.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Config is the validated server configuration
+type Config struct {
+	Addr          string // listen address, ":" + PORT
+	LogLevel      slog.Level
+	HTTP          HTTP
+	PublicBaseURL string // origin used in links and passkeys, no trailing slash
+	AdminSecret   string // operator token; also the root of the keys from Key
+	ExportDir     string // where finished export archives wait for download
+	Database      Database
+	Mail          Mail
+	XOAuth        XOAuth
+	Cookies       Cookies
+	Headers       Headers
+	Features      Features
+	Metrics       Metrics
+	Tracing       Tracing
+}
+
+// HTTP bounds how long clients may hold the server. Slow clients cannot
+// keep connections open forever, and a shutdown waits at most
+// ShutdownTimeout for requests in flight.
+type HTTP struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration // also bounds export downloads
+	IdleTimeout       time.Duration
+	DrainDelay        time.Duration // /readyz fails this long before the listener closes
+	ShutdownTimeout   time.Duration
+
+	// Reverse proxies whose X-Forwarded-For and X-Real-IP are believed.
+	// From anyone else those headers are ignored.
+	TrustedProxies []netip.Prefix
+}
+
+// Database is the Postgres connection and pool sizing. Zero sizes leave the
+// pgx defaults in place.
+type Database struct {
+	URL             string
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
+
+// Mail is outgoing mail: the SMTP relay when SMTPAddr is set, otherwise .eml
+// files in Dir (or the log) for development
+type Mail struct {
+	From         string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	Dir          string
+}
+
+// XOAuth is the OAuth client for X handle verification. The URLs point it at
+// another provider, e.g. cmd/fakeidp in development.
+type XOAuth struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+}
+
+// Enabled reports whether handle verification is configured
+func (x XOAuth) Enabled() bool {
+	return x.ClientID != ""
+}
+
+// Cookies are the attributes of the cookies the site sets
+type Cookies struct {
+	Secure     bool // send only over HTTPS; defaults to on for an https PublicBaseURL
+	HostPrefix bool // __Host- cookie names; defaults to Secure
+}
+
+// Headers tune the security headers sent with every response
+type Headers struct {
+	HSTSMaxAge            time.Duration // 0 sends no Strict-Transport-Security; defaults to a year for https
+	HSTSIncludeSubdomains bool          // HSTS covers every subdomain too; off unless the operator opts in
+	ReferrerPolicy        string
+	CSPReportURI          string // where browsers report Content-Security-Policy violations
+}
+
+// referrerPolicies are the values Referrer-Policy accepts
+var referrerPolicies = []string{
+	"no-referrer", "no-referrer-when-downgrade", "origin", "origin-when-cross-origin",
+	"same-origin", "strict-origin", "strict-origin-when-cross-origin", "unsafe-url",
+}
+
+// Metrics is where /metrics is served. With Addr it gets a listener of its
+// own, which should not be reachable from the internet; with Token it is
+// served on the main listener to scrapers sending that bearer token. Both
+// may be set. With neither, there is no /metrics.
+type Metrics struct {
+	Addr  string // host:port, e.g. 127.0.0.1:9090
+	Token string
+}
+
+// Enabled reports whether /metrics is served anywhere
+func (m Metrics) Enabled() bool {
+	return m.Addr != "" || m.Token != ""
+}
+
+// Tracing is where OpenTelemetry spans go. Without Endpoint nothing is
+// recorded.
+type Tracing struct {
+	Endpoint    string  // OTLP/HTTP traces URL, e.g. http://localhost:4318/v1/traces
+	SampleRatio float64 // share of new traces kept, 0 to 1
+}
+
+// Features switch parts of the site off. All are on by default.
+type Features struct {
+	Registration bool // /register, redeeming invitations
+	Waitlist     bool // POST /waitlist from the landing page
+	AgentAPI     bool // the /api routes agents call
+}
+
+// Load reads and validates the configuration the server needs
+func Load() (*Config, error) {
+	return load(os.Getenv)
+}
+
+func load(getenv func(string) string) (*Config, error) {
+	s, err := newSource(getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{
+		AdminSecret: s.str("ADMIN_SECRET", ""),
+		LogLevel:    s.logLevel("LOG_LEVEL", slog.LevelInfo),
+		ExportDir:   s.str("EXPORT_DIR", "/opt/synbridge/exports"),
+		HTTP: HTTP{
+			ReadHeaderTimeout: s.duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+			ReadTimeout:       s.duration("HTTP_READ_TIMEOUT", 30*time.Second),
+			WriteTimeout:      s.duration("HTTP_WRITE_TIMEOUT", 2*time.Minute),
+			IdleTimeout:       s.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+			DrainDelay:        s.duration("HTTP_DRAIN_DELAY", 0),
+			ShutdownTimeout:   s.duration("HTTP_SHUTDOWN_TIMEOUT", 30*time.Second),
+			TrustedProxies:    s.prefixes("TRUSTED_PROXIES", "127.0.0.1,::1"),
+		},
+		Database: s.database(),
+		Mail: Mail{
+			SMTPAddr:     s.str("SMTP_ADDR", ""),
+			SMTPUsername: s.str("SMTP_USERNAME", ""),
+			SMTPPassword: s.str("SMTP_PASSWORD", ""),
+			Dir:          s.str("MAIL_DIR", ""),
+		},
+		XOAuth: XOAuth{
+			ClientID:     s.str("X_OAUTH_CLIENT_ID", ""),
+			ClientSecret: s.str("X_OAUTH_CLIENT_SECRET", ""),
+			AuthURL:      s.url("X_OAUTH_AUTH_URL"),
+			TokenURL:     s.url("X_OAUTH_TOKEN_URL"),
+			UserInfoURL:  s.url("X_OAUTH_USERINFO_URL"),
+		},
+		Features: Features{
+			Registration: s.boolean("FEATURE_REGISTRATION", true),
+			Waitlist:     s.boolean("FEATURE_WAITLIST", true),
+			AgentAPI:     s.boolean("FEATURE_AGENT_API", true),
+		},
+		Tracing: Tracing{
+			Endpoint:    s.url("TRACING_ENDPOINT"),
+			SampleRatio: s.ratio("TRACING_SAMPLE_RATIO", 1),
+		},
+		Headers: Headers{
+			HSTSIncludeSubdomains: s.boolean("HSTS_INCLUDE_SUBDOMAINS", false),
+			ReferrerPolicy:        s.str("REFERRER_POLICY", "strict-origin-when-cross-origin"),
+			CSPReportURI:          s.url("CSP_REPORT_URI"),
+		},
+		Metrics: Metrics{
+			Addr:  s.str("METRICS_ADDR", ""),
+			Token: s.str("METRICS_TOKEN", ""),
+		},
+	}
+
+	// Templates and assets are compiled in now; reading STATIC_DIR keeps
+	// settings files that still set it loading
+	s.lookup("STATIC_DIR")
+
+	port := s.integer("PORT", 8080)
+	if port < 1 || port > 65535 {
+		s.fail("PORT", "must be between 1 and 65535")
+	}
+	c.Addr = ":" + strconv.Itoa(port)
+
+	if c.AdminSecret == "" {
+		s.fail("ADMIN_SECRET", "is required")
+	}
+
+	c.PublicBaseURL = strings.TrimSuffix(s.str("PUBLIC_BASE_URL", "https://synbridge.eu"), "/")
+	u, err := url.Parse(c.PublicBaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+		s.fail("PUBLIC_BASE_URL", "must be an origin such as https://synbridge.eu (no path)")
+		u = &url.URL{Scheme: "https", Host: "synbridge.eu"}
+	}
+	c.Mail.From = s.str("MAIL_FROM", "Synbridge <noreply@"+u.Hostname()+">")
+	c.Cookies.Secure = s.boolean("COOKIE_SECURE", u.Scheme == "https")
+	c.Cookies.HostPrefix = s.boolean("COOKIE_HOST_PREFIX", c.Cookies.Secure)
+	if c.Cookies.HostPrefix && !c.Cookies.Secure {
+		s.fail("COOKIE_HOST_PREFIX", "needs COOKIE_SECURE; browsers drop __Host- cookies that are not Secure")
+	}
+	hstsDefault := time.Duration(0)
+	if u.Scheme == "https" {
+		hstsDefault = 365 * 24 * time.Hour
+	}
+	c.Headers.HSTSMaxAge = s.duration("HSTS_MAX_AGE", hstsDefault)
+
+	if c.Mail.SMTPAddr == "" && (c.Mail.SMTPUsername != "" || c.Mail.SMTPPassword != "") {
+		s.fail("SMTP_USERNAME", "is set but SMTP_ADDR is not")
+	}
+	if !c.XOAuth.Enabled() && c.XOAuth.ClientSecret != "" {
+		s.fail("X_OAUTH_CLIENT_SECRET", "is set but X_OAUTH_CLIENT_ID is not")
+	}
+	if !slices.Contains(referrerPolicies, c.Headers.ReferrerPolicy) {
+		s.fail("REFERRER_POLICY", "must be a Referrer-Policy value such as strict-origin-when-cross-origin")
+	}
+	if c.Metrics.Addr != "" {
+		if _, port, err := net.SplitHostPort(c.Metrics.Addr); err != nil || port == "" {
+			s.fail("METRICS_ADDR", "must be host:port, such as 127.0.0.1:9090")
+		} else if c.Metrics.Addr == c.Addr || c.Metrics.Addr == "0.0.0.0"+c.Addr {
+			s.fail("METRICS_ADDR", "must differ from the main listener; use METRICS_TOKEN to serve /metrics there")
+		}
+	}
+	if c.Metrics.Token != "" && len(c.Metrics.Token) < 16 {
+		s.fail("METRICS_TOKEN", "must be at least 16 characters")
+	}
+
+	s.checkUnknown()
+	if err := s.err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadDatabase reads and validates only the database settings, for the
+// operator commands
+func LoadDatabase() (Database, error) {
+	s, err := newSource(os.Getenv)
+	if err != nil {
+		return Database{}, err
+	}
+	d := s.database()
+	return d, s.err()
+}
+
+// PoolConfig returns the pgxpool configuration for d
+func (d Database) PoolConfig() (*pgxpool.Config, error) {
+	pc, err := pgxpool.ParseConfig(d.URL)
+	if err != nil {
+		return nil, err
+	}
+	if d.MaxConns > 0 {
+		pc.MaxConns = d.MaxConns
+	}
+	if d.MinConns > 0 {
+		pc.MinConns = d.MinConns
+	}
+	if d.MaxConnLifetime > 0 {
+		pc.MaxConnLifetime = d.MaxConnLifetime
+	}
+	if d.MaxConnIdleTime > 0 {
+		pc.MaxConnIdleTime = d.MaxConnIdleTime
+	}
+	return pc, nil
+}
+
+func (s *source) database() Database {
+	d := Database{
+		URL:             s.str("DATABASE_URL", ""),
+		MaxConns:        int32(s.integer("DB_MAX_CONNS", 0)),
+		MinConns:        int32(s.integer("DB_MIN_CONNS", 0)),
+		MaxConnLifetime: s.duration("DB_MAX_CONN_LIFETIME", 0),
+		MaxConnIdleTime: s.duration("DB_MAX_CONN_IDLE_TIME", 0),
+	}
+	if d.URL == "" {
+		s.fail("DATABASE_URL", "is required")
+	} else if _, err := pgxpool.ParseConfig(d.URL); err != nil {
+		// The parse error may quote the password; keep it out of the logs
+		s.fail("DATABASE_URL", "is not a valid Postgres connection string")
+	}
+	if d.MaxConns < 0 || d.MinConns < 0 {
+		s.fail("DB_MAX_CONNS", "pool sizes cannot be negative")
+	}
+	if d.MaxConns > 0 && d.MinConns > d.MaxConns {
+		s.fail("DB_MIN_CONNS", "is larger than DB_MAX_CONNS")
+	}
+	return d
+}
+
+// Typed getters. Each records a problem instead of returning it, so Load
+// can report every mistake at once.
+
+func (s *source) str(key, def string) string {
+	if v, ok := s.lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+func (s *source) integer(key string, def int) int {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		s.fail(key, "must be a whole number")
+		return def
+	}
+	return n
+}
+
+func (s *source) boolean(key string, def bool) bool {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		s.fail(key, "must be true or false")
+		return def
+	}
+	return b
+}
+
+// ratio returns a number between 0 and 1
+func (s *source) ratio(key string, def float64) float64 {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 || f > 1 {
+		s.fail(key, "must be a number between 0 and 1")
+		return def
+	}
+	return f
+}
+
+func (s *source) duration(key string, def time.Duration) time.Duration {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		s.fail(key, "must be a duration such as 30m or 1h")
+		return def
+	}
+	return d
+}
+
+func (s *source) logLevel(key string, def slog.Level) slog.Level {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(v)); err != nil {
+		s.fail(key, "must be debug, info, warn or error")
+		return def
+	}
+	return l
+}
+
+// prefixes reads a comma-separated list of IP addresses and CIDR ranges
+func (s *source) prefixes(key, def string) []netip.Prefix {
+	v := s.str(key, def)
+	var out []netip.Prefix
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(item)
+			if aerr != nil {
+				s.fail(key, "must list IP addresses or CIDR ranges, such as 127.0.0.1,10.0.0.0/8")
+				return nil
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		out = append(out, p.Masked())
+	}
+	return out
+}
+
+// url returns an optional absolute http(s) URL
+func (s *source) url(key string) string {
+	v, ok := s.lookup(key)
+	if !ok {
+		return ""
+	}
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		s.fail(key, "must be an absolute http(s) URL")
+		return ""
+	}
+	return v
+}
+
+func (s *source) fail(key, problem string) {
+	s.problems = append(s.problems, fmt.Errorf("%s %s", key, problem))
+}
+
+func (s *source) err() error {
+	if len(s.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration: %w", errors.Join(s.problems...))
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// env is a fake environment holding the two required settings plus set;
+// an empty value in set removes a setting
+func env(set map[string]string) func(string) string {
+	m := map[string]string{
+		"ADMIN_SECRET": "admin secret",
+		"DATABASE_URL": "postgres://synbridge@localhost/synbridge",
+	}
+	for k, v := range set {
+		m[k] = v
+	}
+	return func(key string) string { return m[key] }
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   map[string]string
+		check func(*testing.T, *Config)
+	}{
+		{"defaults", nil, func(t *testing.T, c *Config) {
+			want := HTTP{
+				ReadHeaderTimeout: 5 * time.Second,
+				ReadTimeout:       30 * time.Second,
+				WriteTimeout:      2 * time.Minute,
+				IdleTimeout:       2 * time.Minute,
+				ShutdownTimeout:   30 * time.Second,
+			}
+			want.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32"), netip.MustParsePrefix("::1/128")}
+			if !reflect.DeepEqual(c.HTTP, want) {
+				t.Errorf("HTTP = %+v, want %+v", c.HTTP, want)
+			}
+			if c.Addr != ":8080" || c.LogLevel != slog.LevelInfo || c.ExportDir != "/opt/synbridge/exports" {
+				t.Errorf("Addr %q, LogLevel %v, ExportDir %q", c.Addr, c.LogLevel, c.ExportDir)
+			}
+			if c.PublicBaseURL != "https://synbridge.eu" || c.Mail.From != "Synbridge <noreply@synbridge.eu>" {
+				t.Errorf("PublicBaseURL %q, MAIL_FROM %q", c.PublicBaseURL, c.Mail.From)
+			}
+			if c.Features != (Features{Registration: true, Waitlist: true, AgentAPI: true}) {
+				t.Errorf("Features = %+v, want all on", c.Features)
+			}
+			if c.XOAuth.Enabled() || c.Metrics.Enabled() || c.Tracing.Endpoint != "" || c.Tracing.SampleRatio != 1 {
+				t.Errorf("optional services on by default: %+v %+v %+v", c.XOAuth, c.Metrics, c.Tracing)
+			}
+		}},
+
+		// An https base URL turns on Secure cookies, __Host- names and a year of HSTS
+		{"https", nil, func(t *testing.T, c *Config) {
+			if c.Cookies != (Cookies{Secure: true, HostPrefix: true}) {
+				t.Errorf("Cookies = %+v", c.Cookies)
+			}
+			want := Headers{HSTSMaxAge: 365 * 24 * time.Hour, ReferrerPolicy: "strict-origin-when-cross-origin"}
+			if c.Headers != want {
+				t.Errorf("Headers = %+v, want %+v", c.Headers, want)
+			}
+		}},
+		{"http", map[string]string{"PUBLIC_BASE_URL": "http://localhost:8080/"}, func(t *testing.T, c *Config) {
+			if c.PublicBaseURL != "http://localhost:8080" {
+				t.Errorf("PublicBaseURL = %q, want the trailing slash trimmed", c.PublicBaseURL)
+			}
+			if c.Cookies != (Cookies{}) || c.Headers.HSTSMaxAge != 0 {
+				t.Errorf("Cookies = %+v, HSTS %v; want neither over plain HTTP", c.Cookies, c.Headers.HSTSMaxAge)
+			}
+			if c.Mail.From != "Synbridge <noreply@localhost>" {
+				t.Errorf("MAIL_FROM = %q", c.Mail.From)
+			}
+		}},
+		{"https without Secure cookies", map[string]string{"COOKIE_SECURE": "false"}, func(t *testing.T, c *Config) {
+			if c.Cookies != (Cookies{}) {
+				t.Errorf("Cookies = %+v, want COOKIE_HOST_PREFIX to follow COOKIE_SECURE", c.Cookies)
+			}
+		}},
+		{"http with HSTS", map[string]string{"PUBLIC_BASE_URL": "http://localhost", "HSTS_MAX_AGE": "5m", "HSTS_INCLUDE_SUBDOMAINS": "true"}, func(t *testing.T, c *Config) {
+			if c.Headers.HSTSMaxAge != 5*time.Minute || !c.Headers.HSTSIncludeSubdomains {
+				t.Errorf("Headers = %+v", c.Headers)
+			}
+		}},
+		{"HSTS off", map[string]string{"HSTS_MAX_AGE": "0"}, func(t *testing.T, c *Config) {
+			if c.Headers.HSTSMaxAge != 0 {
+				t.Errorf("HSTSMaxAge = %v", c.Headers.HSTSMaxAge)
+			}
+		}},
+
+		{"settings", map[string]string{
+			"PORT":                 "9000",
+			"LOG_LEVEL":            "debug",
+			"TRUSTED_PROXIES":      " 10.1.2.3/8, 192.0.2.1 ,,2001:db8::/32",
+			"REFERRER_POLICY":      "no-referrer",
+			"FEATURE_WAITLIST":     "0",
+			"SMTP_ADDR":            "smtp.example.org:587",
+			"SMTP_USERNAME":        "mailer",
+			"X_OAUTH_CLIENT_ID":    "client",
+			"X_OAUTH_AUTH_URL":     "http://localhost:9999/authorize",
+			"METRICS_ADDR":         "127.0.0.1:9090",
+			"METRICS_TOKEN":        "0123456789abcdef",
+			"TRACING_SAMPLE_RATIO": "0.25",
+			"DB_MAX_CONNS":         "10",
+			"DB_MIN_CONNS":         "2",
+		}, func(t *testing.T, c *Config) {
+			if c.Addr != ":9000" || c.LogLevel != slog.LevelDebug {
+				t.Errorf("Addr %q, LogLevel %v", c.Addr, c.LogLevel)
+			}
+			want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.0.2.1/32"), netip.MustParsePrefix("2001:db8::/32")}
+			if !slices.Equal(c.HTTP.TrustedProxies, want) {
+				t.Errorf("TrustedProxies = %v, want %v", c.HTTP.TrustedProxies, want)
+			}
+			if c.Headers.ReferrerPolicy != "no-referrer" || c.Features.Waitlist || !c.XOAuth.Enabled() || c.Tracing.SampleRatio != 0.25 {
+				t.Errorf("settings not applied: %+v %+v %+v %+v", c.Headers, c.Features, c.XOAuth, c.Tracing)
+			}
+			if c.Database.MaxConns != 10 || c.Database.MinConns != 2 || c.Metrics.Addr != "127.0.0.1:9090" {
+				t.Errorf("Database %+v, Metrics %+v", c.Database, c.Metrics)
+			}
+		}},
+		{"STATIC_DIR still accepted", map[string]string{"STATIC_DIR": "/opt/synbridge/static"}, func(*testing.T, *Config) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := load(env(tt.set))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, c)
+		})
+	}
+}
+
+// TestLoadProblems covers every setting Load refuses. Each case must fail
+// with its own message.
+func TestLoadProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		set  map[string]string
+		want string
+	}{
+		{"no admin secret", map[string]string{"ADMIN_SECRET": ""}, "ADMIN_SECRET is required"},
+		{"no database", map[string]string{"DATABASE_URL": ""}, "DATABASE_URL is required"},
+		{"bad database URL", map[string]string{"DATABASE_URL": "postgres://u:secret@%zz/db"}, "DATABASE_URL is not a valid Postgres connection string"},
+		{"negative pool", map[string]string{"DB_MAX_CONNS": "-1"}, "DB_MAX_CONNS pool sizes cannot be negative"},
+		{"min above max", map[string]string{"DB_MAX_CONNS": "2", "DB_MIN_CONNS": "5"}, "DB_MIN_CONNS is larger than DB_MAX_CONNS"},
+		{"port out of range", map[string]string{"PORT": "70000"}, "PORT must be between 1 and 65535"},
+		{"port not a number", map[string]string{"PORT": "http"}, "PORT must be a whole number"},
+		{"base URL with a path", map[string]string{"PUBLIC_BASE_URL": "https://synbridge.eu/forum"}, "PUBLIC_BASE_URL must be an origin"},
+		{"base URL without a scheme", map[string]string{"PUBLIC_BASE_URL": "synbridge.eu"}, "PUBLIC_BASE_URL must be an origin"},
+		{"host prefix without Secure", map[string]string{"COOKIE_SECURE": "false", "COOKIE_HOST_PREFIX": "true"}, "COOKIE_HOST_PREFIX needs COOKIE_SECURE"},
+		{"SMTP login without a relay", map[string]string{"SMTP_PASSWORD": "x"}, "SMTP_USERNAME is set but SMTP_ADDR is not"},
+		{"OAuth secret without a client", map[string]string{"X_OAUTH_CLIENT_SECRET": "x"}, "X_OAUTH_CLIENT_SECRET is set but X_OAUTH_CLIENT_ID is not"},
+		{"referrer policy", map[string]string{"REFERRER_POLICY": "never"}, "REFERRER_POLICY must be a Referrer-Policy value"},
+		{"metrics address", map[string]string{"METRICS_ADDR": "9090"}, "METRICS_ADDR must be host:port"},
+		{"metrics on the main port", map[string]string{"METRICS_ADDR": ":8080"}, "METRICS_ADDR must differ from the main listener"},
+		{"metrics on all interfaces of the main port", map[string]string{"METRICS_ADDR": "0.0.0.0:8080"}, "METRICS_ADDR must differ from the main listener"},
+		{"short metrics token", map[string]string{"METRICS_TOKEN": "short"}, "METRICS_TOKEN must be at least 16 characters"},
+		{"log level", map[string]string{"LOG_LEVEL": "loud"}, "LOG_LEVEL must be debug, info, warn or error"},
+		{"boolean", map[string]string{"FEATURE_REGISTRATION": "yes"}, "FEATURE_REGISTRATION must be true or false"},
+		{"duration", map[string]string{"HTTP_READ_TIMEOUT": "30"}, "HTTP_READ_TIMEOUT must be a duration"},
+		{"negative duration", map[string]string{"HSTS_MAX_AGE": "-1h"}, "HSTS_MAX_AGE must be a duration"},
+		{"ratio", map[string]string{"TRACING_SAMPLE_RATIO": "2"}, "TRACING_SAMPLE_RATIO must be a number between 0 and 1"},
+		{"URL", map[string]string{"CSP_REPORT_URI": "/csp-report"}, "CSP_REPORT_URI must be an absolute http(s) URL"},
+		{"URL scheme", map[string]string{"TRACING_ENDPOINT": "ftp://collector/v1/traces"}, "TRACING_ENDPOINT must be an absolute http(s) URL"},
+		{"proxies", map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8,proxy.internal"}, "TRUSTED_PROXIES must list IP addresses or CIDR ranges"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(env(tt.set))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Every mistake is reported at once
+	_, err := load(env(map[string]string{"ADMIN_SECRET": "", "PORT": "0", "LOG_LEVEL": "loud"}))
+	if err == nil || strings.Count(err.Error(), "\n") != 2 {
+		t.Errorf("error %v, want three problems", err)
+	}
+}
+
+func TestSettingsFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		t.Helper()
+		name := filepath.Join(dir, "synbridge.env")
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	name := write(`# settings
+export PORT=9000
+LOG_LEVEL="warn"
+REFERRER_POLICY='same-origin'
+
+FEATURE_AGENT_API=false
+`)
+	c, err := load(env(map[string]string{FileEnv: name, "LOG_LEVEL": "error"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != ":9000" || c.Headers.ReferrerPolicy != "same-origin" || c.Features.AgentAPI {
+		t.Errorf("file settings not applied: %q %q %+v", c.Addr, c.Headers.ReferrerPolicy, c.Features)
+	}
+	if c.LogLevel != slog.LevelError {
+		t.Errorf("LogLevel = %v, want the environment to win over the file", c.LogLevel)
+	}
+
+	for _, tt := range []struct {
+		name, content, want string
+	}{
+		{"unknown setting", "PORT=9000\nREFERER_POLICY=origin\n", "REFERER_POLICY in " + name + " is not a known setting"},
+		{"not KEY=VALUE", "PORT 9000\n", name + " line 1: expected KEY=VALUE"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			write(tt.content)
+			if _, err := load(env(map[string]string{FileEnv: name})); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := load(env(map[string]string{FileEnv: filepath.Join(dir, "missing.env")})); err == nil || !strings.HasPrefix(err.Error(), FileEnv+":") {
+		t.Errorf("missing settings file: error %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+)
+
+// Purposes of the keys derived from AdminSecret. Each has its own label, so
+// a value signed for one purpose never verifies for another.
+const (
+	KeyCSRF        = "synbridge/csrf-tokens/v1"
+	KeyExportLinks = "synbridge/export-links/v1"
+	KeyOperator    = "synbridge/operator-sessions/v1"
+)
+
+// Key derives the 32-byte key for purpose from AdminSecret with HKDF-SHA256
+func (c *Config) Key(purpose string) []byte {
+	key, err := hkdf.Key(sha256.New, []byte(c.AdminSecret), nil, purpose, 32)
+	if err != nil {
+		// Only an output length HKDF-SHA256 cannot produce fails
+		panic(err)
+	}
+	return key
+}
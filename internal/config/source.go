@@ -0,0 +1,87 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FileEnv names the optional settings file
+const FileEnv = "SYNBRIDGE_CONFIG"
+
+// source looks settings up in the environment, then in the settings file
+type source struct {
+	getenv   func(string) string
+	file     map[string]string
+	fileName string
+	seen     map[string]bool
+	problems []error
+}
+
+// newSource reads the environment through getenv, os.Getenv outside tests
+func newSource(getenv func(string) string) (*source, error) {
+	s := &source{getenv: getenv, file: map[string]string{}, seen: map[string]bool{}}
+	if name := getenv(FileEnv); name != "" {
+		if err := s.readFile(name); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// lookup returns a setting; empty values count as unset
+func (s *source) lookup(key string) (string, bool) {
+	s.seen[key] = true
+	if v := strings.TrimSpace(s.getenv(key)); v != "" {
+		return v, true
+	}
+	v, ok := s.file[key]
+	return v, ok && v != ""
+}
+
+// readFile parses KEY=VALUE lines. Blank lines and lines starting with #
+// are skipped; a value may be wrapped in single or double quotes.
+func (s *source) readFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", FileEnv, err)
+	}
+	defer f.Close()
+	s.fileName = name
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("%s line %d: expected KEY=VALUE", name, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		s.file[key] = value
+	}
+	return scanner.Err()
+}
+
+// checkUnknown reports settings in the file that nothing read, which are
+// almost always typos
+func (s *source) checkUnknown() {
+	var unknown []string
+	for key := range s.file {
+		if !s.seen[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		s.fail(key, "in "+s.fileName+" is not a known setting")
+	}
+}
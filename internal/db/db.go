@@ -6,6 +6,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -26,23 +27,45 @@ func (q *Queries) GetHealth(ctx context.Context) (int32, error) {
 
 // Struct types for database entities
 type Invitation struct {
-	ID            int
-	Code          string
-	TwitterHandle string
-	CreatedAt     time.Time
-	UsedAt        *time.Time
-	UsedBy        *int
+	ID              int
+	Code            string
+	TwitterHandle   string
+	CreatedBy       *int // nullable; NULL for operator-issued codes
+	CreatedAt       time.Time
+	UsedAt          *time.Time
+	UsedBy          *int
+	ExpiresAt       *time.Time // nullable; NULL never expires
+	RevokedAt       *time.Time
+	ReissuedFrom    *int    // the invitation this one replaced
+	CreatedByHandle *string // joined from humans
+	UsedByHandle    *string // joined from humans
 }
 
 type Human struct {
-	ID            int
-	TwitterHandle string
-	PasswordHash  string
-	Jurisdiction  string
-	TribeName     *string // nullable; if NULL, display twitter_handle
-	Bio           *string // nullable
-	Location      *string // nullable
-	CreatedAt     time.Time
+	ID                     int
+	TwitterHandle          string
+	PasswordHash           string
+	Jurisdiction           string     // effective class, see package jurisdiction
+	JurisdictionSource     string     // where the class came from: JurisdictionDefault, ...
+	JurisdictionVerifiedBy *string    // nullable; the provider behind a verified class
+	TribeName              *string    // nullable; if NULL, display twitter_handle
+	Bio                    *string    // nullable
+	Location               *string    // nullable
+	Language               string     // text search configuration for this human's posts (see SearchLanguages)
+	Email                  *string    // nullable; only ever a verified address
+	TOTPEnabledAt          *time.Time // nullable; set when two-factor authentication is on
+	SuspendedAt            *time.Time // nullable; set while an operator has suspended the account
+	CreatedAt              time.Time
+}
+
+// humanColumns is the column list scanned by scanHuman
+const humanColumns = "id, twitter_handle, password_hash, jurisdiction, jurisdiction_source, jurisdiction_verified_by, tribe_name, bio, location, language, email, totp_enabled_at, suspended_at, created_at"
+
+// scanHuman scans a row selected with humanColumns
+func scanHuman(row pgx.Row) (Human, error) {
+	var h Human
+	err := row.Scan(&h.ID, &h.TwitterHandle, &h.PasswordHash, &h.Jurisdiction, &h.JurisdictionSource, &h.JurisdictionVerifiedBy, &h.TribeName, &h.Bio, &h.Location, &h.Language, &h.Email, &h.TOTPEnabledAt, &h.SuspendedAt, &h.CreatedAt)
+	return h, err
 }
 
 // DisplayName returns tribe_name if set, otherwise twitter_handle
@@ -54,17 +77,23 @@ func (h Human) DisplayName() string {
 }
 
 type Session struct {
-	ID        string
-	HumanID   int
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	ID         string // sha256 of the cookie value
+	HumanID    int
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastSeenAt time.Time
+	UserAgent  string
+	IPPrefix   string
+	Remember   bool
 }
 
 type Space struct {
-	ID          int
-	Name        string
-	Description string
-	CreatedAt   time.Time
+	ID                   int
+	Name                 string
+	Description          string
+	AllowedJurisdictions []string   // nil: anyone may post
+	ArchivedAt           *time.Time // nullable; archived spaces are read-only
+	CreatedAt            time.Time
 }
 
 type SpaceWithStats struct {
@@ -100,41 +129,16 @@ type ThreadSummary struct {
 }
 
 type Post struct {
-	ID           int
-	ThreadID     int
-	AuthorType   string
-	AuthorID     int
-	AuthorHandle string // human: twitter_handle; agent: agent name
-	AuthorTribe  string // agent only: owner's twitter_handle ("Tribe of X")
-	Content      string
-	ContentHTML  string // markdown rendered to HTML (computed, not stored)
-	CreatedAt    time.Time
-}
-
-// CreateInvitation inserts a new invitation code for a twitter handle
-// created_by can be NULL (admin-generated invitations have no human creator)
-func (q *Queries) CreateInvitation(ctx context.Context, code, twitterHandle string) error {
-	_, err := q.pool.Exec(ctx,
-		"INSERT INTO invitations (code, twitter_handle) VALUES ($1, $2)",
-		code, twitterHandle)
-	return err
-}
-
-// GetInvitation returns an invitation by code, only if unused
-func (q *Queries) GetInvitation(ctx context.Context, code string) (Invitation, error) {
-	var inv Invitation
-	err := q.pool.QueryRow(ctx,
-		"SELECT id, code, twitter_handle, created_at, used_at, used_by FROM invitations WHERE code = $1 AND used_at IS NULL",
-		code).Scan(&inv.ID, &inv.Code, &inv.TwitterHandle, &inv.CreatedAt, &inv.UsedAt, &inv.UsedBy)
-	return inv, err
-}
-
-// MarkInvitationUsed sets used_at and used_by on an invitation
-func (q *Queries) MarkInvitationUsed(ctx context.Context, code string, humanID int) error {
-	_, err := q.pool.Exec(ctx,
-		"UPDATE invitations SET used_at = NOW(), used_by = $1 WHERE code = $2",
-		humanID, code)
-	return err
+	ID                 int
+	ThreadID           int
+	AuthorType         string
+	AuthorID           int
+	AuthorHandle       string // human: twitter_handle; agent: agent name
+	AuthorTribe        string // agent only: owner's twitter_handle ("Tribe of X")
+	AuthorJurisdiction string // human only: jurisdiction class
+	Content            string
+	ContentHTML        string // markdown rendered to HTML (computed, not stored)
+	CreatedAt          time.Time
 }
 
 // CreateHuman inserts a new human account
@@ -146,50 +150,24 @@ func (q *Queries) CreateHuman(ctx context.Context, twitterHandle, passwordHash s
 	return id, err
 }
 
-// GetHumanByHandle returns a human by twitter_handle
+// GetHumanByHandle returns a human by twitter_handle. The tombstone human is
+// not a member and is never found.
 func (q *Queries) GetHumanByHandle(ctx context.Context, twitterHandle string) (Human, error) {
-	var h Human
-	err := q.pool.QueryRow(ctx,
-		"SELECT id, twitter_handle, password_hash, jurisdiction, tribe_name, bio, location, created_at FROM humans WHERE twitter_handle = $1",
-		twitterHandle).Scan(&h.ID, &h.TwitterHandle, &h.PasswordHash, &h.Jurisdiction, &h.TribeName, &h.Bio, &h.Location, &h.CreatedAt)
-	return h, err
-}
-
-// CreateSession inserts a new session
-func (q *Queries) CreateSession(ctx context.Context, sessionID string, humanID int, expiresAt time.Time) error {
-	_, err := q.pool.Exec(ctx,
-		"INSERT INTO sessions (id, human_id, expires_at) VALUES ($1, $2, $3)",
-		sessionID, humanID, expiresAt)
-	return err
-}
-
-// GetSession returns a session by ID if not expired
-func (q *Queries) GetSession(ctx context.Context, sessionID string) (Session, error) {
-	var s Session
-	err := q.pool.QueryRow(ctx,
-		"SELECT id, human_id, created_at, expires_at FROM sessions WHERE id = $1 AND expires_at > NOW()",
-		sessionID).Scan(&s.ID, &s.HumanID, &s.CreatedAt, &s.ExpiresAt)
-	return s, err
-}
-
-// DeleteSession removes a session from the database
-func (q *Queries) DeleteSession(ctx context.Context, sessionID string) error {
-	_, err := q.pool.Exec(ctx, "DELETE FROM sessions WHERE id = $1", sessionID)
-	return err
+	return scanHuman(q.pool.QueryRow(ctx,
+		"SELECT "+humanColumns+" FROM humans WHERE twitter_handle = $1 AND twitter_handle <> $2",
+		twitterHandle, TombstoneHumanHandle))
 }
 
 // GetHumanByID returns a human by ID
 func (q *Queries) GetHumanByID(ctx context.Context, id int) (Human, error) {
-	var h Human
-	err := q.pool.QueryRow(ctx,
-		"SELECT id, twitter_handle, password_hash, jurisdiction, tribe_name, bio, location, created_at FROM humans WHERE id = $1",
-		id).Scan(&h.ID, &h.TwitterHandle, &h.PasswordHash, &h.Jurisdiction, &h.TribeName, &h.Bio, &h.Location, &h.CreatedAt)
-	return h, err
+	return scanHuman(q.pool.QueryRow(ctx,
+		"SELECT "+humanColumns+" FROM humans WHERE id = $1",
+		id))
 }
 
-// ListSpaces returns all spaces ordered by id
+// ListSpaces returns all spaces that are not archived, ordered by id
 func (q *Queries) ListSpaces(ctx context.Context) ([]Space, error) {
-	rows, err := q.pool.Query(ctx, "SELECT id, name, description, created_at FROM spaces ORDER BY id")
+	rows, err := q.pool.Query(ctx, "SELECT id, name, description, allowed_jurisdictions, archived_at, created_at FROM spaces WHERE archived_at IS NULL ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
@@ -198,7 +176,7 @@ func (q *Queries) ListSpaces(ctx context.Context) ([]Space, error) {
 	var spaces []Space
 	for rows.Next() {
 		var s Space
-		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.CreatedAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.AllowedJurisdictions, &s.ArchivedAt, &s.CreatedAt); err != nil {
 			return nil, err
 		}
 		spaces = append(spaces, s)
@@ -206,7 +184,7 @@ func (q *Queries) ListSpaces(ctx context.Context) ([]Space, error) {
 	return spaces, rows.Err()
 }
 
-// ListSpacesWithStats returns all spaces with thread count, post count, and last activity
+// ListSpacesWithStats returns all spaces that are not archived with thread count, post count, and last activity
 func (q *Queries) ListSpacesWithStats(ctx context.Context) ([]SpaceWithStats, error) {
 	rows, err := q.pool.Query(ctx, `
 		SELECT
@@ -217,6 +195,7 @@ func (q *Queries) ListSpacesWithStats(ctx context.Context) ([]SpaceWithStats, er
 		FROM spaces s
 		LEFT JOIN threads t ON t.space_id = s.id
 		LEFT JOIN posts p ON p.thread_id = t.id
+		WHERE s.archived_at IS NULL
 		GROUP BY s.id
 		ORDER BY s.id
 	`)
@@ -241,8 +220,8 @@ func (q *Queries) ListSpacesWithStats(ctx context.Context) ([]SpaceWithStats, er
 func (q *Queries) GetSpace(ctx context.Context, id int) (Space, error) {
 	var s Space
 	err := q.pool.QueryRow(ctx,
-		"SELECT id, name, description, created_at FROM spaces WHERE id = $1",
-		id).Scan(&s.ID, &s.Name, &s.Description, &s.CreatedAt)
+		"SELECT id, name, description, allowed_jurisdictions, archived_at, created_at FROM spaces WHERE id = $1",
+		id).Scan(&s.ID, &s.Name, &s.Description, &s.AllowedJurisdictions, &s.ArchivedAt, &s.CreatedAt)
 	return s, err
 }
 
@@ -295,7 +274,8 @@ func (q *Queries) GetThread(ctx context.Context, id int) (Thread, error) {
 func (q *Queries) CreateThread(ctx context.Context, spaceID int, title string, authorType string, authorID int) (int, error) {
 	var id int
 	err := q.pool.QueryRow(ctx,
-		"INSERT INTO threads (space_id, title, author_type, author_id, last_post_at) VALUES ($1, $2, $3, $4, NOW()) RETURNING id",
+		`INSERT INTO threads (space_id, title, author_type, author_id, last_post_at, lang)
+		 VALUES ($1, $2, $3, $4, NOW(), `+authorLanguageSQL("$3", "$4")+`) RETURNING id`,
 		spaceID, title, authorType, authorID).Scan(&id)
 	return id, err
 }
@@ -308,6 +288,7 @@ func (q *Queries) ListPosts(ctx context.Context, threadID int) ([]Post, error) {
 		SELECT p.id, p.thread_id, p.author_type, p.author_id,
 		       COALESCE(h.twitter_handle, a.name) as author_handle,
 		       CASE WHEN p.author_type = 'agent' THEN owner.twitter_handle ELSE '' END as author_tribe,
+		       COALESCE(h.jurisdiction, '') as author_jurisdiction,
 		       p.content, p.created_at
 		FROM posts p
 		LEFT JOIN humans h ON h.id = p.author_id AND p.author_type = 'human'
@@ -328,7 +309,7 @@ func (q *Queries) ListPosts(ctx context.Context, threadID int) ([]Post, error) {
 		var authorHandle *string
 		var authorTribe *string
 		if err := rows.Scan(&p.ID, &p.ThreadID, &p.AuthorType, &p.AuthorID, &authorHandle,
-			&authorTribe, &p.Content, &p.CreatedAt); err != nil {
+			&authorTribe, &p.AuthorJurisdiction, &p.Content, &p.CreatedAt); err != nil {
 			return nil, err
 		}
 		if authorHandle != nil {
@@ -342,7 +323,9 @@ func (q *Queries) ListPosts(ctx context.Context, threadID int) ([]Post, error) {
 	return posts, rows.Err()
 }
 
-// CreatePost inserts a new post, updates thread last_post_at, returns new post id
+// CreatePost inserts a new post, updates thread last_post_at, notifies watchers,
+// auto-watches the thread for the author and advances the author's read marker.
+// Returns the new post id
 func (q *Queries) CreatePost(ctx context.Context, threadID int, authorType string, authorID int, content string) (int, error) {
 	tx, err := q.pool.Begin(ctx)
 	if err != nil {
@@ -352,7 +335,8 @@ func (q *Queries) CreatePost(ctx context.Context, threadID int, authorType strin
 
 	var id int
 	err = tx.QueryRow(ctx,
-		"INSERT INTO posts (thread_id, author_type, author_id, content) VALUES ($1, $2, $3, $4) RETURNING id",
+		`INSERT INTO posts (thread_id, author_type, author_id, content, lang)
+		 VALUES ($1, $2, $3, $4, `+authorLanguageSQL("$2", "$3")+`) RETURNING id`,
 		threadID, authorType, authorID, content).Scan(&id)
 	if err != nil {
 		return 0, err
@@ -363,18 +347,51 @@ func (q *Queries) CreatePost(ctx context.Context, threadID int, authorType strin
 		return 0, err
 	}
 
+	// Notify everyone watching the thread (except the author)
+	_, err = tx.Exec(ctx,
+		`INSERT INTO notifications (recipient_type, recipient_id, kind, thread_id, post_id)
+		 SELECT watcher_type, watcher_id, 'reply', thread_id, $4
+		 FROM thread_watches
+		 WHERE thread_id = $1 AND watching AND NOT (watcher_type = $2 AND watcher_id = $3)`,
+		threadID, authorType, authorID, id)
+	if err != nil {
+		return 0, err
+	}
+
+	// Participating watches the thread, unless the author explicitly unwatched it
+	_, err = tx.Exec(ctx,
+		`INSERT INTO thread_watches (watcher_type, watcher_id, thread_id)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (watcher_type, watcher_id, thread_id) DO NOTHING`,
+		authorType, authorID, threadID)
+	if err != nil {
+		return 0, err
+	}
+
+	// The author has read everything up to their own post
+	_, err = tx.Exec(ctx,
+		`INSERT INTO thread_reads (reader_type, reader_id, thread_id, last_read_post_id)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (reader_type, reader_id, thread_id)
+		 DO UPDATE SET last_read_post_id = GREATEST(thread_reads.last_read_post_id, EXCLUDED.last_read_post_id), read_at = NOW()`,
+		authorType, authorID, threadID, id)
+	if err != nil {
+		return 0, err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-// CreateSpace inserts a new space
-func (q *Queries) CreateSpace(ctx context.Context, name, description string) error {
-	_, err := q.pool.Exec(ctx,
-		"INSERT INTO spaces (name, description) VALUES ($1, $2)",
-		name, description)
-	return err
+// CreateSpace inserts a new space, returns new space id
+func (q *Queries) CreateSpace(ctx context.Context, name, description string) (int, error) {
+	var id int
+	err := q.pool.QueryRow(ctx,
+		"INSERT INTO spaces (name, description) VALUES ($1, $2) RETURNING id",
+		name, description).Scan(&id)
+	return id, err
 }
 
 // Agent is the full agent record
@@ -382,7 +399,7 @@ type Agent struct {
 	ID          int
 	OwnerID     int
 	Name        string
-	OwnerHandle string // resolved from humans table
+	OwnerHandle string  // resolved from humans table
 	Bio         *string // nullable
 	CreatedAt   time.Time
 }
@@ -396,14 +413,15 @@ func (q *Queries) CreateAgent(ctx context.Context, ownerID int, name, apiKeyHash
 	return id, err
 }
 
-// GetAgentByKeyHash returns an agent by its api_key_hash
+// GetAgentByKeyHash returns an agent by its api_key_hash. Keys of frozen
+// agents and of suspended owners do not match.
 func (q *Queries) GetAgentByKeyHash(ctx context.Context, keyHash string) (Agent, error) {
 	var a Agent
 	err := q.pool.QueryRow(ctx,
 		`SELECT a.id, a.owner_id, a.name, h.twitter_handle, a.bio, a.created_at
 		 FROM agents a
 		 JOIN humans h ON h.id = a.owner_id
-		 WHERE a.api_key_hash = $1 AND a.frozen_at IS NULL`,
+		 WHERE a.api_key_hash = $1 AND a.frozen_at IS NULL AND h.suspended_at IS NULL`,
 		keyHash).Scan(&a.ID, &a.OwnerID, &a.Name, &a.OwnerHandle, &a.Bio, &a.CreatedAt)
 	return a, err
 }
@@ -480,6 +498,13 @@ func (q *Queries) UpdateHumanLocation(ctx context.Context, humanID int, location
 	return err
 }
 
+// UpdateHumanLanguage sets the text search language used for a human's (and their agents') new posts.
+// language must be one of SearchLanguages.
+func (q *Queries) UpdateHumanLanguage(ctx context.Context, humanID int, language string) error {
+	_, err := q.pool.Exec(ctx, "UPDATE humans SET language = $1 WHERE id = $2", language, humanID)
+	return err
+}
+
 // UpdateAgentBio sets (or clears) the bio for an agent (only if owned by humanID)
 func (q *Queries) UpdateAgentBio(ctx context.Context, agentID, humanID int, bio string) error {
 	var val interface{}
@@ -502,12 +527,12 @@ type TribeSearchResult struct {
 func (q *Queries) SearchTribes(ctx context.Context, query string) ([]TribeSearchResult, error) {
 	like := "%" + query + "%"
 	rows, err := q.pool.Query(ctx,
-		`SELECT id, twitter_handle, password_hash, jurisdiction, tribe_name, bio, location, created_at
+		`SELECT `+humanColumns+`
 		 FROM humans
-		 WHERE twitter_handle ILIKE $1 OR tribe_name ILIKE $1
+		 WHERE (twitter_handle ILIKE $1 OR tribe_name ILIKE $1) AND twitter_handle <> $2
 		 ORDER BY twitter_handle
 		 LIMIT 20`,
-		like)
+		like, TombstoneHumanHandle)
 	if err != nil {
 		return nil, err
 	}
@@ -515,8 +540,8 @@ func (q *Queries) SearchTribes(ctx context.Context, query string) ([]TribeSearch
 
 	var results []TribeSearchResult
 	for rows.Next() {
-		var h Human
-		if err := rows.Scan(&h.ID, &h.TwitterHandle, &h.PasswordHash, &h.Jurisdiction, &h.TribeName, &h.Bio, &h.Location, &h.CreatedAt); err != nil {
+		h, err := scanHuman(rows)
+		if err != nil {
 			return nil, err
 		}
 		agents, _ := q.ListAgentsByHuman(ctx, h.ID)
@@ -538,8 +563,9 @@ type TribePost struct {
 	CreatedAt   time.Time
 }
 
-// GetTribePosts returns all posts by a human and their agents, newest first
-func (q *Queries) GetTribePosts(ctx context.Context, humanID int) ([]TribePost, error) {
+// GetTribePosts returns posts by a human and their agents, newest first.
+// limit <= 0 returns every post (used by data export).
+func (q *Queries) GetTribePosts(ctx context.Context, humanID int, limit int) ([]TribePost, error) {
 	query := `
 		SELECT p.id, t.id, t.title, s.id, s.name,
 		       p.author_type,
@@ -553,9 +579,12 @@ func (q *Queries) GetTribePosts(ctx context.Context, humanID int) ([]TribePost,
 		WHERE (p.author_type = 'human' AND p.author_id = $1)
 		   OR (p.author_type = 'agent' AND a.owner_id = $1)
 		ORDER BY p.created_at DESC
-		LIMIT 200
+		LIMIT NULLIF($2, 0)
 	`
-	rows, err := q.pool.Query(ctx, query, humanID)
+	if limit < 0 {
+		limit = 0
+	}
+	rows, err := q.pool.Query(ctx, query, humanID, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,79 @@
+// Package dbtest gives tests a throwaway Postgres schema. Tests that use it
+// are skipped unless SYNBRIDGE_TEST_DATABASE_URL names a database in which
+// they may create and drop schemas; each test gets its own, so tests can run
+// in parallel against one database.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/migrations"
+)
+
+// EnvDSN names the connection string of the test database
+const EnvDSN = "SYNBRIDGE_TEST_DATABASE_URL"
+
+// Pool returns a pool whose connections work in a fresh, empty schema. The
+// schema is dropped when the test ends.
+func Pool(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv(EnvDSN)
+	if dsn == "" {
+		t.Skip(EnvDSN + " is not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	b := make([]byte, 8)
+	rand.Read(b)
+	schema := "test_" + hex.EncodeToString(b)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		admin.Close(ctx)
+		t.Fatalf("creating schema: %v", err)
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		pool.Close()
+		if _, err := admin.Exec(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("dropping schema %s: %v", schema, err)
+		}
+		admin.Close(ctx)
+	})
+	return pool
+}
+
+// Migrated returns a pool on a fresh schema with every migration applied
+func Migrated(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+	pool := Pool(t)
+	if _, err := (&migrations.Runner{Pool: pool}).Up(context.Background()); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return pool
+}
+
+// Open returns queries on a fresh schema with every migration applied
+func Open(t testing.TB) *db.Queries {
+	t.Helper()
+	return db.New(Migrated(t))
+}
@@ -0,0 +1,311 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Tombstone identities that deleted authors' content is reassigned to
+const (
+	TombstoneHumanHandle = "Deleted Human"
+	TombstoneAgentName   = "Deleted Agent"
+)
+
+// Deletion modes
+const (
+	DeletionAnonymize = "anonymize" // keep content, attribute it to the tombstones
+	DeletionPurge     = "purge"     // remove content as well
+)
+
+// DeletionRequest is a scheduled account deletion
+type DeletionRequest struct {
+	ID           int
+	HumanID      *int // NULL once the account has been deleted
+	Mode         string
+	RequestedAt  time.Time
+	ScheduledFor time.Time
+	CancelledAt  *time.Time
+	CompletedAt  *time.Time
+}
+
+const deletionRequestColumns = "id, human_id, mode, requested_at, scheduled_for, cancelled_at, completed_at"
+
+func scanDeletionRequest(row pgx.Row) (DeletionRequest, error) {
+	var d DeletionRequest
+	err := row.Scan(&d.ID, &d.HumanID, &d.Mode, &d.RequestedAt, &d.ScheduledFor, &d.CancelledAt, &d.CompletedAt)
+	return d, err
+}
+
+// DeletionOutcome summarizes an executed deletion
+type DeletionOutcome struct {
+	Mode          string
+	Agents        int
+	PostsAffected int // reassigned (anonymize) or removed (purge)
+	ExportFiles   []string
+}
+
+// RequestAccountDeletion schedules deletion of a human's account.
+// Fails with a unique violation if one is already pending.
+func (q *Queries) RequestAccountDeletion(ctx context.Context, humanID int, mode string, scheduledFor time.Time) (int, error) {
+	var id int
+	err := q.pool.QueryRow(ctx,
+		"INSERT INTO deletion_requests (human_id, mode, scheduled_for) VALUES ($1, $2, $3) RETURNING id",
+		humanID, mode, scheduledFor).Scan(&id)
+	return id, err
+}
+
+// GetPendingDeletion returns the human's open deletion request, if any
+func (q *Queries) GetPendingDeletion(ctx context.Context, humanID int) (DeletionRequest, error) {
+	return scanDeletionRequest(q.pool.QueryRow(ctx,
+		"SELECT "+deletionRequestColumns+" FROM deletion_requests WHERE human_id = $1 AND cancelled_at IS NULL AND completed_at IS NULL",
+		humanID))
+}
+
+// CancelAccountDeletion withdraws the human's open deletion request
+func (q *Queries) CancelAccountDeletion(ctx context.Context, humanID int) error {
+	_, err := q.pool.Exec(ctx,
+		"UPDATE deletion_requests SET cancelled_at = NOW() WHERE human_id = $1 AND cancelled_at IS NULL AND completed_at IS NULL",
+		humanID)
+	return err
+}
+
+// ListDueDeletions returns open deletion requests whose grace period has passed
+func (q *Queries) ListDueDeletions(ctx context.Context) ([]DeletionRequest, error) {
+	rows, err := q.pool.Query(ctx,
+		"SELECT "+deletionRequestColumns+` FROM deletion_requests
+		 WHERE cancelled_at IS NULL AND completed_at IS NULL AND scheduled_for <= NOW()
+		 ORDER BY scheduled_for`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reqs []DeletionRequest
+	for rows.Next() {
+		d, err := scanDeletionRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, d)
+	}
+	return reqs, rows.Err()
+}
+
+// ownedBySQL matches rows authored by the human ($1) or one of their agents ($2)
+const ownedBySQL = "((author_type = 'human' AND author_id = $1) OR (author_type = 'agent' AND author_id = ANY($2::int[])))"
+
+// ExecuteAccountDeletion deletes a human account in one transaction:
+// content is reassigned to the tombstone identities (anonymize) or removed
+// (purge), sessions and agent keys are revoked, personal rows are deleted and
+// a transparency event is recorded. Returns pgx.ErrNoRows if the request was
+// cancelled or already executed.
+func (q *Queries) ExecuteAccountDeletion(ctx context.Context, requestID int) (DeletionOutcome, error) {
+	var out DeletionOutcome
+
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return out, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Serialize deletions so tombstones are created once
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('synbridge.account_deletion'))"); err != nil {
+		return out, err
+	}
+
+	var humanID int
+	err = tx.QueryRow(ctx,
+		`SELECT human_id, mode FROM deletion_requests
+		 WHERE id = $1 AND human_id IS NOT NULL AND cancelled_at IS NULL AND completed_at IS NULL
+		 FOR UPDATE`,
+		requestID).Scan(&humanID, &out.Mode)
+	if err != nil {
+		return out, err
+	}
+
+	tombHuman, tombAgent, err := ensureTombstones(ctx, tx)
+	if err != nil {
+		return out, err
+	}
+
+	var agentIDs []int32
+	if err := tx.QueryRow(ctx,
+		"SELECT COALESCE(array_agg(id), '{}') FROM agents WHERE owner_id = $1",
+		humanID).Scan(&agentIDs); err != nil {
+		return out, err
+	}
+	out.Agents = len(agentIDs)
+
+	if out.Mode == DeletionPurge {
+		// Threads nobody else took part in go entirely
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM threads t
+			WHERE `+ownedBySQL+`
+			  AND NOT EXISTS (
+			    SELECT 1 FROM posts p
+			    WHERE p.thread_id = t.id
+			      AND NOT ((p.author_type = 'human' AND p.author_id = $1) OR (p.author_type = 'agent' AND p.author_id = ANY($2::int[])))
+			  )`, humanID, agentIDs); err != nil {
+			return out, err
+		}
+		rows, err := tx.Query(ctx, "DELETE FROM posts WHERE "+ownedBySQL+" RETURNING thread_id", humanID, agentIDs)
+		if err != nil {
+			return out, err
+		}
+		threadIDs, err := pgx.CollectRows(rows, pgx.RowTo[int32])
+		if err != nil {
+			return out, err
+		}
+		out.PostsAffected = len(threadIDs)
+		// Threads that lost posts sort by their last remaining one
+		if _, err := tx.Exec(ctx, `
+			UPDATE threads t SET last_post_at = COALESCE(
+			       (SELECT MAX(p.created_at) FROM posts p WHERE p.thread_id = t.id), t.created_at)
+			WHERE t.id = ANY($1::int[])`, threadIDs); err != nil {
+			return out, err
+		}
+		// Threads that others replied in stay, without the original title
+		if _, err := tx.Exec(ctx, `
+			UPDATE threads SET title = '[removed]',
+			       author_type = 'human', author_id = $3
+			WHERE `+ownedBySQL, humanID, agentIDs, tombHuman); err != nil {
+			return out, err
+		}
+	} else {
+		humanPosts, err := tx.Exec(ctx,
+			"UPDATE posts SET author_id = $2 WHERE author_type = 'human' AND author_id = $1", humanID, tombHuman)
+		if err != nil {
+			return out, err
+		}
+		agentPosts, err := tx.Exec(ctx,
+			"UPDATE posts SET author_id = $2 WHERE author_type = 'agent' AND author_id = ANY($1::int[])", agentIDs, tombAgent)
+		if err != nil {
+			return out, err
+		}
+		out.PostsAffected = int(humanPosts.RowsAffected() + agentPosts.RowsAffected())
+		if _, err := tx.Exec(ctx,
+			"UPDATE threads SET author_id = $2 WHERE author_type = 'human' AND author_id = $1", humanID, tombHuman); err != nil {
+			return out, err
+		}
+		if _, err := tx.Exec(ctx,
+			"UPDATE threads SET author_id = $2 WHERE author_type = 'agent' AND author_id = ANY($1::int[])", agentIDs, tombAgent); err != nil {
+			return out, err
+		}
+	}
+
+	// Reading state and notifications that point at the person
+	for _, stmt := range []string{
+		"DELETE FROM thread_reads WHERE (reader_type = 'human' AND reader_id = $1) OR (reader_type = 'agent' AND reader_id = ANY($2::int[]))",
+		"DELETE FROM thread_watches WHERE (watcher_type = 'human' AND watcher_id = $1) OR (watcher_type = 'agent' AND watcher_id = ANY($2::int[]))",
+		"DELETE FROM notifications WHERE (recipient_type = 'human' AND recipient_id = $1) OR (recipient_type = 'agent' AND recipient_id = ANY($2::int[]))",
+	} {
+		if _, err := tx.Exec(ctx, stmt, humanID, agentIDs); err != nil {
+			return out, err
+		}
+	}
+
+	// Invitations stay as the record of who vouched for whom, handed to the
+	// tombstone human. The codes the person issued remember the invitation
+	// they joined with, so their invitees keep their place in the invite
+	// tree; the ones not yet redeemed are revoked.
+	for _, stmt := range []struct {
+		sql  string
+		args []any
+	}{
+		{`UPDATE invitations SET issuer_invitation_id =
+		         (SELECT id FROM invitations WHERE used_by = $1 ORDER BY used_at LIMIT 1)
+		  WHERE created_by = $1`, []any{humanID}},
+		{"UPDATE invitations SET revoked_at = NOW() WHERE created_by = $1 AND " + validInvitationSQL, []any{humanID}},
+		{"UPDATE invitations SET created_by = $2 WHERE created_by = $1", []any{humanID, tombHuman}},
+		{"UPDATE invitations SET used_by = $2, twitter_handle = $3 WHERE used_by = $1", []any{humanID, tombHuman, TombstoneHumanHandle}},
+	} {
+		if _, err := tx.Exec(ctx, stmt.sql, stmt.args...); err != nil {
+			return out, err
+		}
+	}
+
+	// Export archives are removed from disk by the caller after commit
+	rows, err := tx.Query(ctx, "DELETE FROM export_jobs WHERE human_id = $1 AND file_path IS NOT NULL RETURNING file_path", humanID)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return out, err
+		}
+		out.ExportFiles = append(out.ExportFiles, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+
+	// Queued mail still holds the address
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM mail_outbox WHERE lower(to_address) = (SELECT lower(email) FROM humans WHERE id = $1)",
+		humanID); err != nil {
+		return out, err
+	}
+
+	// Revoke sessions and agent keys, then drop the personal record
+	for _, stmt := range []string{
+		"DELETE FROM sessions WHERE human_id = $1",
+		"DELETE FROM security_events WHERE human_id = $1 OR handle = (SELECT twitter_handle FROM humans WHERE id = $1)",
+		"DELETE FROM waitlist_entries WHERE lower(twitter_handle) = (SELECT lower(twitter_handle) FROM humans WHERE id = $1)",
+		"DELETE FROM agents WHERE owner_id = $1",
+		"UPDATE deletion_requests SET completed_at = NOW() WHERE human_id = $1 AND completed_at IS NULL AND cancelled_at IS NULL",
+		"DELETE FROM humans WHERE id = $1",
+	} {
+		if _, err := tx.Exec(ctx, stmt, humanID); err != nil {
+			return out, err
+		}
+	}
+
+	kind := "account_anonymized"
+	if out.Mode == DeletionPurge {
+		kind = "account_purged"
+	}
+	if err := recordTransparencyEvent(ctx, tx, kind, map[string]any{
+		"agents": out.Agents,
+		"posts":  out.PostsAffected,
+	}); err != nil {
+		return out, err
+	}
+
+	return out, tx.Commit(ctx)
+}
+
+// ensureTombstones returns the ids of the tombstone human and agent, creating them on first use.
+// The tombstone human can never log in: '!' is not a valid bcrypt hash.
+func ensureTombstones(ctx context.Context, tx pgx.Tx) (humanID, agentID int, err error) {
+	err = tx.QueryRow(ctx, `
+		WITH ins AS (
+			INSERT INTO humans (twitter_handle, password_hash) VALUES ($1, '!')
+			ON CONFLICT (twitter_handle) DO NOTHING
+			RETURNING id
+		)
+		SELECT id FROM ins
+		UNION ALL
+		SELECT id FROM humans WHERE twitter_handle = $1
+		LIMIT 1`,
+		TombstoneHumanHandle).Scan(&humanID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = tx.QueryRow(ctx,
+		"SELECT id FROM agents WHERE owner_id = $1 AND name = $2 ORDER BY id LIMIT 1",
+		humanID, TombstoneAgentName).Scan(&agentID)
+	if err == pgx.ErrNoRows {
+		// Frozen, with an impossible key hash: never authenticates
+		err = tx.QueryRow(ctx,
+			`INSERT INTO agents (owner_id, name, substrate, api_key_hash, frozen_at)
+			 VALUES ($1, $2, 'deleted', '!', NOW()) RETURNING id`,
+			humanID, TombstoneAgentName).Scan(&agentID)
+	}
+	return humanID, agentID, err
+}
@@ -0,0 +1,108 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/dbtest"
+)
+
+// TestPurgeKeepsInviteTree deletes a member in the middle of the invite tree
+// who posted in someone else's thread
+func TestPurgeKeepsInviteTree(t *testing.T) {
+	pool := dbtest.Migrated(t)
+	q := db.New(pool)
+	ctx := context.Background()
+
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	invite := func(code, handle string, by int) db.Invitation {
+		t.Helper()
+		inv, err := q.CreateInvitation(ctx, code, handle, &by, nil, nil)
+		must(err)
+		return inv
+	}
+
+	alice, err := q.CreateHuman(ctx, "alice", "x")
+	must(err)
+	bobInv := invite("code-bob", "bob", alice)
+	bob, err := q.RegisterWithInvitation(ctx, "code-bob", "bob", "x")
+	must(err)
+	carolInv := invite("code-carol", "carol", bob)
+	_, err = q.RegisterWithInvitation(ctx, "code-carol", "carol", "x")
+	must(err)
+	daveInv := invite("code-dave", "dave", bob)
+
+	space, err := q.CreateSpace(ctx, "General", "")
+	must(err)
+	thread, err := q.CreateThread(ctx, space, "Hello", "human", alice)
+	must(err)
+	_, err = q.CreatePost(ctx, thread, "human", alice, "first")
+	must(err)
+	_, err = pool.Exec(ctx, "UPDATE posts SET created_at = NOW() - INTERVAL '1 day'")
+	must(err)
+	_, err = q.CreatePost(ctx, thread, "human", bob, "reply")
+	must(err)
+
+	req, err := q.RequestAccountDeletion(ctx, bob, db.DeletionPurge, time.Now())
+	must(err)
+	out, err := q.ExecuteAccountDeletion(ctx, req)
+	must(err)
+	if out.PostsAffected != 1 {
+		t.Errorf("purge removed %d posts, want 1", out.PostsAffected)
+	}
+
+	// The thread sorts by the post that is left
+	th, err := q.GetThread(ctx, thread)
+	must(err)
+	if time.Since(th.LastPostAt) < 12*time.Hour {
+		t.Errorf("last_post_at %v still reflects the purged reply", th.LastPostAt)
+	}
+
+	// Bob's place in the tree survives under the tombstone handle
+	edges, err := q.ListInviteEdges(ctx)
+	must(err)
+	byID := map[int]db.InviteEdge{}
+	for _, e := range edges {
+		byID[e.InvitationID] = e
+	}
+	if e, ok := byID[bobInv.ID]; !ok || e.InviteeHandle != db.TombstoneHumanHandle {
+		t.Errorf("bob's invitation edge = %+v, %v; want it kept as %q", e, ok, db.TombstoneHumanHandle)
+	}
+	if e := byID[carolInv.ID]; e.ParentID == nil || *e.ParentID != bobInv.ID {
+		t.Errorf("carol's parent = %v, want bob's invitation %d", e.ParentID, bobInv.ID)
+	}
+
+	// Bob's unredeemed code dies with him
+	inv, err := q.GetInvitationByID(ctx, daveInv.ID)
+	must(err)
+	if inv.Status(time.Now()) != db.InvitationRevoked {
+		t.Errorf("dave's invitation is %s, want revoked", inv.Status(time.Now()))
+	}
+
+	// The tombstone is no member
+	if _, err := q.GetHumanByHandle(ctx, db.TombstoneHumanHandle); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetHumanByHandle(tombstone) = %v, want no rows", err)
+	}
+	stats, err := q.ListInviterStats(ctx)
+	must(err)
+	for _, s := range stats {
+		if s.Handle == db.TombstoneHumanHandle {
+			t.Error("ListInviterStats lists the tombstone")
+		}
+	}
+	hits, err := q.SearchContent(ctx, "first", db.SearchFilter{TribeHandle: db.TombstoneHumanHandle})
+	must(err)
+	if len(hits) != 0 {
+		t.Errorf("searching the tombstone's tribe found %d hits", len(hits))
+	}
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExportJob is a GDPR data export request and its produced archive
+type ExportJob struct {
+	ID           int
+	HumanID      int
+	Status       string // pending, running, ready, failed, expired
+	FilePath     *string
+	Error        *string
+	CreatedAt    time.Time
+	CompletedAt  *time.Time
+	ExpiresAt    *time.Time
+	DownloadedAt *time.Time
+}
+
+const exportJobColumns = "id, human_id, status, file_path, error, created_at, completed_at, expires_at, downloaded_at"
+
+func scanExportJob(row pgx.Row) (ExportJob, error) {
+	var j ExportJob
+	err := row.Scan(&j.ID, &j.HumanID, &j.Status, &j.FilePath, &j.Error, &j.CreatedAt, &j.CompletedAt, &j.ExpiresAt, &j.DownloadedAt)
+	return j, err
+}
+
+// CreateExportJob queues a new export for a human, returns the job id
+func (q *Queries) CreateExportJob(ctx context.Context, humanID int) (int, error) {
+	var id int
+	err := q.pool.QueryRow(ctx,
+		"INSERT INTO export_jobs (human_id) VALUES ($1) RETURNING id",
+		humanID).Scan(&id)
+	return id, err
+}
+
+// GetLatestExportJob returns the human's most recent export job
+func (q *Queries) GetLatestExportJob(ctx context.Context, humanID int) (ExportJob, error) {
+	return scanExportJob(q.pool.QueryRow(ctx,
+		"SELECT "+exportJobColumns+" FROM export_jobs WHERE human_id = $1 ORDER BY id DESC LIMIT 1",
+		humanID))
+}
+
+// ClaimExportJob marks the oldest pending job as running and returns it.
+// Returns pgx.ErrNoRows when nothing is pending. Safe across instances.
+func (q *Queries) ClaimExportJob(ctx context.Context) (ExportJob, error) {
+	return scanExportJob(q.pool.QueryRow(ctx, `
+		UPDATE export_jobs SET status = 'running'
+		WHERE id = (
+			SELECT id FROM export_jobs WHERE status = 'pending'
+			ORDER BY id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING `+exportJobColumns))
+}
+
+// CompleteExportJob records the archive path and when its download link expires
+func (q *Queries) CompleteExportJob(ctx context.Context, id int, filePath string, expiresAt time.Time) error {
+	_, err := q.pool.Exec(ctx,
+		"UPDATE export_jobs SET status = 'ready', file_path = $2, completed_at = NOW(), expires_at = $3 WHERE id = $1",
+		id, filePath, expiresAt)
+	return err
+}
+
+// FailExportJob records a build failure
+func (q *Queries) FailExportJob(ctx context.Context, id int, msg string) error {
+	_, err := q.pool.Exec(ctx,
+		"UPDATE export_jobs SET status = 'failed', error = $2, completed_at = NOW() WHERE id = $1",
+		id, msg)
+	return err
+}
+
+// ConsumeExportJob atomically marks a ready, unexpired, never-downloaded job
+// owned by humanID as downloaded and returns its archive path. Any other state
+// returns pgx.ErrNoRows, so each archive can be fetched exactly once.
+func (q *Queries) ConsumeExportJob(ctx context.Context, id, humanID int) (string, error) {
+	var path string
+	err := q.pool.QueryRow(ctx, `
+		UPDATE export_jobs SET downloaded_at = NOW()
+		WHERE id = $1 AND human_id = $2 AND status = 'ready'
+		  AND downloaded_at IS NULL AND expires_at > NOW()
+		RETURNING file_path`,
+		id, humanID).Scan(&path)
+	return path, err
+}
+
+// ListStaleExportFiles returns archives that were downloaded or whose link expired,
+// and marks those jobs expired so the files can be removed
+func (q *Queries) ListStaleExportFiles(ctx context.Context) ([]string, error) {
+	rows, err := q.pool.Query(ctx, `
+		UPDATE export_jobs SET status = 'expired'
+		WHERE status = 'ready' AND (downloaded_at IS NOT NULL OR expires_at <= NOW())
+		RETURNING file_path`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var p *string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		if p != nil {
+			paths = append(paths, *p)
+		}
+	}
+	return paths, rows.Err()
+}
+
+// AgentProfile is the full declared profile of an agent, including frozen agents
+type AgentProfile struct {
+	ID         int
+	Name       string
+	Substrate  string
+	Model      *string
+	MemoryMode *string
+	Bio        *string
+	CreatedAt  time.Time
+	FrozenAt   *time.Time
+}
+
+// ListAgentProfilesByHuman returns every agent a human owns (frozen included), oldest first
+func (q *Queries) ListAgentProfilesByHuman(ctx context.Context, humanID int) ([]AgentProfile, error) {
+	rows, err := q.pool.Query(ctx,
+		`SELECT id, name, substrate, model, memory_mode, bio, created_at, frozen_at
+		 FROM agents
+		 WHERE owner_id = $1
+		 ORDER BY created_at`,
+		humanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var agents []AgentProfile
+	for rows.Next() {
+		var a AgentProfile
+		if err := rows.Scan(&a.ID, &a.Name, &a.Substrate, &a.Model, &a.MemoryMode, &a.Bio, &a.CreatedAt, &a.FrozenAt); err != nil {
+			return nil, err
+		}
+		agents = append(agents, a)
+	}
+	return agents, rows.Err()
+}
+
+// Notification is one entry of a recipient's notification history
+type Notification struct {
+	ID            int
+	RecipientType string
+	RecipientID   int
+	Kind          string
+	ThreadID      int
+	PostID        int
+	CreatedAt     time.Time
+	ReadAt        *time.Time
+}
+
+// ListTribeNotifications returns every notification addressed to a human or their agents, oldest first
+func (q *Queries) ListTribeNotifications(ctx context.Context, humanID int) ([]Notification, error) {
+	rows, err := q.pool.Query(ctx,
+		`SELECT n.id, n.recipient_type, n.recipient_id, n.kind, n.thread_id, n.post_id, n.created_at, n.read_at
+		 FROM notifications n
+		 WHERE (n.recipient_type = 'human' AND n.recipient_id = $1)
+		    OR (n.recipient_type = 'agent' AND n.recipient_id IN (SELECT id FROM agents WHERE owner_id = $1))
+		 ORDER BY n.created_at`,
+		humanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Notification
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.RecipientType, &n.RecipientID, &n.Kind, &n.ThreadID, &n.PostID, &n.CreatedAt, &n.ReadAt); err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,311 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Invitation states, derived from used_at, revoked_at and expires_at
+const (
+	InvitationPending = "pending"
+	InvitationUsed    = "used"
+	InvitationExpired = "expired"
+	InvitationRevoked = "revoked"
+)
+
+var (
+	// ErrInviteQuota means a member has no invitations left
+	ErrInviteQuota = errors.New("invitation quota used up")
+	// ErrInvitationUnavailable means a code is used, revoked, expired or unknown
+	ErrInvitationUnavailable = errors.New("invitation code is not valid")
+)
+
+// Status reports the invitation's state at now
+func (inv Invitation) Status(now time.Time) string {
+	switch {
+	case inv.UsedAt != nil:
+		return InvitationUsed
+	case inv.RevokedAt != nil:
+		return InvitationRevoked
+	case inv.ExpiresAt != nil && !inv.ExpiresAt.After(now):
+		return InvitationExpired
+	}
+	return InvitationPending
+}
+
+// invitationSelect selects the columns scanned by scanInvitation
+const invitationSelect = `SELECT i.id, i.code, i.twitter_handle, i.created_by, i.created_at, i.used_at, i.used_by,
+	i.expires_at, i.revoked_at, i.reissued_from, c.twitter_handle, u.twitter_handle
+	FROM invitations i
+	LEFT JOIN humans c ON c.id = i.created_by
+	LEFT JOIN humans u ON u.id = i.used_by`
+
+// scanInvitation scans a row selected with invitationSelect
+func scanInvitation(row pgx.Row) (Invitation, error) {
+	var inv Invitation
+	err := row.Scan(&inv.ID, &inv.Code, &inv.TwitterHandle, &inv.CreatedBy, &inv.CreatedAt, &inv.UsedAt, &inv.UsedBy,
+		&inv.ExpiresAt, &inv.RevokedAt, &inv.ReissuedFrom, &inv.CreatedByHandle, &inv.UsedByHandle)
+	return inv, err
+}
+
+// validInvitationSQL matches invitations that can still be redeemed
+const validInvitationSQL = "used_at IS NULL AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())"
+
+// quotaUseSQL matches a member's invitations that count against their quota:
+// used ones and ones that can still be redeemed
+const quotaUseSQL = "created_by = $1 AND (used_at IS NOT NULL OR (revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())))"
+
+// CreateInvitation inserts a new invitation code for a twitter handle.
+// createdBy is nil for operator-issued codes; a member's code is refused with
+// ErrInviteQuota once their quota is used up. reissuedFrom links a code to
+// the one it replaces.
+func (q *Queries) CreateInvitation(ctx context.Context, code, twitterHandle string, createdBy *int, expiresAt *time.Time, reissuedFrom *int) (Invitation, error) {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return Invitation{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if createdBy != nil {
+		// Lock the member so concurrent invites cannot both take the last slot
+		var quota, used int
+		if err := tx.QueryRow(ctx, "SELECT invite_quota FROM humans WHERE id = $1 FOR UPDATE", *createdBy).Scan(&quota); err != nil {
+			return Invitation{}, err
+		}
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM invitations WHERE "+quotaUseSQL, *createdBy).Scan(&used); err != nil {
+			return Invitation{}, err
+		}
+		if used >= quota {
+			return Invitation{}, ErrInviteQuota
+		}
+	}
+
+	var id int
+	if err := tx.QueryRow(ctx,
+		"INSERT INTO invitations (code, twitter_handle, created_by, expires_at, reissued_from) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		code, twitterHandle, createdBy, expiresAt, reissuedFrom).Scan(&id); err != nil {
+		return Invitation{}, err
+	}
+	inv, err := scanInvitation(tx.QueryRow(ctx, invitationSelect+" WHERE i.id = $1", id))
+	if err != nil {
+		return Invitation{}, err
+	}
+	return inv, tx.Commit(ctx)
+}
+
+// GetInvitation returns an invitation by code, only if it can still be redeemed
+func (q *Queries) GetInvitation(ctx context.Context, code string) (Invitation, error) {
+	return scanInvitation(q.pool.QueryRow(ctx,
+		invitationSelect+" WHERE i.code = $1 AND i.used_at IS NULL AND i.revoked_at IS NULL AND (i.expires_at IS NULL OR i.expires_at > NOW())",
+		code))
+}
+
+// GetInvitationByID returns an invitation in any state
+func (q *Queries) GetInvitationByID(ctx context.Context, id int) (Invitation, error) {
+	return scanInvitation(q.pool.QueryRow(ctx, invitationSelect+" WHERE i.id = $1", id))
+}
+
+// RegisterWithInvitation creates a human and redeems their invitation in one
+// transaction. Fails with ErrInvitationUnavailable if the code was used,
+// revoked or expired in the meantime.
+func (q *Queries) RegisterWithInvitation(ctx context.Context, code, twitterHandle, passwordHash string) (int, error) {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	if err := tx.QueryRow(ctx,
+		"INSERT INTO humans (twitter_handle, password_hash) VALUES ($1, $2) RETURNING id",
+		twitterHandle, passwordHash).Scan(&id); err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(ctx,
+		"UPDATE invitations SET used_at = NOW(), used_by = $1 WHERE code = $2 AND "+validInvitationSQL,
+		id, code)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() != 1 {
+		return 0, ErrInvitationUnavailable
+	}
+	return id, tx.Commit(ctx)
+}
+
+// RevokeInvitation revokes an invitation that has not been used. createdBy,
+// when set, restricts this to the member's own invitations. Reports false if
+// nothing was revoked.
+func (q *Queries) RevokeInvitation(ctx context.Context, id int, createdBy *int) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		"UPDATE invitations SET revoked_at = NOW() WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL AND ($2::int IS NULL OR created_by = $2)",
+		id, createdBy)
+	return tag.RowsAffected() == 1, err
+}
+
+// HasPendingInvitation reports whether twitterHandle holds a code that can still be redeemed
+func (q *Queries) HasPendingInvitation(ctx context.Context, twitterHandle string) (bool, error) {
+	var ok bool
+	err := q.pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM invitations WHERE lower(twitter_handle) = lower($1) AND "+validInvitationSQL+")",
+		twitterHandle).Scan(&ok)
+	return ok, err
+}
+
+// InvitationFilter narrows ListInvitations; zero values match everything
+type InvitationFilter struct {
+	Status    string // one of the Invitation* states
+	CreatedBy int    // member who issued the code
+	Operator  bool   // only operator-issued codes
+	Handle    string // invited handle
+	Limit     int    // default 100, at most 1000
+}
+
+// ListInvitations returns matching invitations, newest first
+func (q *Queries) ListInvitations(ctx context.Context, f InvitationFilter) ([]Invitation, error) {
+	if f.Limit <= 0 || f.Limit > 1000 {
+		f.Limit = 100
+	}
+	rows, err := q.pool.Query(ctx,
+		invitationSelect+`
+		 WHERE CASE $1
+		         WHEN 'used' THEN i.used_at IS NOT NULL
+		         WHEN 'revoked' THEN i.used_at IS NULL AND i.revoked_at IS NOT NULL
+		         WHEN 'expired' THEN i.used_at IS NULL AND i.revoked_at IS NULL AND i.expires_at <= NOW()
+		         WHEN 'pending' THEN i.used_at IS NULL AND i.revoked_at IS NULL AND (i.expires_at IS NULL OR i.expires_at > NOW())
+		         ELSE TRUE
+		       END
+		   AND ($2 = 0 OR i.created_by = $2)
+		   AND (NOT $3 OR i.created_by IS NULL)
+		   AND ($4 = '' OR lower(i.twitter_handle) = lower($4))
+		 ORDER BY i.created_at DESC, i.id DESC
+		 LIMIT $5`,
+		f.Status, f.CreatedBy, f.Operator, f.Handle, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Invitation
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, inv)
+	}
+	return out, rows.Err()
+}
+
+// InvitationStats counts invitations by state
+type InvitationStats struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Used    int `json:"used"`
+	Expired int `json:"expired"`
+	Revoked int `json:"revoked"`
+}
+
+// GetInvitationStats counts all invitations by state
+func (q *Queries) GetInvitationStats(ctx context.Context) (InvitationStats, error) {
+	var s InvitationStats
+	err := q.pool.QueryRow(ctx, `SELECT COUNT(*),
+		COUNT(*) FILTER (WHERE used_at IS NULL AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())),
+		COUNT(*) FILTER (WHERE used_at IS NOT NULL),
+		COUNT(*) FILTER (WHERE used_at IS NULL AND revoked_at IS NULL AND expires_at <= NOW()),
+		COUNT(*) FILTER (WHERE used_at IS NULL AND revoked_at IS NOT NULL)
+		FROM invitations`).Scan(&s.Total, &s.Pending, &s.Used, &s.Expired, &s.Revoked)
+	return s, err
+}
+
+// InviterStats is one member's invitation record
+type InviterStats struct {
+	HumanID int    `json:"human_id"`
+	Handle  string `json:"handle"`
+	Quota   int    `json:"quota"`
+	Issued  int    `json:"issued"`
+	Joined  int    `json:"joined"`
+	Left    int    `json:"left"` // invitations they may still issue
+}
+
+// ListInviterStats returns every member who has issued invitations, most
+// joined first. Codes of deleted members, held by the tombstone, are left out.
+func (q *Queries) ListInviterStats(ctx context.Context) ([]InviterStats, error) {
+	rows, err := q.pool.Query(ctx, `SELECT h.id, h.twitter_handle, h.invite_quota, COUNT(i.id),
+		COUNT(i.id) FILTER (WHERE i.used_at IS NOT NULL),
+		COUNT(i.id) FILTER (WHERE i.used_at IS NOT NULL OR (i.revoked_at IS NULL AND (i.expires_at IS NULL OR i.expires_at > NOW())))
+		FROM humans h JOIN invitations i ON i.created_by = h.id
+		WHERE h.twitter_handle <> $1
+		GROUP BY h.id ORDER BY 5 DESC, 4 DESC, h.id`, TombstoneHumanHandle)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []InviterStats
+	for rows.Next() {
+		var s InviterStats
+		var counted int
+		if err := rows.Scan(&s.HumanID, &s.Handle, &s.Quota, &s.Issued, &s.Joined, &counted); err != nil {
+			return nil, err
+		}
+		s.Left = max(s.Quota-counted, 0)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// InviteQuotaLeft returns how many more invitations a member may issue
+func (q *Queries) InviteQuotaLeft(ctx context.Context, humanID int) (int, error) {
+	var left int
+	err := q.pool.QueryRow(ctx,
+		"SELECT GREATEST(h.invite_quota - (SELECT COUNT(*) FROM invitations WHERE "+quotaUseSQL+"), 0) FROM humans h WHERE h.id = $1",
+		humanID).Scan(&left)
+	return left, err
+}
+
+// SetInviteQuota sets how many invitations a member may have outstanding or used
+func (q *Queries) SetInviteQuota(ctx context.Context, humanID, quota int) error {
+	_, err := q.pool.Exec(ctx, "UPDATE humans SET invite_quota = $2 WHERE id = $1", humanID, quota)
+	return err
+}
+
+// InviteEdge records that the member who joined with invitation
+// InvitationID was vouched for by the member who joined with ParentID.
+// ParentID is nil when an operator issued the code or the inviter joined
+// without one. Deleted members keep their place under the tombstone handle.
+type InviteEdge struct {
+	InvitationID  int
+	ParentID      *int
+	InviteeHandle string
+	JoinedAt      time.Time
+}
+
+// ListInviteEdges returns who invited whom for every member who joined with an invitation
+func (q *Queries) ListInviteEdges(ctx context.Context) ([]InviteEdge, error) {
+	rows, err := q.pool.Query(ctx, `SELECT i.id,
+		COALESCE(i.issuer_invitation_id,
+		         (SELECT p.id FROM invitations p WHERE p.used_by = c.id ORDER BY p.used_at LIMIT 1)),
+		u.twitter_handle, i.used_at
+		FROM invitations i
+		JOIN humans u ON u.id = i.used_by
+		LEFT JOIN humans c ON c.id = i.created_by AND c.twitter_handle <> $1
+		ORDER BY i.used_at, i.id`, TombstoneHumanHandle)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []InviteEdge
+	for rows.Next() {
+		var e InviteEdge
+		if err := rows.Scan(&e.InvitationID, &e.ParentID, &e.InviteeHandle, &e.JoinedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// Jurisdiction sources, weakest first
+const (
+	JurisdictionDefault  = "default"  // nothing set; the column default applies
+	JurisdictionSelf     = "self"     // declared by the human in settings
+	JurisdictionVerified = "verified" // derived from a verification provider's country
+	JurisdictionAdmin    = "admin"    // operator override
+)
+
+// jurisdictionColumns are the inputs setJurisdiction may write
+var jurisdictionColumns = map[string]string{
+	JurisdictionSelf:     "jurisdiction_declared",
+	JurisdictionVerified: "jurisdiction_verified",
+	JurisdictionAdmin:    "jurisdiction_override",
+}
+
+// SetDeclaredJurisdiction records the class a human declared for themselves.
+// It only shows once no verified class or override outranks it.
+func (q *Queries) SetDeclaredJurisdiction(ctx context.Context, humanID int, class string) error {
+	return q.setJurisdiction(ctx, humanID, JurisdictionSelf, &class, nil)
+}
+
+// SetVerifiedJurisdiction records the class a verification provider
+// established, such as a phone or ID check an operator ran, nil to clear it.
+// X handle verification reports no country, so operators record this with
+// `synbridge human verify-jurisdiction`.
+func (q *Queries) SetVerifiedJurisdiction(ctx context.Context, humanID int, class *string, provider string) error {
+	var by *string
+	if class != nil {
+		by = &provider
+	}
+	return q.setJurisdiction(ctx, humanID, JurisdictionVerified, class, by)
+}
+
+// SetJurisdictionOverride sets an operator override, nil to release it
+func (q *Queries) SetJurisdictionOverride(ctx context.Context, humanID int, class *string) error {
+	return q.setJurisdiction(ctx, humanID, JurisdictionAdmin, class, nil)
+}
+
+// setJurisdiction writes one input and recomputes the effective class and its
+// source. by names the provider of a verified class and is ignored otherwise.
+func (q *Queries) setJurisdiction(ctx context.Context, humanID int, source string, class, by *string) error {
+	column, ok := jurisdictionColumns[source]
+	if !ok {
+		return errors.New("unknown jurisdiction source " + source)
+	}
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "UPDATE humans SET "+column+" = $2 WHERE id = $1", humanID, class); err != nil {
+		return err
+	}
+	if source == JurisdictionVerified {
+		if _, err := tx.Exec(ctx, "UPDATE humans SET jurisdiction_verified_by = $2 WHERE id = $1", humanID, by); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE humans SET
+		   jurisdiction = COALESCE(jurisdiction_override, jurisdiction_verified, jurisdiction_declared, 'EU-EEA'),
+		   jurisdiction_source = CASE
+		     WHEN jurisdiction_override IS NOT NULL THEN 'admin'
+		     WHEN jurisdiction_verified IS NOT NULL THEN 'verified'
+		     WHEN jurisdiction_declared IS NOT NULL THEN 'self'
+		     ELSE 'default' END
+		 WHERE id = $1`, humanID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetAuthorJurisdiction returns the class a post by this author is held to:
+// the human's own, or the owning human's for an agent
+func (q *Queries) GetAuthorJurisdiction(ctx context.Context, authorType string, authorID int) (string, error) {
+	var class string
+	err := q.pool.QueryRow(ctx,
+		`SELECT h.jurisdiction FROM humans h
+		 WHERE h.id = CASE WHEN $1 = 'agent' THEN (SELECT owner_id FROM agents WHERE id = $2) ELSE $2 END`,
+		authorType, authorID).Scan(&class)
+	return class, err
+}
+
+// SetSpaceJurisdictions restricts posting in a space to the given classes, nil to open it to everyone
+func (q *Queries) SetSpaceJurisdictions(ctx context.Context, spaceID int, allowed []string) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		"UPDATE spaces SET allowed_jurisdictions = $2 WHERE id = $1",
+		spaceID, allowed)
+	return tag.RowsAffected() == 1, err
+}
@@ -0,0 +1,54 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/dbtest"
+	"github.com/BioAILogic/agentbridge/internal/jurisdiction"
+)
+
+// TestJurisdictionPrecedence sets and clears each input in turn: an override
+// beats a verified class, which beats the human's own declaration
+func TestJurisdictionPrecedence(t *testing.T) {
+	q := dbtest.Open(t)
+	ctx := context.Background()
+	id, err := q.CreateHuman(ctx, "alice", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eea, nonEEA := jurisdiction.EEA, jurisdiction.NonEEA
+
+	steps := []struct {
+		name       string
+		set        func() error
+		class      string
+		source     string
+		verifiedBy string
+	}{
+		{"declared", func() error { return q.SetDeclaredJurisdiction(ctx, id, nonEEA) }, nonEEA, db.JurisdictionSelf, ""},
+		{"verified", func() error { return q.SetVerifiedJurisdiction(ctx, id, &eea, "phone") }, eea, db.JurisdictionVerified, "phone"},
+		{"declaration under a verified class", func() error { return q.SetDeclaredJurisdiction(ctx, id, nonEEA) }, eea, db.JurisdictionVerified, "phone"},
+		{"override", func() error { return q.SetJurisdictionOverride(ctx, id, &nonEEA) }, nonEEA, db.JurisdictionAdmin, "phone"},
+		{"override released", func() error { return q.SetJurisdictionOverride(ctx, id, nil) }, eea, db.JurisdictionVerified, "phone"},
+		{"verification cleared", func() error { return q.SetVerifiedJurisdiction(ctx, id, nil, "") }, nonEEA, db.JurisdictionSelf, ""},
+	}
+	for _, s := range steps {
+		if err := s.set(); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		h, err := q.GetHumanByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		by := ""
+		if h.JurisdictionVerifiedBy != nil {
+			by = *h.JurisdictionVerifiedBy
+		}
+		if h.Jurisdiction != s.class || h.JurisdictionSource != s.source || by != s.verifiedBy {
+			t.Errorf("%s: %s from %s verified by %q, want %s from %s verified by %q",
+				s.name, h.Jurisdiction, h.JurisdictionSource, by, s.class, s.source, s.verifiedBy)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Email token purposes
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+)
+
+// EmailToken is a consumed single-use token
+type EmailToken struct {
+	HumanID int
+	Email   *string // verify_email only
+}
+
+// OutboxMail is a queued transactional email
+type OutboxMail struct {
+	ID       int
+	To       string
+	Subject  string
+	Body     string
+	Attempts int
+}
+
+// GetHumanByEmail returns the human with this verified email (case-insensitive)
+func (q *Queries) GetHumanByEmail(ctx context.Context, email string) (Human, error) {
+	return scanHuman(q.pool.QueryRow(ctx,
+		"SELECT "+humanColumns+" FROM humans WHERE lower(email) = lower($1)",
+		email))
+}
+
+// SetHumanEmail records a newly verified email address
+func (q *Queries) SetHumanEmail(ctx context.Context, humanID int, email string) error {
+	_, err := q.pool.Exec(ctx,
+		"UPDATE humans SET email = $1, email_verified_at = NOW() WHERE id = $2",
+		email, humanID)
+	return err
+}
+
+// UpdatePasswordHash replaces a human's bcrypt hash
+func (q *Queries) UpdatePasswordHash(ctx context.Context, humanID int, passwordHash string) error {
+	_, err := q.pool.Exec(ctx, "UPDATE humans SET password_hash = $1 WHERE id = $2", passwordHash, humanID)
+	return err
+}
+
+// CreateEmailToken stores the hash of a new token and retires the human's
+// earlier unused tokens for the same purpose
+func (q *Queries) CreateEmailToken(ctx context.Context, humanID int, purpose, tokenHash string, email *string, expiresAt time.Time) error {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE email_tokens SET used_at = NOW() WHERE human_id = $1 AND purpose = $2 AND used_at IS NULL",
+		humanID, purpose); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO email_tokens (human_id, purpose, token_hash, email, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		humanID, purpose, tokenHash, email, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CheckEmailToken reports whether a token is unused and unexpired, without consuming it
+func (q *Queries) CheckEmailToken(ctx context.Context, purpose, tokenHash string) (bool, error) {
+	var ok bool
+	err := q.pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM email_tokens
+		 WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW())`,
+		tokenHash, purpose).Scan(&ok)
+	return ok, err
+}
+
+// ConsumeEmailToken marks a valid token used and returns it.
+// Unknown, used or expired tokens return pgx.ErrNoRows.
+func (q *Queries) ConsumeEmailToken(ctx context.Context, purpose, tokenHash string) (EmailToken, error) {
+	var t EmailToken
+	err := q.pool.QueryRow(ctx,
+		`UPDATE email_tokens SET used_at = NOW()
+		 WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		 RETURNING human_id, email`,
+		tokenHash, purpose).Scan(&t.HumanID, &t.Email)
+	return t, err
+}
+
+// ConsumeHumanEmailToken is ConsumeEmailToken for a token humanID asked
+// for. Another human's token is left untouched and returns pgx.ErrNoRows.
+func (q *Queries) ConsumeHumanEmailToken(ctx context.Context, purpose, tokenHash string, humanID int) (EmailToken, error) {
+	var t EmailToken
+	err := q.pool.QueryRow(ctx,
+		`UPDATE email_tokens SET used_at = NOW()
+		 WHERE token_hash = $1 AND purpose = $2 AND human_id = $3 AND used_at IS NULL AND expires_at > NOW()
+		 RETURNING human_id, email`,
+		tokenHash, purpose, humanID).Scan(&t.HumanID, &t.Email)
+	return t, err
+}
+
+// EnqueueMail adds a message to the outbox
+func (q *Queries) EnqueueMail(ctx context.Context, to, subject, body string) error {
+	_, err := q.pool.Exec(ctx,
+		"INSERT INTO mail_outbox (to_address, subject, body) VALUES ($1, $2, $3)",
+		to, subject, body)
+	return err
+}
+
+// ClaimOutboxMail takes the next due message and pushes its next attempt back,
+// so a crash mid-send retries later instead of never. Returns pgx.ErrNoRows when
+// nothing is due. Messages are given up after 8 attempts.
+func (q *Queries) ClaimOutboxMail(ctx context.Context) (OutboxMail, error) {
+	var m OutboxMail
+	err := q.pool.QueryRow(ctx, `
+		UPDATE mail_outbox
+		SET attempts = attempts + 1,
+		    next_attempt_at = NOW() + make_interval(mins => power(2, attempts)::int)
+		WHERE id = (
+			SELECT id FROM mail_outbox
+			WHERE sent_at IS NULL AND attempts < 8 AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING id, to_address, subject, body, attempts`).Scan(&m.ID, &m.To, &m.Subject, &m.Body, &m.Attempts)
+	return m, err
+}
+
+// MarkMailSent records a successful delivery
+func (q *Queries) MarkMailSent(ctx context.Context, id int) error {
+	_, err := q.pool.Exec(ctx, "UPDATE mail_outbox SET sent_at = NOW(), last_error = NULL WHERE id = $1", id)
+	return err
+}
+
+// MarkMailFailed records a delivery error; the message is retried at its next_attempt_at
+func (q *Queries) MarkMailFailed(ctx context.Context, id int, msg string) error {
+	_, err := q.pool.Exec(ctx, "UPDATE mail_outbox SET last_error = $2 WHERE id = $1", id, msg)
+	return err
+}
+
+// PruneOutbox deletes sent or abandoned messages older than the cutoff,
+// so addresses do not linger in the outbox
+func (q *Queries) PruneOutbox(ctx context.Context, olderThan time.Time) error {
+	_, err := q.pool.Exec(ctx,
+		"DELETE FROM mail_outbox WHERE created_at < $1 AND (sent_at IS NOT NULL OR attempts >= 8)",
+		olderThan)
+	return err
+}
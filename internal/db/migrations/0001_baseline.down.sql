@@ -0,0 +1 @@
+DROP TABLE IF EXISTS posts, threads, spaces, sessions, agents, invitations, humans;
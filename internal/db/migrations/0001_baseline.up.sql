@@ -0,0 +1,91 @@
+-- Baseline: the tables the prototype started with. Written with IF NOT EXISTS
+-- so that databases created by hand before migrations existed adopt it as is.
+
+CREATE TABLE IF NOT EXISTS humans (
+  id SERIAL PRIMARY KEY,
+  twitter_handle TEXT UNIQUE NOT NULL,
+  password_hash TEXT NOT NULL,
+  jurisdiction TEXT NOT NULL DEFAULT 'EU-EEA',
+  tribe_name TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS invitations (
+  id SERIAL PRIMARY KEY,
+  code TEXT UNIQUE NOT NULL,
+  twitter_handle TEXT NOT NULL,
+  created_by INT REFERENCES humans(id), -- NULL for operator-issued codes
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  used_at TIMESTAMPTZ,
+  used_by INT REFERENCES humans(id)
+);
+
+CREATE TABLE IF NOT EXISTS agents (
+  id SERIAL PRIMARY KEY,
+  owner_id INT NOT NULL REFERENCES humans(id) ON DELETE CASCADE, -- the tribe head
+  name TEXT NOT NULL,
+  substrate TEXT NOT NULL,
+  model TEXT,
+  memory_mode TEXT,
+  api_key_hash TEXT NOT NULL, -- sha256 of the agent's API key
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  frozen_at TIMESTAMPTZ
+);
+
+-- The old schema.sql called the owner column tribe_human_id and had no key
+-- column, while the code always used owner_id and api_key_hash. Bring a
+-- database created from that file in line; '!' never matches a key hash.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns
+             WHERE table_schema = current_schema() AND table_name = 'agents' AND column_name = 'tribe_human_id')
+     AND NOT EXISTS (SELECT 1 FROM information_schema.columns
+                     WHERE table_schema = current_schema() AND table_name = 'agents' AND column_name = 'owner_id') THEN
+    ALTER TABLE agents RENAME COLUMN tribe_human_id TO owner_id;
+  END IF;
+  IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                 WHERE table_schema = current_schema() AND table_name = 'agents' AND column_name = 'api_key_hash') THEN
+    ALTER TABLE agents ADD COLUMN api_key_hash TEXT NOT NULL DEFAULT '!';
+    ALTER TABLE agents ALTER COLUMN api_key_hash DROP DEFAULT;
+  END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS sessions (
+  id TEXT PRIMARY KEY,
+  human_id INT NOT NULL REFERENCES humans(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS spaces (
+  id SERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  description TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS threads (
+  id SERIAL PRIMARY KEY,
+  space_id INT NOT NULL REFERENCES spaces(id) ON DELETE CASCADE,
+  title TEXT NOT NULL,
+  author_type TEXT NOT NULL CHECK (author_type IN ('human', 'agent')),
+  author_id INT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_post_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+  id SERIAL PRIMARY KEY,
+  thread_id INT NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+  author_type TEXT NOT NULL CHECK (author_type IN ('human', 'agent')),
+  author_id INT NOT NULL,
+  content TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_threads_space ON threads(space_id);
+CREATE INDEX IF NOT EXISTS idx_threads_last_post ON threads(last_post_at DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_thread ON posts(thread_id);
+CREATE INDEX IF NOT EXISTS idx_agents_tribe ON agents(owner_id);
+CREATE INDEX IF NOT EXISTS idx_agents_key ON agents(api_key_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);
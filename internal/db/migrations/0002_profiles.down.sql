@@ -0,0 +1,3 @@
+ALTER TABLE agents DROP COLUMN IF EXISTS bio;
+ALTER TABLE humans DROP COLUMN IF EXISTS location;
+ALTER TABLE humans DROP COLUMN IF EXISTS bio;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS notifications, thread_watches, thread_reads;
@@ -0,0 +1,37 @@
+-- Per-reader thread read markers, thread watches, notifications
+
+-- Read marker per reader (human or agent) per thread
+CREATE TABLE IF NOT EXISTS thread_reads (
+  reader_type TEXT NOT NULL CHECK (reader_type IN ('human', 'agent')),
+  reader_id INT NOT NULL,
+  thread_id INT NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+  last_read_post_id INT NOT NULL DEFAULT 0,
+  read_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (reader_type, reader_id, thread_id)
+);
+
+-- Watch state per reader per thread. watching = FALSE records an explicit
+-- unwatch so that posting again does not silently re-subscribe.
+CREATE TABLE IF NOT EXISTS thread_watches (
+  watcher_type TEXT NOT NULL CHECK (watcher_type IN ('human', 'agent')),
+  watcher_id INT NOT NULL,
+  thread_id INT NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+  watching BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (watcher_type, watcher_id, thread_id)
+);
+
+-- Notifications (replies in watched threads; not algorithmic)
+CREATE TABLE IF NOT EXISTS notifications (
+  id SERIAL PRIMARY KEY,
+  recipient_type TEXT NOT NULL CHECK (recipient_type IN ('human', 'agent')),
+  recipient_id INT NOT NULL,
+  kind TEXT NOT NULL,
+  thread_id INT NOT NULL REFERENCES threads(id) ON DELETE CASCADE,
+  post_id INT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  read_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_thread_watches_thread ON thread_watches(thread_id) WHERE watching;
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient ON notifications(recipient_type, recipient_id) WHERE read_at IS NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_posts_created;
+ALTER TABLE threads DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE threads DROP COLUMN IF EXISTS lang;
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE posts DROP COLUMN IF EXISTS lang;
+ALTER TABLE humans DROP COLUMN IF EXISTS language;
@@ -0,0 +1,16 @@
+-- Full-text search over post content and thread titles
+
+-- Text search configuration each human (and their agents) writes in
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'simple';
+
+-- Posts and threads are indexed with their author's language at creation time
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS lang regconfig NOT NULL DEFAULT 'simple';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+  GENERATED ALWAYS AS (to_tsvector(lang, content)) STORED;
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS lang regconfig NOT NULL DEFAULT 'simple';
+ALTER TABLE threads ADD COLUMN IF NOT EXISTS search_vector tsvector
+  GENERATED ALWAYS AS (to_tsvector(lang, title)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_threads_search ON threads USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_posts_created ON posts(created_at);
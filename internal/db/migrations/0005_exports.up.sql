@@ -0,0 +1,16 @@
+-- GDPR data export jobs
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+  id SERIAL PRIMARY KEY,
+  human_id INT NOT NULL REFERENCES humans(id) ON DELETE CASCADE,
+  status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'ready', 'failed', 'expired')),
+  file_path TEXT,
+  error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  completed_at TIMESTAMPTZ,
+  expires_at TIMESTAMPTZ,
+  downloaded_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_human ON export_jobs(human_id);
+CREATE INDEX IF NOT EXISTS idx_export_jobs_pending ON export_jobs(id) WHERE status = 'pending';
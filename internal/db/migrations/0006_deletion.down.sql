@@ -0,0 +1 @@
+DROP TABLE IF EXISTS transparency_events, deletion_requests;
@@ -0,0 +1,24 @@
+-- Account deletion requests and transparency report events
+
+CREATE TABLE IF NOT EXISTS deletion_requests (
+  id SERIAL PRIMARY KEY,
+  human_id INT REFERENCES humans(id) ON DELETE SET NULL,
+  mode TEXT NOT NULL CHECK (mode IN ('anonymize', 'purge')),
+  requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  scheduled_for TIMESTAMPTZ NOT NULL,
+  cancelled_at TIMESTAMPTZ,
+  completed_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS transparency_events (
+  id SERIAL PRIMARY KEY,
+  kind TEXT NOT NULL,
+  detail JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deletion_requests_open ON deletion_requests(human_id)
+  WHERE cancelled_at IS NULL AND completed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_deletion_requests_due ON deletion_requests(scheduled_for)
+  WHERE cancelled_at IS NULL AND completed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_transparency_events_created ON transparency_events(created_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS mail_outbox, email_tokens;
+DROP INDEX IF EXISTS idx_humans_email;
+ALTER TABLE humans DROP COLUMN IF EXISTS email_verified_at;
+ALTER TABLE humans DROP COLUMN IF EXISTS email;
@@ -0,0 +1,31 @@
+-- Optional verified email, single-use email tokens, mail outbox
+
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS email TEXT;
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_humans_email ON humans(lower(email)) WHERE email IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS email_tokens (
+  id SERIAL PRIMARY KEY,
+  human_id INT NOT NULL REFERENCES humans(id) ON DELETE CASCADE,
+  purpose TEXT NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+  token_hash TEXT UNIQUE NOT NULL,
+  email TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS mail_outbox (
+  id SERIAL PRIMARY KEY,
+  to_address TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  body TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_error TEXT,
+  sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_tokens_human ON email_tokens(human_id);
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_pending ON mail_outbox(next_attempt_at) WHERE sent_at IS NULL;
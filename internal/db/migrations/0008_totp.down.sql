@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS pending_logins, recovery_codes;
+ALTER TABLE humans DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE humans DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE humans DROP COLUMN IF EXISTS totp_secret;
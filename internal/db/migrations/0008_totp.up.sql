@@ -0,0 +1,24 @@
+-- TOTP two-factor authentication, recovery codes, pending logins
+
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id SERIAL PRIMARY KEY,
+  human_id INT NOT NULL REFERENCES humans(id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  used_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS pending_logins (
+  id TEXT PRIMARY KEY,
+  human_id INT NOT NULL REFERENCES humans(id) ON DELETE CASCADE,
+  attempts INT NOT NULL DEFAULT 0,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_recovery_codes_human ON recovery_codes(human_id) WHERE used_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_pending_logins_expires ON pending_logins(expires_at);
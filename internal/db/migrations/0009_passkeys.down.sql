@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS webauthn_ceremonies, passkeys;
+ALTER TABLE humans DROP COLUMN IF EXISTS webauthn_id;
@@ -0,0 +1,24 @@
+-- WebAuthn passkeys and passkey-only accounts
+
+-- Opaque WebAuthn user handle, generated on first passkey registration
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS webauthn_id BYTEA UNIQUE;
+
+CREATE TABLE IF NOT EXISTS passkeys (
+  id SERIAL PRIMARY KEY,
+  human_id INT NOT NULL REFERENCES humans(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  credential_id BYTEA UNIQUE NOT NULL,
+  credential JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies (
+  id TEXT PRIMARY KEY,
+  human_id INT REFERENCES humans(id) ON DELETE CASCADE,
+  data JSONB NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_passkeys_human ON passkeys(human_id);
+CREATE INDEX IF NOT EXISTS idx_webauthn_ceremonies_expires ON webauthn_ceremonies(expires_at);
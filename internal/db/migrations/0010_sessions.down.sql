@@ -0,0 +1,9 @@
+-- Session ids stay hashed: the raw cookie values are gone, so everyone is
+-- signed out by rolling back past this point.
+DELETE FROM sessions;
+DROP INDEX IF EXISTS idx_sessions_human;
+ALTER TABLE pending_logins DROP COLUMN IF EXISTS remember;
+ALTER TABLE sessions DROP COLUMN IF EXISTS remember;
+ALTER TABLE sessions DROP COLUMN IF EXISTS ip_prefix;
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_seen_at;
@@ -0,0 +1,21 @@
+-- Hashed session tokens, device info, sliding expiry and remember-me
+
+-- Existing rows hold the raw cookie value; replace it with its sha256 so
+-- signed-in users stay signed in. Guarded so a second run does not re-hash.
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                 WHERE table_name = 'sessions' AND column_name = 'last_seen_at') THEN
+    UPDATE sessions SET id = encode(sha256(convert_to(id, 'UTF8')), 'hex');
+  END IF;
+END $$;
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip_prefix TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS remember BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- "Remember me" must survive the second-factor step
+ALTER TABLE pending_logins ADD COLUMN IF NOT EXISTS remember BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_human ON sessions(human_id);
@@ -0,0 +1,16 @@
+-- Security event log (sign-in failures, lockouts) for brute-force protection
+
+CREATE TABLE IF NOT EXISTS security_events (
+  id BIGSERIAL PRIMARY KEY,
+  kind TEXT NOT NULL,
+  human_id INT REFERENCES humans(id) ON DELETE SET NULL,
+  handle TEXT NOT NULL DEFAULT '',
+  ip_prefix TEXT NOT NULL DEFAULT '',
+  detail JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_handle ON security_events(handle, created_at) WHERE kind IN ('login_failed', 'login_succeeded', 'password_reset');
+CREATE INDEX IF NOT EXISTS idx_security_events_ip ON security_events(ip_prefix, created_at) WHERE kind = 'login_failed';
+CREATE INDEX IF NOT EXISTS idx_security_events_created ON security_events(created_at);
+CREATE INDEX IF NOT EXISTS idx_security_events_human ON security_events(human_id, created_at);
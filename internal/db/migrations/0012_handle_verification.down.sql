@@ -0,0 +1 @@
+DROP TABLE IF EXISTS handle_verification_flows, handle_verifications;
@@ -0,0 +1,21 @@
+-- X handle ownership verification
+
+-- Receipt of a successful verification. The provider's tokens are never stored.
+CREATE TABLE IF NOT EXISTS handle_verifications (
+  human_id INT PRIMARY KEY REFERENCES humans(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL, -- 'x'
+  subject TEXT NOT NULL, -- the provider's stable account id
+  handle TEXT NOT NULL, -- the username the provider reported
+  verified_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  UNIQUE (provider, subject)
+);
+
+-- In-flight OAuth authorizations, keyed by the hash of their state parameter
+CREATE TABLE IF NOT EXISTS handle_verification_flows (
+  id TEXT PRIMARY KEY,
+  human_id INT NOT NULL REFERENCES humans(id) ON DELETE CASCADE,
+  code_verifier TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_handle_verification_flows_expires ON handle_verification_flows(expires_at);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_invitations_handle;
+DROP INDEX IF EXISTS idx_invitations_created_by;
+ALTER TABLE humans DROP COLUMN IF EXISTS invite_quota;
+ALTER TABLE invitations DROP COLUMN IF EXISTS reissued_from;
+ALTER TABLE invitations DROP COLUMN IF EXISTS revoked_at;
+ALTER TABLE invitations DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,11 @@
+-- Invitation expiry, revocation, re-issue and member quotas
+
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ; -- NULL: never expires
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS reissued_from INT REFERENCES invitations(id) ON DELETE SET NULL;
+
+-- Invitations a member may have outstanding or used
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS invite_quota INT NOT NULL DEFAULT 3;
+
+CREATE INDEX IF NOT EXISTS idx_invitations_created_by ON invitations(created_by);
+CREATE INDEX IF NOT EXISTS idx_invitations_handle ON invitations(lower(twitter_handle));
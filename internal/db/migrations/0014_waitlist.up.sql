@@ -0,0 +1,15 @@
+-- Waitlist in the database (replaces /opt/synbridge/waitlist.txt; import the
+-- old file with: go run ./cmd/waitlist-import /opt/synbridge/waitlist.txt)
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+  id SERIAL PRIMARY KEY,
+  twitter_handle TEXT NOT NULL, -- normalized: lowercase, no @
+  source TEXT NOT NULL, -- where the request came from, e.g. 'landing' or 'waitlist.txt'
+  consented_at TIMESTAMPTZ NOT NULL, -- when they agreed to be contacted on X
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  invitation_id INT REFERENCES invitations(id) ON DELETE SET NULL,
+  invited_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_waitlist_handle ON waitlist_entries(lower(twitter_handle));
+CREATE INDEX IF NOT EXISTS idx_waitlist_created ON waitlist_entries(created_at);
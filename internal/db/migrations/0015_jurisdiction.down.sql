@@ -0,0 +1,5 @@
+ALTER TABLE spaces DROP COLUMN IF EXISTS allowed_jurisdictions;
+ALTER TABLE humans DROP COLUMN IF EXISTS jurisdiction_override;
+ALTER TABLE humans DROP COLUMN IF EXISTS jurisdiction_verified;
+ALTER TABLE humans DROP COLUMN IF EXISTS jurisdiction_declared;
+ALTER TABLE humans DROP COLUMN IF EXISTS jurisdiction_source;
@@ -0,0 +1,11 @@
+-- Jurisdiction classes (self-declared, verified, admin override) and per-space posting restrictions
+
+-- humans.jurisdiction stays the effective class shown on posts; it is
+-- recomputed from the columns below: override, then verified, then declared
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS jurisdiction_source TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS jurisdiction_declared TEXT;
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS jurisdiction_verified TEXT;
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS jurisdiction_override TEXT;
+
+-- NULL: anyone may post; otherwise only authors in one of the listed classes
+ALTER TABLE spaces ADD COLUMN IF NOT EXISTS allowed_jurisdictions TEXT[];
@@ -0,0 +1,4 @@
+ALTER TABLE spaces DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE humans DROP COLUMN IF EXISTS suspension_reason;
+ALTER TABLE humans DROP COLUMN IF EXISTS suspended_at;
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,19 @@
+-- Operator actions: audit log, suspended humans, archived spaces
+
+-- Every operator action from the synbridge CLI
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL,               -- e.g. 'cli:root'
+    action TEXT NOT NULL,              -- e.g. 'agent.freeze'
+    target TEXT NOT NULL DEFAULT '',   -- e.g. 'agent:12'
+    detail JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+
+-- A suspended human cannot sign in, and their agents' keys stop working
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMPTZ;
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS suspension_reason TEXT;
+
+-- An archived space is read-only and no longer listed
+ALTER TABLE spaces ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;
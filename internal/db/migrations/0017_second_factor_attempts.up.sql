@@ -0,0 +1,7 @@
+-- Second-factor codes tried per human within the current window, across
+-- the sign-in step and the step-up prompts (export, deletion, agent keys)
+CREATE TABLE IF NOT EXISTS second_factor_attempts (
+    human_id INT PRIMARY KEY REFERENCES humans(id) ON DELETE CASCADE,
+    attempts INT NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+ALTER TABLE passkeys DROP COLUMN IF EXISTS flagged_at;
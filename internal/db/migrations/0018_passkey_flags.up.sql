@@ -0,0 +1,3 @@
+-- A passkey whose signature counter went backwards may have been copied;
+-- it is flagged and no longer accepted for sign-in
+ALTER TABLE passkeys ADD COLUMN IF NOT EXISTS flagged_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_security_events_reset_ip;
+DROP INDEX IF EXISTS idx_security_events_reset_handle;
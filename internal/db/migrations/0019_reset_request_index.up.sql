@@ -0,0 +1,4 @@
+-- Password reset requests are counted per handle and per network, so that
+-- nobody can flood an inbox with reset mail
+CREATE INDEX IF NOT EXISTS idx_security_events_reset_handle ON security_events(handle, created_at) WHERE kind = 'reset_requested';
+CREATE INDEX IF NOT EXISTS idx_security_events_reset_ip ON security_events(ip_prefix, created_at) WHERE kind = 'reset_requested';
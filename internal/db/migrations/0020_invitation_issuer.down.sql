@@ -0,0 +1 @@
+ALTER TABLE invitations DROP COLUMN IF EXISTS issuer_invitation_id;
@@ -0,0 +1,4 @@
+-- When a member deletes their account, the codes they issued are handed to
+-- the tombstone human. issuer_invitation_id keeps the invitation that member
+-- joined with, so their invitees keep their place in the invite tree.
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS issuer_invitation_id INT REFERENCES invitations(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+ALTER TABLE humans DROP COLUMN IF EXISTS jurisdiction_verified_by;
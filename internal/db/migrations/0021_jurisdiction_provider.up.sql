@@ -0,0 +1,2 @@
+-- Who vouched for humans.jurisdiction_verified, e.g. a phone or ID check
+ALTER TABLE humans ADD COLUMN IF NOT EXISTS jurisdiction_verified_by TEXT;
@@ -0,0 +1,294 @@
+// Package migrations applies the database schema. Each change is a pair of
+// numbered files, NNNN_name.up.sql and NNNN_name.down.sql, embedded in the
+// binary. Applied versions are recorded in schema_migrations together with
+// the checksum of their up file, so an edited migration is caught instead of
+// silently diverging. A Postgres advisory lock keeps concurrent instances
+// from migrating at the same time.
+//
+// Migrations up to 0015 were run by hand with psql before this package
+// existed. They are idempotent, so `synbridge migrate up` on such a database
+// re-applies them harmlessly and records them.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockKey identifies the advisory lock held while migrating ("synbridg")
+const lockKey int64 = 0x73796e6272696467
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 of Up
+}
+
+// Status is a migration and whether it has been applied
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	Modified  bool // applied with a different checksum than the embedded file
+}
+
+var fileName = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// All returns the embedded migrations in version order
+func All() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := files.ReadFile(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+			sum := sha256.Sum256(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	for i, mig := range out {
+		if mig.Version != i+1 {
+			return nil, fmt.Errorf("migration %04d_%s is out of sequence, expected %04d", mig.Version, mig.Name, i+1)
+		}
+	}
+	return out, nil
+}
+
+// ErrModified means an applied migration no longer matches its embedded file
+var ErrModified = errors.New("applied migration was modified")
+
+// Runner applies and reverts migrations on a database
+type Runner struct {
+	Pool *pgxpool.Pool
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the ones applied
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := r.locked(ctx, func(conn *pgxpool.Conn) error {
+		status, err := status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			if s.Modified {
+				return fmt.Errorf("%w: %04d_%s", ErrModified, s.Version, s.Name)
+			}
+		}
+		for _, s := range status {
+			if s.AppliedAt != nil {
+				continue
+			}
+			if err := apply(ctx, conn, s.Migration.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx,
+					"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+					s.Version, s.Name, s.Checksum)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", s.Version, s.Name, err)
+			}
+			applied = append(applied, s.Migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns the ones reverted
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := r.locked(ctx, func(conn *pgxpool.Conn) error {
+		status, err := status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(status) - 1; i >= 0 && len(reverted) < steps; i-- {
+			s := status[i]
+			if s.AppliedAt == nil {
+				continue
+			}
+			if s.Modified {
+				return fmt.Errorf("%w: %04d_%s", ErrModified, s.Version, s.Name)
+			}
+			if err := apply(ctx, conn, s.Migration.Down, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", s.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("reverting %04d_%s: %w", s.Version, s.Name, err)
+			}
+			reverted = append(reverted, s.Migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every embedded migration with its applied state
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	conn, err := r.Pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	return status(ctx, conn)
+}
+
+// Pending counts migrations that are not applied yet. It does not create
+// schema_migrations, so it is safe to call from health checks.
+func (r *Runner) Pending(ctx context.Context) (int, error) {
+	all, err := All()
+	if err != nil {
+		return 0, err
+	}
+	var applied int
+	err = r.Pool.QueryRow(ctx,
+		`SELECT CASE WHEN to_regclass('schema_migrations') IS NULL THEN 0
+		        ELSE (SELECT COUNT(*) FROM schema_migrations) END`).Scan(&applied)
+	if err != nil {
+		return 0, err
+	}
+	return max(len(all)-applied, 0), nil
+}
+
+// locked runs fn on one connection while holding the migration lock
+func (r *Runner) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := r.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL, -- sha256 of the up file
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`)
+	return err
+}
+
+// status pairs the embedded migrations with schema_migrations
+func status(ctx context.Context, conn *pgxpool.Conn) ([]Status, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	type row struct {
+		checksum  string
+		appliedAt time.Time
+	}
+	applied := map[int]row{}
+	rows, err := conn.Query(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		var r row
+		if err := rows.Scan(&v, &r.checksum, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[v] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, len(all))
+	for i, m := range all {
+		out[i].Migration = m
+		if r, ok := applied[m.Version]; ok {
+			out[i].AppliedAt = &r.appliedAt
+			out[i].Modified = r.checksum != m.Checksum
+			delete(applied, m.Version)
+		}
+	}
+	for v := range applied {
+		return nil, fmt.Errorf("database has migration %04d, which this binary does not know; is it older than the database?", v)
+	}
+	return out, nil
+}
+
+// apply runs sql and record in one transaction
+func apply(ctx context.Context, conn *pgxpool.Conn, sql string, record func(pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// No arguments: pgx sends this over the simple protocol, which allows
+	// several statements in one call
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
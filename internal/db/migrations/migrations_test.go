@@ -0,0 +1,302 @@
+package migrations_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/dbtest"
+	"github.com/BioAILogic/agentbridge/internal/db/migrations"
+)
+
+func TestAll(t *testing.T) {
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Errorf("migration %d is numbered %04d", i+1, m.Version)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("%04d_%s lacks an up or down file", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		if m.Checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("%04d_%s: checksum %s is not the sha256 of its up file", m.Version, m.Name, m.Checksum)
+		}
+	}
+}
+
+func TestUpDownUp(t *testing.T) {
+	pool := dbtest.Pool(t)
+	ctx := context.Background()
+	r := &migrations.Runner{Pool: pool}
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	up := func() string {
+		t.Helper()
+		applied, err := r.Up(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(applied) != len(all) {
+			t.Fatalf("applied %d migrations, want %d", len(applied), len(all))
+		}
+		checkApplied(t, r, pool, all)
+		if err := db.New(pool).CheckSchema(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return catalog(t, pool)
+	}
+
+	first := up()
+	if n, err := r.Pending(ctx); err != nil || n != 0 {
+		t.Fatalf("Pending = %d, %v after up", n, err)
+	}
+	if again, err := r.Up(ctx); err != nil || len(again) != 0 {
+		t.Fatalf("second up applied %d, %v", len(again), err)
+	}
+
+	reverted, err := r.Down(ctx, len(all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != len(all) || reverted[0].Version != len(all) {
+		t.Fatalf("down reverted %d migrations, newest %04d", len(reverted), reverted[0].Version)
+	}
+	tables, err := db.New(pool).UserTables(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tables, []string{"schema_migrations"}) {
+		t.Errorf("tables left after down: %v", tables)
+	}
+	if n, err := r.Pending(ctx); err != nil || n != len(all) {
+		t.Fatalf("Pending = %d, %v after down", n, err)
+	}
+
+	if second := up(); second != first {
+		t.Errorf("schema after up, down, up differs from the first up:\n%s", diffLines(first, second))
+	}
+}
+
+func TestModifiedMigrationRefused(t *testing.T) {
+	pool := dbtest.Pool(t)
+	ctx := context.Background()
+	r := &migrations.Runner{Pool: pool}
+	if _, err := r.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, "UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1"); err != nil {
+		t.Fatal(err)
+	}
+	status, err := r.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status[0].Modified {
+		t.Error("Status does not report the edited migration")
+	}
+	if _, err := r.Up(ctx); !errors.Is(err, migrations.ErrModified) {
+		t.Errorf("Up = %v, want ErrModified", err)
+	}
+	if _, err := r.Down(ctx, len(status)); !errors.Is(err, migrations.ErrModified) {
+		t.Errorf("Down = %v, want ErrModified", err)
+	}
+}
+
+// TestQueriesMatchCatalog prepares every constant SQL statement in package db
+// against the migrated schema, so a query naming a table or column that no
+// migration creates fails here rather than in production
+func TestQueriesMatchCatalog(t *testing.T) {
+	pool := dbtest.Migrated(t)
+	ctx := context.Background()
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Release()
+
+	queries := dbQueries(t)
+	if len(queries) == 0 {
+		t.Fatal("found no queries in package db")
+	}
+	for _, q := range queries {
+		_, err := conn.Conn().PgConn().Prepare(ctx, "", q.sql, nil)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "42P18" {
+			continue // a parameter whose type only the bound value decides
+		}
+		if err != nil {
+			t.Errorf("%s: %v\n%s", q.pos, err, q.sql)
+		}
+	}
+}
+
+// checkApplied compares schema_migrations with the embedded migrations
+func checkApplied(t *testing.T, r *migrations.Runner, pool *pgxpool.Pool, all []migrations.Migration) {
+	t.Helper()
+	ctx := context.Background()
+	status, err := r.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil || s.Modified {
+			t.Errorf("%04d_%s: applied %v, modified %v", s.Version, s.Name, s.AppliedAt != nil, s.Modified)
+		}
+	}
+	rows, err := pool.Query(ctx, "SELECT version, name, checksum FROM schema_migrations ORDER BY version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	i := 0
+	for ; rows.Next(); i++ {
+		var version int
+		var name, checksum string
+		if err := rows.Scan(&version, &name, &checksum); err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(all) || version != all[i].Version || name != all[i].Name || checksum != all[i].Checksum {
+			t.Errorf("schema_migrations row %04d_%s %s does not match the embedded file", version, name, checksum)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if i != len(all) {
+		t.Errorf("schema_migrations has %d rows, want %d", i, len(all))
+	}
+}
+
+// catalog describes the tables, columns, indexes and constraints of the
+// current schema, one per line
+func catalog(t *testing.T, pool *pgxpool.Pool) string {
+	t.Helper()
+	rows, err := pool.Query(context.Background(), `
+		SELECT table_name || '.' || column_name || ' ' || data_type || ' ' || is_nullable || ' ' || COALESCE(column_default, '')
+		  FROM information_schema.columns WHERE table_schema = current_schema()
+		UNION ALL
+		SELECT regexp_replace(indexdef, 'test_[0-9a-f]+\.', '')
+		  FROM pg_indexes WHERE schemaname = current_schema()
+		UNION ALL
+		SELECT conrelid::regclass || ' ' || conname || ' ' || pg_get_constraintdef(oid)
+		  FROM pg_constraint WHERE connamespace = current_schema()::regnamespace
+		ORDER BY 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var b strings.Builder
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			t.Fatal(err)
+		}
+		b.WriteString(line + "\n")
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func diffLines(a, b string) string {
+	as, bs := strings.Split(a, "\n"), strings.Split(b, "\n")
+	var out []string
+	for _, l := range as {
+		if !slices.Contains(bs, l) {
+			out = append(out, "- "+l)
+		}
+	}
+	for _, l := range bs {
+		if !slices.Contains(as, l) {
+			out = append(out, "+ "+l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+type query struct {
+	pos string
+	sql string
+}
+
+// queryMethods are the pgx calls whose first argument after the context is SQL
+var queryMethods = map[string]bool{"Exec": true, "Query": true, "QueryRow": true}
+
+var sqlStart = regexp.MustCompile(`(?i)^\s*(SELECT|INSERT|UPDATE|DELETE|WITH)\b`)
+
+// dbQueries returns the SQL passed to pgx in package db wherever it is a
+// constant. Queries assembled at run time are left out.
+func dbQueries(t *testing.T) []query {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("..", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, p := range paths {
+		if strings.HasSuffix(p, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, p, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	// Only constant values are needed, so imports may fail to resolve;
+	// the checker keeps going and still folds the package's constants.
+	info := &types.Info{Types: map[ast.Expr]types.TypeAndValue{}}
+	conf := types.Config{Importer: importer.Default(), Error: func(error) {}}
+	conf.Check("db", fset, files, info)
+
+	var out []query
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || !queryMethods[sel.Sel.Name] {
+				return true
+			}
+			tv := info.Types[call.Args[1]]
+			if tv.Value == nil || tv.Value.Kind() != constant.String {
+				return true
+			}
+			sql := constant.StringVal(tv.Value)
+			if sqlStart.MatchString(sql) {
+				out = append(out, query{pos: fmt.Sprint(fset.Position(call.Pos())), sql: sql})
+			}
+			return true
+		})
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is one operator action from the audit log
+type AuditEntry struct {
+	ID        int64
+	Actor     string
+	Action    string
+	Target    string
+	Detail    map[string]any
+	CreatedAt time.Time
+}
+
+// RecordAudit stores an operator action. target names the affected record,
+// e.g. "agent:12"; detail holds whatever the action needs to be understood later.
+func (q *Queries) RecordAudit(ctx context.Context, actor, action, target string, detail map[string]any) error {
+	if detail == nil {
+		detail = map[string]any{}
+	}
+	b, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	_, err = q.pool.Exec(ctx,
+		"INSERT INTO audit_log (actor, action, target, detail) VALUES ($1, $2, $3, $4)",
+		actor, action, target, b)
+	return err
+}
+
+// ListAuditLog returns the latest operator actions, newest first
+func (q *Queries) ListAuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	rows, err := q.pool.Query(ctx,
+		"SELECT id, actor, action, target, detail, created_at FROM audit_log ORDER BY id DESC LIMIT $1",
+		limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Target, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// SetAgentFrozen freezes an agent (its key stops working, it disappears from
+// its owner's list) or thaws it. Freezes count in the transparency report.
+// Reports false if the agent does not exist or already was in that state.
+func (q *Queries) SetAgentFrozen(ctx context.Context, agentID int, frozen bool) (bool, error) {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	stmt := "UPDATE agents SET frozen_at = NOW() WHERE id = $1 AND frozen_at IS NULL"
+	kind := "agent_frozen"
+	if !frozen {
+		stmt = "UPDATE agents SET frozen_at = NULL WHERE id = $1 AND frozen_at IS NOT NULL"
+		kind = "agent_unfrozen"
+	}
+	tag, err := tx.Exec(ctx, stmt, agentID)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if err := recordTransparencyEvent(ctx, tx, kind, nil); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// IsFrozenAgentKey reports whether keyHash belongs to a frozen agent, to tell
+// a frozen agent's attempts apart from wrong keys
+func (q *Queries) IsFrozenAgentKey(ctx context.Context, keyHash string) (bool, error) {
+	var frozen bool
+	err := q.pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM agents WHERE api_key_hash = $1 AND frozen_at IS NOT NULL)",
+		keyHash).Scan(&frozen)
+	return frozen, err
+}
+
+// SuspendHuman suspends an account: it is signed out everywhere, cannot sign
+// in again and its agents' keys stop working until UnsuspendHuman. Reports
+// false if the human does not exist or is already suspended.
+func (q *Queries) SuspendHuman(ctx context.Context, humanID int, reason string) (bool, error) {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		"UPDATE humans SET suspended_at = NOW(), suspension_reason = NULLIF($2, '') WHERE id = $1 AND suspended_at IS NULL",
+		humanID, reason)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	for _, stmt := range []string{
+		"DELETE FROM sessions WHERE human_id = $1",
+		"DELETE FROM pending_logins WHERE human_id = $1",
+	} {
+		if _, err := tx.Exec(ctx, stmt, humanID); err != nil {
+			return false, err
+		}
+	}
+	if err := recordTransparencyEvent(ctx, tx, "human_suspended", nil); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// UnsuspendHuman lifts a suspension. Reports false if the human was not suspended.
+func (q *Queries) UnsuspendHuman(ctx context.Context, humanID int) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		"UPDATE humans SET suspended_at = NULL, suspension_reason = NULL WHERE id = $1 AND suspended_at IS NOT NULL",
+		humanID)
+	return tag.RowsAffected() == 1, err
+}
+
+// ArchiveSpace makes a space read-only and drops it from the space lists.
+// Reports false if the space does not exist or is already archived.
+func (q *Queries) ArchiveSpace(ctx context.Context, spaceID int) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		"UPDATE spaces SET archived_at = NOW() WHERE id = $1 AND archived_at IS NULL",
+		spaceID)
+	return tag.RowsAffected() == 1, err
+}
+
+// CountSpaces counts every space, archived ones included
+func (q *Queries) CountSpaces(ctx context.Context) (int, error) {
+	var n int
+	err := q.pool.QueryRow(ctx, "SELECT COUNT(*) FROM spaces").Scan(&n)
+	return n, err
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Passkey is a registered WebAuthn credential
+type Passkey struct {
+	ID           int
+	HumanID      int
+	Name         string
+	CredentialID []byte
+	Credential   []byte // JSON credential record (public key, sign count, flags)
+	CreatedAt    time.Time
+	LastUsedAt   *time.Time
+	FlaggedAt    *time.Time // possibly cloned; refused at sign-in
+}
+
+const passkeyColumns = "id, human_id, name, credential_id, credential, created_at, last_used_at, flagged_at"
+
+// EnsureWebAuthnID returns the human's WebAuthn user handle, storing candidate if none exists yet
+func (q *Queries) EnsureWebAuthnID(ctx context.Context, humanID int, candidate []byte) ([]byte, error) {
+	var id []byte
+	err := q.pool.QueryRow(ctx,
+		"UPDATE humans SET webauthn_id = COALESCE(webauthn_id, $2) WHERE id = $1 RETURNING webauthn_id",
+		humanID, candidate).Scan(&id)
+	return id, err
+}
+
+// GetHumanByWebAuthnID returns the human owning a WebAuthn user handle
+func (q *Queries) GetHumanByWebAuthnID(ctx context.Context, webauthnID []byte) (Human, error) {
+	return scanHuman(q.pool.QueryRow(ctx,
+		"SELECT "+humanColumns+" FROM humans WHERE webauthn_id = $1",
+		webauthnID))
+}
+
+// ListPasskeys returns a human's passkeys, oldest first
+func (q *Queries) ListPasskeys(ctx context.Context, humanID int) ([]Passkey, error) {
+	rows, err := q.pool.Query(ctx,
+		"SELECT "+passkeyColumns+" FROM passkeys WHERE human_id = $1 ORDER BY created_at",
+		humanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []Passkey
+	for rows.Next() {
+		var p Passkey
+		if err := rows.Scan(&p.ID, &p.HumanID, &p.Name, &p.CredentialID, &p.Credential, &p.CreatedAt, &p.LastUsedAt, &p.FlaggedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, p)
+	}
+	return keys, rows.Err()
+}
+
+// CreatePasskey stores a newly registered credential
+func (q *Queries) CreatePasskey(ctx context.Context, humanID int, name string, credentialID, credential []byte) error {
+	_, err := q.pool.Exec(ctx,
+		"INSERT INTO passkeys (human_id, name, credential_id, credential) VALUES ($1, $2, $3, $4)",
+		humanID, name, credentialID, credential)
+	return err
+}
+
+// RecordPasskeyUse stores the updated credential record (sign count) after a
+// login. It returns false, storing nothing, when the passkey is flagged.
+func (q *Queries) RecordPasskeyUse(ctx context.Context, credentialID, credential []byte) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		"UPDATE passkeys SET credential = $2, last_used_at = NOW() WHERE credential_id = $1 AND flagged_at IS NULL",
+		credentialID, credential)
+	return tag.RowsAffected() == 1, err
+}
+
+// FlagPasskey marks a passkey as possibly cloned, so it no longer signs in
+func (q *Queries) FlagPasskey(ctx context.Context, credentialID []byte) error {
+	_, err := q.pool.Exec(ctx,
+		"UPDATE passkeys SET flagged_at = COALESCE(flagged_at, NOW()) WHERE credential_id = $1",
+		credentialID)
+	return err
+}
+
+// DeletePasskey removes one of a human's passkeys. A passkey-only account
+// keeps its last passkey: the delete matches nothing and returns false.
+func (q *Queries) DeletePasskey(ctx context.Context, id, humanID int) (bool, error) {
+	tag, err := q.pool.Exec(ctx, `
+		DELETE FROM passkeys p
+		WHERE p.id = $1 AND p.human_id = $2
+		  AND (
+		    (SELECT password_hash FROM humans WHERE id = $2) <> ''
+		    OR (SELECT COUNT(*) FROM passkeys WHERE human_id = $2) > 1
+		  )`,
+		id, humanID)
+	return tag.RowsAffected() == 1, err
+}
+
+// RemovePassword makes an account passkey-only. Only allowed while the human has a passkey.
+func (q *Queries) RemovePassword(ctx context.Context, humanID int) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		"UPDATE humans SET password_hash = '' WHERE id = $1 AND EXISTS (SELECT 1 FROM passkeys WHERE human_id = $1)",
+		humanID)
+	return tag.RowsAffected() == 1, err
+}
+
+// SaveWebAuthnCeremony stores challenge state for an in-flight registration or login
+func (q *Queries) SaveWebAuthnCeremony(ctx context.Context, idHash string, humanID *int, data []byte, expiresAt time.Time) error {
+	_, err := q.pool.Exec(ctx,
+		"INSERT INTO webauthn_ceremonies (id, human_id, data, expires_at) VALUES ($1, $2, $3, $4)",
+		idHash, humanID, data, expiresAt)
+	return err
+}
+
+// TakeWebAuthnCeremony removes and returns unexpired ceremony state, so each challenge is answered once
+func (q *Queries) TakeWebAuthnCeremony(ctx context.Context, idHash string) (*int, []byte, error) {
+	var humanID *int
+	var data []byte
+	err := q.pool.QueryRow(ctx,
+		"DELETE FROM webauthn_ceremonies WHERE id = $1 AND expires_at > NOW() RETURNING human_id, data",
+		idHash).Scan(&humanID, &data)
+	return humanID, data, err
+}
+
+// DeleteExpiredWebAuthnCeremonies removes abandoned ceremonies
+func (q *Queries) DeleteExpiredWebAuthnCeremonies(ctx context.Context) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM webauthn_ceremonies WHERE expires_at <= NOW()")
+	return err
+}
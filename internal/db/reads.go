@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// ThreadReadState is one reader's view of a thread: how far they have read
+// and whether they are watching it.
+type ThreadReadState struct {
+	ThreadID          int
+	Tracked           bool // reader has opened the thread (or posted in it) before
+	LastReadPostID    int
+	UnreadCount       int
+	FirstUnreadPostID int // 0 if nothing unread
+	Watching          bool
+}
+
+// UnreadThread is a thread with posts the reader has not seen yet
+type UnreadThread struct {
+	ThreadID          int
+	Title             string
+	SpaceID           int
+	SpaceName         string
+	LastPostAt        time.Time
+	UnreadCount       int
+	FirstUnreadPostID int
+}
+
+// MarkThreadRead advances the reader's marker to the newest post in the thread
+// and clears their notifications for it. readerType is 'human' or 'agent'.
+func (q *Queries) MarkThreadRead(ctx context.Context, readerType string, readerID, threadID int) error {
+	_, err := q.pool.Exec(ctx,
+		`INSERT INTO thread_reads (reader_type, reader_id, thread_id, last_read_post_id)
+		 VALUES ($1, $2, $3, (SELECT COALESCE(MAX(id), 0) FROM posts WHERE thread_id = $3))
+		 ON CONFLICT (reader_type, reader_id, thread_id)
+		 DO UPDATE SET last_read_post_id = GREATEST(thread_reads.last_read_post_id, EXCLUDED.last_read_post_id), read_at = NOW()`,
+		readerType, readerID, threadID)
+	if err != nil {
+		return err
+	}
+	_, err = q.pool.Exec(ctx,
+		`UPDATE notifications SET read_at = NOW()
+		 WHERE recipient_type = $1 AND recipient_id = $2 AND thread_id = $3 AND read_at IS NULL`,
+		readerType, readerID, threadID)
+	return err
+}
+
+// GetThreadReadState returns the reader's read marker and watch state for one thread
+func (q *Queries) GetThreadReadState(ctx context.Context, readerType string, readerID, threadID int) (ThreadReadState, error) {
+	var s ThreadReadState
+	var lastRead *int
+	var firstUnread *int
+	err := q.pool.QueryRow(ctx, `
+		SELECT t.id, r.last_read_post_id, COALESCE(w.watching, FALSE),
+		       COUNT(p.id) FILTER (WHERE p.id > COALESCE(r.last_read_post_id, 0))::int,
+		       MIN(p.id) FILTER (WHERE p.id > COALESCE(r.last_read_post_id, 0))
+		FROM threads t
+		LEFT JOIN thread_reads r ON r.thread_id = t.id AND r.reader_type = $1 AND r.reader_id = $2
+		LEFT JOIN thread_watches w ON w.thread_id = t.id AND w.watcher_type = $1 AND w.watcher_id = $2
+		LEFT JOIN posts p ON p.thread_id = t.id
+		WHERE t.id = $3
+		GROUP BY t.id, r.last_read_post_id, w.watching
+	`, readerType, readerID, threadID).Scan(&s.ThreadID, &lastRead, &s.Watching, &s.UnreadCount, &firstUnread)
+	if lastRead != nil {
+		s.Tracked = true
+		s.LastReadPostID = *lastRead
+	}
+	if firstUnread != nil {
+		s.FirstUnreadPostID = *firstUnread
+	}
+	return s, err
+}
+
+// ListThreadReadStates returns the reader's read state for every thread in a space, keyed by thread id
+func (q *Queries) ListThreadReadStates(ctx context.Context, readerType string, readerID, spaceID int) (map[int]ThreadReadState, error) {
+	rows, err := q.pool.Query(ctx, `
+		SELECT t.id, r.last_read_post_id, COALESCE(w.watching, FALSE),
+		       COUNT(p.id) FILTER (WHERE p.id > COALESCE(r.last_read_post_id, 0))::int,
+		       MIN(p.id) FILTER (WHERE p.id > COALESCE(r.last_read_post_id, 0))
+		FROM threads t
+		LEFT JOIN thread_reads r ON r.thread_id = t.id AND r.reader_type = $1 AND r.reader_id = $2
+		LEFT JOIN thread_watches w ON w.thread_id = t.id AND w.watcher_type = $1 AND w.watcher_id = $2
+		LEFT JOIN posts p ON p.thread_id = t.id
+		WHERE t.space_id = $3
+		GROUP BY t.id, r.last_read_post_id, w.watching
+	`, readerType, readerID, spaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[int]ThreadReadState)
+	for rows.Next() {
+		var s ThreadReadState
+		var lastRead *int
+		var firstUnread *int
+		if err := rows.Scan(&s.ThreadID, &lastRead, &s.Watching, &s.UnreadCount, &firstUnread); err != nil {
+			return nil, err
+		}
+		if lastRead != nil {
+			s.Tracked = true
+			s.LastReadPostID = *lastRead
+		}
+		if firstUnread != nil {
+			s.FirstUnreadPostID = *firstUnread
+		}
+		states[s.ThreadID] = s
+	}
+	return states, rows.Err()
+}
+
+// SetThreadWatch explicitly watches (true) or unwatches (false) a thread
+func (q *Queries) SetThreadWatch(ctx context.Context, watcherType string, watcherID, threadID int, watching bool) error {
+	_, err := q.pool.Exec(ctx,
+		`INSERT INTO thread_watches (watcher_type, watcher_id, thread_id, watching)
+		 VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (watcher_type, watcher_id, thread_id) DO UPDATE SET watching = EXCLUDED.watching`,
+		watcherType, watcherID, threadID, watching)
+	return err
+}
+
+// ListUnreadThreads returns threads the reader watches or has posted in (and has
+// not explicitly unwatched) that contain posts newer than their read marker,
+// most recently active first
+func (q *Queries) ListUnreadThreads(ctx context.Context, readerType string, readerID int) ([]UnreadThread, error) {
+	rows, err := q.pool.Query(ctx, `
+		SELECT t.id, t.title, s.id, s.name, t.last_post_at,
+		       COUNT(p.id)::int AS unread_count,
+		       MIN(p.id) AS first_unread
+		FROM threads t
+		JOIN spaces s ON s.id = t.space_id
+		LEFT JOIN thread_reads r ON r.thread_id = t.id AND r.reader_type = $1 AND r.reader_id = $2
+		LEFT JOIN thread_watches w ON w.thread_id = t.id AND w.watcher_type = $1 AND w.watcher_id = $2
+		JOIN posts p ON p.thread_id = t.id AND p.id > COALESCE(r.last_read_post_id, 0)
+		WHERE w.watching IS TRUE
+		   OR (w.watching IS NULL AND EXISTS (
+		         SELECT 1 FROM posts mine
+		         WHERE mine.thread_id = t.id AND mine.author_type = $1 AND mine.author_id = $2))
+		GROUP BY t.id, s.id
+		ORDER BY t.last_post_at DESC
+		LIMIT 100
+	`, readerType, readerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var threads []UnreadThread
+	for rows.Next() {
+		var t UnreadThread
+		if err := rows.Scan(&t.ThreadID, &t.Title, &t.SpaceID, &t.SpaceName, &t.LastPostAt,
+			&t.UnreadCount, &t.FirstUnreadPostID); err != nil {
+			return nil, err
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
+// CountUnreadNotifications returns the number of unread notifications for a recipient
+func (q *Queries) CountUnreadNotifications(ctx context.Context, recipientType string, recipientID int) (int, error) {
+	var n int
+	err := q.pool.QueryRow(ctx,
+		"SELECT COUNT(*)::int FROM notifications WHERE recipient_type = $1 AND recipient_id = $2 AND read_at IS NULL",
+		recipientType, recipientID).Scan(&n)
+	return n, err
+}
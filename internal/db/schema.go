@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expectedColumns lists, per table, the columns the queries in this package
+// read or write. Keep it in step with internal/db/migrations; the migrations
+// tests prepare every query against the migrated schema and catch what this
+// list misses.
+var expectedColumns = map[string][]string{
+	"humans":                    {"id", "twitter_handle", "password_hash", "jurisdiction", "jurisdiction_source", "jurisdiction_declared", "jurisdiction_verified", "jurisdiction_verified_by", "jurisdiction_override", "tribe_name", "bio", "location", "language", "email", "email_verified_at", "totp_secret", "totp_enabled_at", "totp_last_step", "webauthn_id", "invite_quota", "suspended_at", "suspension_reason", "created_at"},
+	"invitations":               {"id", "code", "twitter_handle", "created_by", "created_at", "used_at", "used_by", "expires_at", "revoked_at", "reissued_from", "issuer_invitation_id"},
+	"agents":                    {"id", "owner_id", "name", "substrate", "model", "memory_mode", "bio", "api_key_hash", "created_at", "frozen_at"},
+	"sessions":                  {"id", "human_id", "created_at", "expires_at", "last_seen_at", "user_agent", "ip_prefix", "remember"},
+	"spaces":                    {"id", "name", "description", "allowed_jurisdictions", "archived_at", "created_at"},
+	"threads":                   {"id", "space_id", "title", "author_type", "author_id", "created_at", "last_post_at", "lang", "search_vector"},
+	"posts":                     {"id", "thread_id", "author_type", "author_id", "content", "created_at", "lang", "search_vector"},
+	"thread_reads":              {"reader_type", "reader_id", "thread_id", "last_read_post_id", "read_at"},
+	"thread_watches":            {"watcher_type", "watcher_id", "thread_id", "watching", "created_at"},
+	"notifications":             {"id", "recipient_type", "recipient_id", "kind", "thread_id", "post_id", "created_at", "read_at"},
+	"export_jobs":               {"id", "human_id", "status", "file_path", "error", "created_at", "completed_at", "expires_at", "downloaded_at"},
+	"deletion_requests":         {"id", "human_id", "mode", "requested_at", "scheduled_for", "cancelled_at", "completed_at"},
+	"transparency_events":       {"id", "kind", "detail", "created_at"},
+	"email_tokens":              {"id", "human_id", "purpose", "token_hash", "email", "created_at", "expires_at", "used_at"},
+	"mail_outbox":               {"id", "to_address", "subject", "body", "created_at", "attempts", "next_attempt_at", "last_error", "sent_at"},
+	"recovery_codes":            {"id", "human_id", "code_hash", "created_at", "used_at"},
+	"pending_logins":            {"id", "human_id", "attempts", "remember", "created_at", "expires_at"},
+	"second_factor_attempts":    {"human_id", "attempts", "window_start"},
+	"passkeys":                  {"id", "human_id", "name", "credential_id", "credential", "created_at", "last_used_at", "flagged_at"},
+	"webauthn_ceremonies":       {"id", "human_id", "data", "expires_at"},
+	"security_events":           {"id", "kind", "human_id", "handle", "ip_prefix", "detail", "created_at"},
+	"handle_verifications":      {"human_id", "provider", "subject", "handle", "verified_at"},
+	"handle_verification_flows": {"id", "human_id", "code_verifier", "expires_at"},
+	"audit_log":                 {"id", "actor", "action", "target", "detail", "created_at"},
+	"waitlist_entries":          {"id", "twitter_handle", "source", "consented_at", "created_at", "invitation_id", "invited_at"},
+}
+
+// CheckSchema compares the database with expectedColumns and describes every
+// missing table or column. It returns nil when the queries will find
+// everything they need.
+func (q *Queries) CheckSchema(ctx context.Context) error {
+	rows, err := q.pool.Query(ctx,
+		`SELECT table_name, column_name FROM information_schema.columns
+		 WHERE table_schema = current_schema()`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	have := map[string]map[string]bool{}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return err
+		}
+		if have[table] == nil {
+			have[table] = map[string]bool{}
+		}
+		have[table][column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for table, columns := range expectedColumns {
+		if have[table] == nil {
+			missing = append(missing, "table "+table)
+			continue
+		}
+		for _, c := range columns {
+			if !have[table][c] {
+				missing = append(missing, "column "+table+"."+c)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("schema is missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// UserTables lists the tables in the current schema, for checking that a
+// database is empty
+func (q *Queries) UserTables(ctx context.Context) ([]string, error) {
+	rows, err := q.pool.Query(ctx,
+		`SELECT table_name FROM information_schema.tables
+		 WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		 ORDER BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// SearchLanguages are the Postgres text search configurations members can
+// write in: the EU/EEA languages Postgres ships a stemmer for, plus "simple"
+// (no stemming) as the language-neutral default.
+var SearchLanguages = []string{
+	"simple",
+	"danish",
+	"dutch",
+	"english",
+	"finnish",
+	"french",
+	"german",
+	"greek",
+	"hungarian",
+	"irish",
+	"italian",
+	"lithuanian",
+	"norwegian",
+	"portuguese",
+	"romanian",
+	"spanish",
+	"swedish",
+}
+
+// IsSearchLanguage reports whether lang is one of SearchLanguages
+func IsSearchLanguage(lang string) bool {
+	for _, l := range SearchLanguages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// authorLanguageSQL returns an SQL expression for the text search language of
+// an author: the human's own language, or the owning human's for an agent.
+// typeParam and idParam are the placeholders holding author_type and author_id.
+func authorLanguageSQL(typeParam, idParam string) string {
+	return `COALESCE((SELECT language FROM humans WHERE id = CASE WHEN ` + typeParam + `::text = 'human' THEN ` + idParam + `::int
+	        ELSE (SELECT owner_id FROM agents WHERE id = ` + idParam + `::int) END), 'simple')::regconfig`
+}
+
+// SearchFilter narrows a content search. Zero values mean "no filter".
+type SearchFilter struct {
+	SpaceID     int
+	AuthorType  string // "human" or "agent"
+	TribeHandle string // twitter_handle of the tribe human (matches the human and their agents; never the tombstone)
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Offset      int
+}
+
+// SearchResult is one hit: either a post whose content matched, or a thread whose title matched
+type SearchResult struct {
+	Kind        string // "post" or "thread"
+	PostID      int    // matching post, or the thread's opening post for title hits
+	ThreadID    int
+	ThreadTitle string
+	SpaceID     int
+	SpaceName   string
+	AuthorType  string
+	AuthorName  string // handle or agent name
+	AuthorTribe string // agent only: owner's twitter_handle
+	Snippet     string // highlighted excerpt; matches wrapped in SnippetStart/SnippetStop
+	Rank        float32
+	CreatedAt   time.Time
+}
+
+// Snippet highlight delimiters. Control characters cannot collide with the
+// markup the caller wraps matches in, and are stripped before rendering.
+const (
+	SnippetStart = "\x02"
+	SnippetStop  = "\x03"
+)
+
+// SearchContent runs a full-text search over post content and thread titles.
+// query uses websearch syntax: words, "quoted phrases", -exclusions and OR.
+// Each post is matched with the text search configuration it was indexed
+// with, so stemming follows the author's language. Returns at most f.Limit
+// results ordered by rank, then recency.
+func (q *Queries) SearchContent(ctx context.Context, query string, f SearchFilter) ([]SearchResult, error) {
+	if f.Limit <= 0 {
+		f.Limit = 20
+	}
+	rows, err := q.pool.Query(ctx, `
+		WITH q AS (
+			SELECT l.name::regconfig AS cfg, websearch_to_tsquery(l.name::regconfig, $1) AS tsq
+			FROM unnest($2::text[]) AS l(name)
+		),
+		hl AS (
+			SELECT 'StartSel=' || chr(2) || ', StopSel=' || chr(3) ||
+			       ', MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" … "' AS opts
+		),
+		hits AS (
+			SELECT 'post' AS kind, p.id AS post_id, t.id AS thread_id, t.title, s.id AS space_id, s.name AS space_name,
+			       p.author_type, COALESCE(h.twitter_handle, a.name) AS author_name,
+			       CASE WHEN p.author_type = 'agent' THEN owner.twitter_handle ELSE '' END AS author_tribe,
+			       ts_headline(p.lang, p.content, q.tsq, hl.opts) AS snippet,
+			       ts_rank(p.search_vector, q.tsq) AS rank,
+			       p.created_at
+			FROM posts p
+			JOIN q ON q.cfg = p.lang AND p.search_vector @@ q.tsq
+			CROSS JOIN hl
+			JOIN threads t ON t.id = p.thread_id
+			JOIN spaces s ON s.id = t.space_id
+			LEFT JOIN humans h ON h.id = p.author_id AND p.author_type = 'human'
+			LEFT JOIN agents a ON a.id = p.author_id AND p.author_type = 'agent'
+			LEFT JOIN humans owner ON owner.id = a.owner_id
+			WHERE ($3 = 0 OR t.space_id = $3)
+			  AND ($4 = '' OR p.author_type = $4)
+			  AND ($5 = '' OR ($5 <> $10 AND (h.twitter_handle = $5 OR owner.twitter_handle = $5)))
+			  AND ($6::timestamptz IS NULL OR p.created_at >= $6)
+			  AND ($7::timestamptz IS NULL OR p.created_at < $7)
+
+			UNION ALL
+
+			SELECT 'thread', (SELECT MIN(fp.id) FROM posts fp WHERE fp.thread_id = t.id), t.id, t.title, s.id, s.name,
+			       t.author_type, COALESCE(h.twitter_handle, a.name),
+			       CASE WHEN t.author_type = 'agent' THEN owner.twitter_handle ELSE '' END,
+			       ts_headline(t.lang, t.title, q.tsq, hl.opts),
+			       ts_rank(t.search_vector, q.tsq) * 2,
+			       t.created_at
+			FROM threads t
+			JOIN q ON q.cfg = t.lang AND t.search_vector @@ q.tsq
+			CROSS JOIN hl
+			JOIN spaces s ON s.id = t.space_id
+			LEFT JOIN humans h ON h.id = t.author_id AND t.author_type = 'human'
+			LEFT JOIN agents a ON a.id = t.author_id AND t.author_type = 'agent'
+			LEFT JOIN humans owner ON owner.id = a.owner_id
+			WHERE ($3 = 0 OR t.space_id = $3)
+			  AND ($4 = '' OR t.author_type = $4)
+			  AND ($5 = '' OR ($5 <> $10 AND (h.twitter_handle = $5 OR owner.twitter_handle = $5)))
+			  AND ($6::timestamptz IS NULL OR t.created_at >= $6)
+			  AND ($7::timestamptz IS NULL OR t.created_at < $7)
+		)
+		SELECT kind, COALESCE(post_id, 0), thread_id, title, space_id, space_name,
+		       author_type, COALESCE(author_name, ''), COALESCE(author_tribe, ''), snippet, rank, created_at
+		FROM hits
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $8 OFFSET $9
+	`, query, SearchLanguages, f.SpaceID, f.AuthorType, f.TribeHandle, f.From, f.To, f.Limit, f.Offset, TombstoneHumanHandle)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.Kind, &r.PostID, &r.ThreadID, &r.ThreadTitle, &r.SpaceID, &r.SpaceName,
+			&r.AuthorType, &r.AuthorName, &r.AuthorTribe, &r.Snippet, &r.Rank, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,291 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Security event kinds
+const (
+	SecurityLoginFailed    = "login_failed"
+	SecurityLoginSucceeded = "login_succeeded"
+	SecurityLoginLocked    = "login_locked"
+	SecurityPasswordReset  = "password_reset"
+	SecurityResetRequested = "reset_requested"
+
+	SecuritySecondFactorFailed = "second_factor_failed"
+	SecuritySecondFactorLocked = "second_factor_locked"
+
+	SecurityPasskeyCloned = "passkey_cloned"
+)
+
+// SecurityEvent is one entry of the security event log
+type SecurityEvent struct {
+	ID        int64          `json:"id"`
+	Kind      string         `json:"kind"`
+	HumanID   *int           `json:"human_id"`
+	Handle    string         `json:"handle"`
+	IPPrefix  string         `json:"ip_prefix"`
+	Detail    map[string]any `json:"detail"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// SecurityEventFilter narrows ListSecurityEvents; zero values match everything
+type SecurityEventFilter struct {
+	Kind     string
+	Handle   string
+	IPPrefix string
+	HumanID  int
+	Since    time.Time
+	Limit    int
+}
+
+// RecordSecurityEvent appends to the security event log
+func (q *Queries) RecordSecurityEvent(ctx context.Context, kind string, humanID *int, handle, ipPrefix string, detail map[string]any) error {
+	if detail == nil {
+		detail = map[string]any{}
+	}
+	b, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	_, err = q.pool.Exec(ctx,
+		"INSERT INTO security_events (kind, human_id, handle, ip_prefix, detail) VALUES ($1, $2, $3, $4, $5)",
+		kind, humanID, handle, ipPrefix, b)
+	return err
+}
+
+// LoginFailures counts the failed sign-ins held against an attempt
+type LoginFailures struct {
+	Handle  int // for the handle, since the later of the window start and its last success or reset
+	Network int // from the network, within the window
+}
+
+// LoginLockout reports the limits a failed attempt reached first
+type LoginLockout struct {
+	Handle   bool // the handle's limit, not reached before in this window
+	Network  bool // likewise the network's
+	Failures LoginFailures
+}
+
+// lastClearedSQL is when a handle's ($1) failures were last wiped by a
+// successful sign-in or a password reset
+const lastClearedSQL = `COALESCE(
+	(SELECT MAX(created_at) FROM security_events WHERE kind IN ('login_succeeded', 'password_reset') AND handle = $1),
+	'-infinity'::timestamptz)`
+
+// lockLoginAttempts serializes sign-in attempts for the handle and the
+// network until tx ends. The handle lock is always taken first.
+func lockLoginAttempts(ctx context.Context, tx pgx.Tx, handle, ipPrefix string) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('synbridge.login.handle:' || $1))", handle); err != nil {
+		return err
+	}
+	if ipPrefix == "" {
+		return nil
+	}
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('synbridge.login.network:' || $1))", ipPrefix)
+	return err
+}
+
+// countLoginFailures counts the failures for the handle and the network since
+func countLoginFailures(ctx context.Context, tx pgx.Tx, handle, ipPrefix string, since time.Time) (LoginFailures, error) {
+	var f LoginFailures
+	err := tx.QueryRow(ctx,
+		`SELECT COUNT(*) FROM security_events
+		 WHERE kind = 'login_failed' AND handle = $1 AND created_at > GREATEST($2, `+lastClearedSQL+`)`,
+		handle, since).Scan(&f.Handle)
+	if err != nil || ipPrefix == "" {
+		return f, err
+	}
+	err = tx.QueryRow(ctx,
+		"SELECT COUNT(*) FROM security_events WHERE kind = 'login_failed' AND ip_prefix = $1 AND created_at > $2",
+		ipPrefix, since).Scan(&f.Network)
+	return f, err
+}
+
+// BeginLoginAttempt records a sign-in attempt as failed before its password
+// is checked, so that concurrent guesses are counted one after another and
+// cannot all slip in under the limits. It returns the event's id and the
+// failures counted before it. Once the handle has handleLimit failures since
+// since, or the network networkLimit, nothing is recorded and the id is 0.
+func (q *Queries) BeginLoginAttempt(ctx context.Context, handle, ipPrefix string, since time.Time, handleLimit, networkLimit int) (int64, LoginFailures, error) {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return 0, LoginFailures{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockLoginAttempts(ctx, tx, handle, ipPrefix); err != nil {
+		return 0, LoginFailures{}, err
+	}
+	before, err := countLoginFailures(ctx, tx, handle, ipPrefix, since)
+	if err != nil {
+		return 0, before, err
+	}
+	if before.Handle >= handleLimit || before.Network >= networkLimit {
+		return 0, before, tx.Commit(ctx)
+	}
+
+	var id int64
+	if err := tx.QueryRow(ctx,
+		"INSERT INTO security_events (kind, handle, ip_prefix, detail) VALUES ('login_failed', $1, $2, '{}') RETURNING id",
+		handle, ipPrefix).Scan(&id); err != nil {
+		return 0, before, err
+	}
+	return id, before, tx.Commit(ctx)
+}
+
+// SucceedLoginAttempt turns the attempt recorded by BeginLoginAttempt into
+// humanID's successful sign-in, which clears the handle's failures
+func (q *Queries) SucceedLoginAttempt(ctx context.Context, eventID int64, humanID int) error {
+	_, err := q.pool.Exec(ctx,
+		"UPDATE security_events SET kind = 'login_succeeded', human_id = $2 WHERE id = $1 AND kind = 'login_failed'",
+		eventID, humanID)
+	return err
+}
+
+// FailLoginAttempt settles the attempt recorded by BeginLoginAttempt as a
+// failure, attributed to humanID when the handle exists. Counting again under
+// the same locks, the first failure at or past a limit since the limit was
+// last reached records a login_locked event and reports it, so exactly one
+// attempt per lockout does.
+func (q *Queries) FailLoginAttempt(ctx context.Context, eventID int64, humanID *int, handle, ipPrefix string, since time.Time, handleLimit, networkLimit int) (LoginLockout, error) {
+	var out LoginLockout
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return out, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockLoginAttempts(ctx, tx, handle, ipPrefix); err != nil {
+		return out, err
+	}
+	if humanID != nil {
+		if _, err := tx.Exec(ctx, "UPDATE security_events SET human_id = $2 WHERE id = $1", eventID, *humanID); err != nil {
+			return out, err
+		}
+	}
+	if out.Failures, err = countLoginFailures(ctx, tx, handle, ipPrefix, since); err != nil {
+		return out, err
+	}
+
+	if out.Failures.Handle >= handleLimit {
+		var notified bool
+		if err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM security_events
+			 WHERE kind = 'login_locked' AND handle = $1 AND detail->>'scope' = 'handle'
+			   AND created_at > GREATEST($2, `+lastClearedSQL+`))`,
+			handle, since).Scan(&notified); err != nil {
+			return out, err
+		}
+		if !notified {
+			if err := insertLockout(ctx, tx, humanID, handle, ipPrefix, "handle", out.Failures.Handle); err != nil {
+				return out, err
+			}
+			out.Handle = true
+		}
+	}
+	if ipPrefix != "" && out.Failures.Network >= networkLimit {
+		var notified bool
+		if err := tx.QueryRow(ctx,
+			`SELECT EXISTS (SELECT 1 FROM security_events
+			 WHERE kind = 'login_locked' AND ip_prefix = $1 AND detail->>'scope' = 'network' AND created_at > $2)`,
+			ipPrefix, since).Scan(&notified); err != nil {
+			return out, err
+		}
+		if !notified {
+			if err := insertLockout(ctx, tx, nil, "", ipPrefix, "network", out.Failures.Network); err != nil {
+				return out, err
+			}
+			out.Network = true
+		}
+	}
+	return out, tx.Commit(ctx)
+}
+
+// BeginResetRequest records a password reset request for handle from
+// ipPrefix, unless the handle already has handleLimit requests since since or
+// the network networkLimit, in which case nothing is recorded and it returns
+// false. Requests for one handle or network are counted one after another.
+func (q *Queries) BeginResetRequest(ctx context.Context, humanID *int, handle, ipPrefix string, since time.Time, handleLimit, networkLimit int) (bool, error) {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('synbridge.reset.handle:' || $1))", handle); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('synbridge.reset.network:' || $1))", ipPrefix); err != nil {
+		return false, err
+	}
+	var byHandle, byNetwork int
+	if err := tx.QueryRow(ctx,
+		`SELECT
+		   (SELECT COUNT(*) FROM security_events WHERE kind = 'reset_requested' AND handle = $1 AND created_at > $3),
+		   (SELECT COUNT(*) FROM security_events WHERE kind = 'reset_requested' AND ip_prefix = $2 AND $2 <> '' AND created_at > $3)`,
+		handle, ipPrefix, since).Scan(&byHandle, &byNetwork); err != nil {
+		return false, err
+	}
+	if byHandle >= handleLimit || byNetwork >= networkLimit {
+		return false, nil
+	}
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO security_events (kind, human_id, handle, ip_prefix, detail) VALUES ('reset_requested', $1, $2, $3, '{}')",
+		humanID, handle, ipPrefix); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+func insertLockout(ctx context.Context, tx pgx.Tx, humanID *int, handle, ipPrefix, scope string, failures int) error {
+	detail, err := json.Marshal(map[string]any{"scope": scope, "failures": failures})
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		"INSERT INTO security_events (kind, human_id, handle, ip_prefix, detail) VALUES ('login_locked', $1, $2, $3, $4)",
+		humanID, handle, ipPrefix, detail)
+	return err
+}
+
+// ListSecurityEvents returns matching events, newest first
+func (q *Queries) ListSecurityEvents(ctx context.Context, f SecurityEventFilter) ([]SecurityEvent, error) {
+	if f.Limit <= 0 || f.Limit > 1000 {
+		f.Limit = 100
+	}
+	rows, err := q.pool.Query(ctx,
+		`SELECT id, kind, human_id, handle, ip_prefix, detail, created_at FROM security_events
+		 WHERE ($1 = '' OR kind = $1)
+		   AND ($2 = '' OR handle = $2)
+		   AND ($3 = '' OR ip_prefix = $3)
+		   AND ($4 = 0 OR human_id = $4)
+		   AND created_at >= $5
+		 ORDER BY created_at DESC
+		 LIMIT $6`,
+		f.Kind, f.Handle, f.IPPrefix, f.HumanID, f.Since, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []SecurityEvent
+	for rows.Next() {
+		var e SecurityEvent
+		if err := rows.Scan(&e.ID, &e.Kind, &e.HumanID, &e.Handle, &e.IPPrefix, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// PruneSecurityEvents deletes events older than the cutoff
+func (q *Queries) PruneSecurityEvents(ctx context.Context, olderThan time.Time) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM security_events WHERE created_at < $1", olderThan)
+	return err
+}
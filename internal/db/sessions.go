@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Session lifetimes. A session expires after SessionIdleTTL without use
+// (SessionRememberTTL with "remember me"), and never lives past SessionMaxAge.
+const (
+	SessionIdleTTL     = 24 * time.Hour
+	SessionRememberTTL = 30 * 24 * time.Hour
+	SessionMaxAge      = 90 * 24 * time.Hour
+
+	// sessionTouchInterval limits last-seen writes to one per minute per session
+	sessionTouchInterval = time.Minute
+)
+
+const sessionColumns = "id, human_id, created_at, expires_at, last_seen_at, user_agent, ip_prefix, remember"
+
+func scanSession(row pgx.Row) (Session, error) {
+	var s Session
+	err := row.Scan(&s.ID, &s.HumanID, &s.CreatedAt, &s.ExpiresAt, &s.LastSeenAt, &s.UserAgent, &s.IPPrefix, &s.Remember)
+	return s, err
+}
+
+// CreateSession inserts a new session and returns it. Suspended humans get
+// none: pgx.ErrNoRows.
+func (q *Queries) CreateSession(ctx context.Context, idHash string, humanID int, remember bool, userAgent, ipPrefix string) (Session, error) {
+	ttl := SessionIdleTTL
+	if remember {
+		ttl = SessionRememberTTL
+	}
+	return scanSession(q.pool.QueryRow(ctx,
+		`INSERT INTO sessions (id, human_id, expires_at, user_agent, ip_prefix, remember)
+		 SELECT $1::text, $2::int, $3::timestamptz, $4::text, $5::text, $6::bool
+		 FROM humans WHERE id = $2 AND suspended_at IS NULL
+		 RETURNING `+sessionColumns,
+		idHash, humanID, time.Now().UTC().Add(ttl), userAgent, ipPrefix, remember))
+}
+
+// GetSession returns a session by ID if not expired
+func (q *Queries) GetSession(ctx context.Context, idHash string) (Session, error) {
+	return scanSession(q.pool.QueryRow(ctx,
+		"SELECT "+sessionColumns+" FROM sessions WHERE id = $1 AND expires_at > NOW()",
+		idHash))
+}
+
+// TouchSession records use of a live session and slides its expiry forward.
+// Writes at most once per sessionTouchInterval; returns pgx.ErrNoRows when the
+// session was touched recently or has expired.
+func (q *Queries) TouchSession(ctx context.Context, idHash, userAgent, ipPrefix string) (Session, error) {
+	return scanSession(q.pool.QueryRow(ctx,
+		`UPDATE sessions
+		 SET last_seen_at = NOW(), user_agent = $2, ip_prefix = $3,
+		     expires_at = LEAST(NOW() + make_interval(secs => CASE WHEN remember THEN $4 ELSE $5 END),
+		                        created_at + make_interval(secs => $6))
+		 WHERE id = $1 AND expires_at > NOW() AND last_seen_at < NOW() - make_interval(secs => $7)
+		 RETURNING `+sessionColumns,
+		idHash, userAgent, ipPrefix,
+		SessionRememberTTL.Seconds(), SessionIdleTTL.Seconds(), SessionMaxAge.Seconds(), sessionTouchInterval.Seconds()))
+}
+
+// ListSessions returns a human's live sessions, most recently used first
+func (q *Queries) ListSessions(ctx context.Context, humanID int) ([]Session, error) {
+	rows, err := q.pool.Query(ctx,
+		"SELECT "+sessionColumns+" FROM sessions WHERE human_id = $1 AND expires_at > NOW() ORDER BY last_seen_at DESC",
+		humanID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteSession removes a session from the database
+func (q *Queries) DeleteSession(ctx context.Context, idHash string) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM sessions WHERE id = $1", idHash)
+	return err
+}
+
+// RevokeSession removes one of a human's sessions; false if it was not theirs
+func (q *Queries) RevokeSession(ctx context.Context, humanID int, idHash string) (bool, error) {
+	tag, err := q.pool.Exec(ctx, "DELETE FROM sessions WHERE id = $1 AND human_id = $2", idHash, humanID)
+	return tag.RowsAffected() == 1, err
+}
+
+// RevokeOtherSessions signs a human out everywhere except the given session
+func (q *Queries) RevokeOtherSessions(ctx context.Context, humanID int, keepIDHash string) (int64, error) {
+	tag, err := q.pool.Exec(ctx, "DELETE FROM sessions WHERE human_id = $1 AND id <> $2", humanID, keepIDHash)
+	return tag.RowsAffected(), err
+}
+
+// DeleteSessionsByHuman logs a human out everywhere
+func (q *Queries) DeleteSessionsByHuman(ctx context.Context, humanID int) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM sessions WHERE human_id = $1", humanID)
+	return err
+}
+
+// DeleteExpiredSessions removes sessions past their expiry
+func (q *Queries) DeleteExpiredSessions(ctx context.Context) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM sessions WHERE expires_at <= NOW()")
+	return err
+}
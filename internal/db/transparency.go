@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execer is satisfied by both the pool and a transaction
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// TransparencyCount is one line of the annual transparency report
+type TransparencyCount struct {
+	Kind  string
+	Count int
+}
+
+// RecordTransparencyEvent stores an event for the annual transparency report.
+// detail must not contain personal data: counts and categories only.
+func (q *Queries) RecordTransparencyEvent(ctx context.Context, kind string, detail map[string]any) error {
+	return recordTransparencyEvent(ctx, q.pool, kind, detail)
+}
+
+func recordTransparencyEvent(ctx context.Context, e execer, kind string, detail map[string]any) error {
+	if detail == nil {
+		detail = map[string]any{}
+	}
+	b, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	_, err = e.Exec(ctx, "INSERT INTO transparency_events (kind, detail) VALUES ($1, $2)", kind, b)
+	return err
+}
+
+// TransparencyReport counts events per kind for a calendar year (UTC)
+func (q *Queries) TransparencyReport(ctx context.Context, year int) ([]TransparencyCount, error) {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := q.pool.Query(ctx,
+		`SELECT kind, COUNT(*) FROM transparency_events
+		 WHERE created_at >= $1 AND created_at < $2
+		 GROUP BY kind
+		 ORDER BY kind`,
+		from, from.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []TransparencyCount
+	for rows.Next() {
+		var c TransparencyCount
+		if err := rows.Scan(&c.Kind, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
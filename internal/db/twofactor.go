@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// TOTPState is a human's two-factor configuration
+type TOTPState struct {
+	Secret    *string    // set during enrollment and while enabled
+	EnabledAt *time.Time // NULL while enrollment is unconfirmed
+	LastStep  int64
+}
+
+// GetTOTPState returns a human's two-factor configuration
+func (q *Queries) GetTOTPState(ctx context.Context, humanID int) (TOTPState, error) {
+	var s TOTPState
+	err := q.pool.QueryRow(ctx,
+		"SELECT totp_secret, totp_enabled_at, totp_last_step FROM humans WHERE id = $1",
+		humanID).Scan(&s.Secret, &s.EnabledAt, &s.LastStep)
+	return s, err
+}
+
+// SetPendingTOTPSecret starts (or restarts) enrollment; ignored while 2FA is enabled
+func (q *Queries) SetPendingTOTPSecret(ctx context.Context, humanID int, secret string) error {
+	_, err := q.pool.Exec(ctx,
+		"UPDATE humans SET totp_secret = $2 WHERE id = $1 AND totp_enabled_at IS NULL",
+		humanID, secret)
+	return err
+}
+
+// EnableTOTP confirms enrollment at the verified step and stores fresh recovery codes
+func (q *Queries) EnableTOTP(ctx context.Context, humanID int, step int64, codeHashes []string) error {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE humans SET totp_enabled_at = NOW(), totp_last_step = $2 WHERE id = $1 AND totp_secret IS NOT NULL",
+		humanID, step); err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, humanID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DisableTOTP turns two-factor authentication off and drops recovery codes
+func (q *Queries) DisableTOTP(ctx context.Context, humanID int) error {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE humans SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0 WHERE id = $1",
+		humanID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE human_id = $1", humanID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep records an accepted code's step. Returns false if that step
+// (or a later one) was already used, so each code works once.
+func (q *Queries) UseTOTPStep(ctx context.Context, humanID int, step int64) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		"UPDATE humans SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2",
+		humanID, step)
+	return tag.RowsAffected() == 1, err
+}
+
+// UseRecoveryCode consumes an unused recovery code; false if none matches
+func (q *Queries) UseRecoveryCode(ctx context.Context, humanID int, codeHash string) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		`UPDATE recovery_codes SET used_at = NOW()
+		 WHERE id = (SELECT id FROM recovery_codes WHERE human_id = $1 AND code_hash = $2 AND used_at IS NULL LIMIT 1)`,
+		humanID, codeHash)
+	return tag.RowsAffected() == 1, err
+}
+
+// ReplaceRecoveryCodes invalidates all recovery codes and stores new ones
+func (q *Queries) ReplaceRecoveryCodes(ctx context.Context, humanID int, codeHashes []string) error {
+	tx, err := q.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, humanID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, e execer, humanID int, codeHashes []string) error {
+	if _, err := e.Exec(ctx, "DELETE FROM recovery_codes WHERE human_id = $1", humanID); err != nil {
+		return err
+	}
+	_, err := e.Exec(ctx,
+		"INSERT INTO recovery_codes (human_id, code_hash) SELECT $1, unnest($2::text[])",
+		humanID, codeHashes)
+	return err
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a human has left
+func (q *Queries) CountRecoveryCodes(ctx context.Context, humanID int) (int, error) {
+	var n int
+	err := q.pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM recovery_codes WHERE human_id = $1 AND used_at IS NULL",
+		humanID).Scan(&n)
+	return n, err
+}
+
+// CreatePendingLogin records a login that passed the password check
+func (q *Queries) CreatePendingLogin(ctx context.Context, idHash string, humanID int, remember bool, expiresAt time.Time) error {
+	_, err := q.pool.Exec(ctx,
+		"INSERT INTO pending_logins (id, human_id, remember, expires_at) VALUES ($1, $2, $3, $4)",
+		idHash, humanID, remember, expiresAt)
+	return err
+}
+
+// AttemptPendingLogin counts a second-factor attempt and returns the human id
+// and whether they asked to be remembered. Expired logins and those past
+// maxAttempts return pgx.ErrNoRows.
+func (q *Queries) AttemptPendingLogin(ctx context.Context, idHash string, maxAttempts int) (int, bool, error) {
+	var humanID int
+	var remember bool
+	err := q.pool.QueryRow(ctx,
+		`UPDATE pending_logins SET attempts = attempts + 1
+		 WHERE id = $1 AND expires_at > NOW() AND attempts < $2
+		 RETURNING human_id, remember`,
+		idHash, maxAttempts).Scan(&humanID, &remember)
+	return humanID, remember, err
+}
+
+// AttemptSecondFactor counts a second-factor attempt for a human before the
+// code is checked, so parallel guesses cannot overrun the limit. A window
+// that started before windowStart begins afresh. It returns the attempt's
+// number within the window, or pgx.ErrNoRows once maxAttempts are used up.
+func (q *Queries) AttemptSecondFactor(ctx context.Context, humanID, maxAttempts int, windowStart time.Time) (int, error) {
+	var n int
+	err := q.pool.QueryRow(ctx,
+		`INSERT INTO second_factor_attempts (human_id, attempts) VALUES ($1, 1)
+		 ON CONFLICT (human_id) DO UPDATE SET
+		   attempts = CASE WHEN second_factor_attempts.window_start < $3 THEN 1 ELSE second_factor_attempts.attempts + 1 END,
+		   window_start = CASE WHEN second_factor_attempts.window_start < $3 THEN NOW() ELSE second_factor_attempts.window_start END
+		 WHERE second_factor_attempts.window_start < $3 OR second_factor_attempts.attempts < $2
+		 RETURNING attempts`,
+		humanID, maxAttempts, windowStart).Scan(&n)
+	return n, err
+}
+
+// ClearSecondFactorAttempts forgets a human's attempts after a correct code
+func (q *Queries) ClearSecondFactorAttempts(ctx context.Context, humanID int) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM second_factor_attempts WHERE human_id = $1", humanID)
+	return err
+}
+
+// GetPendingLogin returns the human id of a live pending login
+func (q *Queries) GetPendingLogin(ctx context.Context, idHash string) (int, error) {
+	var humanID int
+	err := q.pool.QueryRow(ctx,
+		"SELECT human_id FROM pending_logins WHERE id = $1 AND expires_at > NOW()",
+		idHash).Scan(&humanID)
+	return humanID, err
+}
+
+// DeletePendingLogin removes a pending login once it completes
+func (q *Queries) DeletePendingLogin(ctx context.Context, idHash string) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM pending_logins WHERE id = $1", idHash)
+	return err
+}
+
+// DeleteExpiredPendingLogins removes abandoned pending logins
+func (q *Queries) DeleteExpiredPendingLogins(ctx context.Context) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM pending_logins WHERE expires_at <= NOW()")
+	return err
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// HandleVerification is the receipt of a verified X handle
+type HandleVerification struct {
+	HumanID    int
+	Provider   string
+	Subject    string // the provider's account id
+	Handle     string // as the provider reported it
+	VerifiedAt time.Time
+}
+
+// ErrAccountClaimed means the provider account already verified another human
+var ErrAccountClaimed = errors.New("account already verifies another human")
+
+// GetHandleVerification returns a human's verification receipt, or pgx.ErrNoRows
+func (q *Queries) GetHandleVerification(ctx context.Context, humanID int) (HandleVerification, error) {
+	var v HandleVerification
+	err := q.pool.QueryRow(ctx,
+		"SELECT human_id, provider, subject, handle, verified_at FROM handle_verifications WHERE human_id = $1",
+		humanID).Scan(&v.HumanID, &v.Provider, &v.Subject, &v.Handle, &v.VerifiedAt)
+	return v, err
+}
+
+// SaveHandleVerification records (or renews) a human's verification receipt
+func (q *Queries) SaveHandleVerification(ctx context.Context, humanID int, provider, subject, handle string) error {
+	_, err := q.pool.Exec(ctx,
+		`INSERT INTO handle_verifications (human_id, provider, subject, handle) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (human_id) DO UPDATE SET provider = $2, subject = $3, handle = $4, verified_at = NOW()`,
+		humanID, provider, subject, handle)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrAccountClaimed
+	}
+	return err
+}
+
+// DeleteHandleVerification drops a human's verification receipt
+func (q *Queries) DeleteHandleVerification(ctx context.Context, humanID int) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM handle_verifications WHERE human_id = $1", humanID)
+	return err
+}
+
+// SaveHandleVerificationFlow stores the PKCE verifier of an authorization in flight
+func (q *Queries) SaveHandleVerificationFlow(ctx context.Context, stateHash string, humanID int, codeVerifier string, expiresAt time.Time) error {
+	_, err := q.pool.Exec(ctx,
+		"INSERT INTO handle_verification_flows (id, human_id, code_verifier, expires_at) VALUES ($1, $2, $3, $4)",
+		stateHash, humanID, codeVerifier, expiresAt)
+	return err
+}
+
+// TakeHandleVerificationFlow removes and returns an unexpired flow started by humanID, so each state is used once
+func (q *Queries) TakeHandleVerificationFlow(ctx context.Context, stateHash string, humanID int) (string, error) {
+	var codeVerifier string
+	err := q.pool.QueryRow(ctx,
+		"DELETE FROM handle_verification_flows WHERE id = $1 AND human_id = $2 AND expires_at > NOW() RETURNING code_verifier",
+		stateHash, humanID).Scan(&codeVerifier)
+	return codeVerifier, err
+}
+
+// DeleteExpiredHandleVerificationFlows removes abandoned authorizations
+func (q *Queries) DeleteExpiredHandleVerificationFlows(ctx context.Context) error {
+	_, err := q.pool.Exec(ctx, "DELETE FROM handle_verification_flows WHERE expires_at <= NOW()")
+	return err
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Waitlist states
+const (
+	WaitlistWaiting = "waiting"
+	WaitlistInvited = "invited"
+)
+
+// WaitlistEntry is a handle asking for an invitation
+type WaitlistEntry struct {
+	ID             int
+	TwitterHandle  string
+	Source         string
+	ConsentedAt    time.Time
+	CreatedAt      time.Time
+	InvitationID   *int
+	InvitedAt      *time.Time
+	InvitationCode *string // joined from invitations
+	Registered     bool    // the handle has an account
+}
+
+// waitlistSelect selects the columns scanned by scanWaitlistEntry
+const waitlistSelect = `SELECT w.id, w.twitter_handle, w.source, w.consented_at, w.created_at, w.invitation_id, w.invited_at,
+	i.code, EXISTS (SELECT 1 FROM humans h WHERE lower(h.twitter_handle) = lower(w.twitter_handle))
+	FROM waitlist_entries w
+	LEFT JOIN invitations i ON i.id = w.invitation_id`
+
+func scanWaitlistEntry(row pgx.Row) (WaitlistEntry, error) {
+	var e WaitlistEntry
+	err := row.Scan(&e.ID, &e.TwitterHandle, &e.Source, &e.ConsentedAt, &e.CreatedAt, &e.InvitationID, &e.InvitedAt,
+		&e.InvitationCode, &e.Registered)
+	return e, err
+}
+
+// AddWaitlistEntry puts a handle on the waitlist. Reports false if it was already there.
+func (q *Queries) AddWaitlistEntry(ctx context.Context, twitterHandle, source string, consentedAt, createdAt time.Time) (bool, error) {
+	tag, err := q.pool.Exec(ctx,
+		`INSERT INTO waitlist_entries (twitter_handle, source, consented_at, created_at) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (lower(twitter_handle)) DO NOTHING`,
+		twitterHandle, source, consentedAt, createdAt)
+	return tag.RowsAffected() == 1, err
+}
+
+// ListWaitlist returns entries in the given state ("" for all), oldest first
+func (q *Queries) ListWaitlist(ctx context.Context, status string, limit int) ([]WaitlistEntry, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 200
+	}
+	rows, err := q.pool.Query(ctx,
+		waitlistSelect+`
+		 WHERE ($1 = '' OR ($1 = 'waiting') = (w.invited_at IS NULL))
+		 ORDER BY w.created_at, w.id
+		 LIMIT $2`,
+		status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []WaitlistEntry
+	for rows.Next() {
+		e, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// GetWaitlistEntry returns one entry
+func (q *Queries) GetWaitlistEntry(ctx context.Context, id int) (WaitlistEntry, error) {
+	return scanWaitlistEntry(q.pool.QueryRow(ctx, waitlistSelect+" WHERE w.id = $1", id))
+}
+
+// MarkWaitlistInvited links an entry to the invitation issued for it
+func (q *Queries) MarkWaitlistInvited(ctx context.Context, id, invitationID int) error {
+	_, err := q.pool.Exec(ctx,
+		"UPDATE waitlist_entries SET invitation_id = $2, invited_at = NOW() WHERE id = $1",
+		id, invitationID)
+	return err
+}
+
+// WaitlistStats counts waitlist entries
+type WaitlistStats struct {
+	Total   int `json:"total"`
+	Waiting int `json:"waiting"`
+	Invited int `json:"invited"`
+	Joined  int `json:"joined"` // invited and redeemed the code
+}
+
+// GetWaitlistStats counts waitlist entries by state
+func (q *Queries) GetWaitlistStats(ctx context.Context) (WaitlistStats, error) {
+	var s WaitlistStats
+	err := q.pool.QueryRow(ctx, `SELECT COUNT(*),
+		COUNT(*) FILTER (WHERE w.invited_at IS NULL),
+		COUNT(*) FILTER (WHERE w.invited_at IS NOT NULL),
+		COUNT(*) FILTER (WHERE i.used_at IS NOT NULL)
+		FROM waitlist_entries w LEFT JOIN invitations i ON i.id = w.invitation_id`).Scan(&s.Total, &s.Waiting, &s.Invited, &s.Joined)
+	return s, err
+}
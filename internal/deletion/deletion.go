@@ -0,0 +1,52 @@
+// Package deletion carries out account deletions once their grace period ends.
+// By default content stays, attributed to "Deleted Human" / "Deleted Agent";
+// a purge removes it as well.
+package deletion
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+)
+
+// GracePeriod is how long a deletion request can still be cancelled
+const GracePeriod = 14 * 24 * time.Hour
+
+// Service executes due deletion requests
+type Service struct {
+	Queries *db.Queries
+}
+
+// RunDue executes every deletion whose grace period has passed. Used as a background task.
+func (s *Service) RunDue(ctx context.Context) error {
+	reqs, err := s.Queries.ListDueDeletions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, req := range reqs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		out, err := s.Queries.ExecuteAccountDeletion(ctx, req.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue // cancelled meanwhile
+		}
+		if err != nil {
+			slog.Error("account deletion failed", "deletion_request", req.ID, "err", err)
+			continue
+		}
+		for _, p := range out.ExportFiles {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				slog.Warn("account deletion: removing export", "deletion_request", req.ID, "err", err)
+			}
+		}
+		slog.Info("account deletion done", "deletion_request", req.ID, "mode", out.Mode, "agents", out.Agents, "posts", out.PostsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+// Package export builds GDPR data exports: a versioned zip of JSON and Markdown
+// files holding everything a human and their agents have contributed.
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+)
+
+// FormatVersion is bumped whenever a file or field in the archive changes meaning
+const FormatVersion = 1
+
+// Service builds export archives and signs their download links
+type Service struct {
+	Queries    *db.Queries
+	Dir        string        // where finished archives wait for download
+	SigningKey []byte        // HMAC key for download links
+	LinkTTL    time.Duration // how long a finished archive can be downloaded
+}
+
+// NewService returns a Service that signs download links with signingKey
+func NewService(queries *db.Queries, dir string, signingKey []byte) *Service {
+	return &Service{
+		Queries:    queries,
+		Dir:        dir,
+		SigningKey: signingKey,
+		LinkTTL:    48 * time.Hour,
+	}
+}
+
+// RunPending builds queued export jobs until none are left. Used as a background task.
+func (s *Service) RunPending(ctx context.Context) error {
+	for ctx.Err() == nil {
+		job, err := s.Queries.ClaimExportJob(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path, err := s.buildFile(ctx, job)
+		if err != nil {
+			slog.Error("export build failed", "export_job", job.ID, "err", err)
+			if ferr := s.Queries.FailExportJob(ctx, job.ID, "build failed"); ferr != nil {
+				return ferr
+			}
+			continue
+		}
+		if err := s.Queries.CompleteExportJob(ctx, job.ID, path, time.Now().UTC().Add(s.LinkTTL)); err != nil {
+			os.Remove(path)
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// Cleanup deletes archives that were downloaded or whose link expired. Used as a background task.
+func (s *Service) Cleanup(ctx context.Context) error {
+	paths, err := s.Queries.ListStaleExportFiles(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			slog.Warn("export cleanup", "err", err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) buildFile(ctx context.Context, job db.ExportJob) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.Dir, "export-"+strconv.Itoa(job.ID)+"-"+hex.EncodeToString(suffix)+".zip")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Build(ctx, job.HumanID, f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// SignedURL returns the download path for a finished job, valid until expiresAt
+func (s *Service) SignedURL(jobID int, expiresAt time.Time) string {
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	return "/settings/export/" + strconv.Itoa(jobID) + "/download?expires=" + exp + "&sig=" + s.sign(jobID, exp)
+}
+
+// Verify checks a download link's signature and expiry
+func (s *Service) Verify(jobID int, expires, sig string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(s.sign(jobID, expires)))
+}
+
+func (s *Service) sign(jobID int, expires string) string {
+	mac := hmac.New(sha256.New, s.SigningKey)
+	mac.Write([]byte(strconv.Itoa(jobID) + ":" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Archive file contents (format version 1)
+
+type manifestJSON struct {
+	Format      string            `json:"format"`
+	Version     int               `json:"version"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Human       string            `json:"human"`
+	Files       map[string]string `json:"files"`
+	NotIncluded []string          `json:"not_included"`
+}
+
+type humanJSON struct {
+	ID                     int       `json:"id"`
+	TwitterHandle          string    `json:"twitter_handle"`
+	TribeName              *string   `json:"tribe_name"`
+	Bio                    *string   `json:"bio"`
+	Location               *string   `json:"location"`
+	Jurisdiction           string    `json:"jurisdiction"`
+	JurisdictionSource     string    `json:"jurisdiction_source"`
+	JurisdictionVerifiedBy *string   `json:"jurisdiction_verified_by"`
+	Language               string    `json:"language"`
+	Email                  *string   `json:"email"`
+	CreatedAt              time.Time `json:"created_at"`
+
+	HandleVerification *verificationJSON `json:"handle_verification"`
+}
+
+type verificationJSON struct {
+	Provider   string    `json:"provider"`
+	Subject    string    `json:"subject"`
+	Handle     string    `json:"handle"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+type agentJSON struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Substrate  string     `json:"substrate"`
+	Model      *string    `json:"model"`
+	MemoryMode *string    `json:"memory_mode"`
+	Bio        *string    `json:"bio"`
+	Tribe      string     `json:"tribe"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FrozenAt   *time.Time `json:"frozen_at"`
+}
+
+type postJSON struct {
+	ID          int       `json:"id"`
+	ThreadID    int       `json:"thread_id"`
+	ThreadTitle string    `json:"thread_title"`
+	SpaceID     int       `json:"space_id"`
+	SpaceName   string    `json:"space_name"`
+	AuthorType  string    `json:"author_type"`
+	Author      string    `json:"author"`
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type notificationJSON struct {
+	ID            int        `json:"id"`
+	RecipientType string     `json:"recipient_type"`
+	RecipientID   int        `json:"recipient_id"`
+	Kind          string     `json:"kind"`
+	ThreadID      int        `json:"thread_id"`
+	PostID        int        `json:"post_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReadAt        *time.Time `json:"read_at"`
+}
+
+// Build writes the complete export archive for a human to w
+func (s *Service) Build(ctx context.Context, humanID int, w io.Writer) error {
+	human, err := s.Queries.GetHumanByID(ctx, humanID)
+	if err != nil {
+		return fmt.Errorf("load human: %w", err)
+	}
+	agents, err := s.Queries.ListAgentProfilesByHuman(ctx, humanID)
+	if err != nil {
+		return fmt.Errorf("load agents: %w", err)
+	}
+	posts, err := s.Queries.GetTribePosts(ctx, humanID, 0)
+	if err != nil {
+		return fmt.Errorf("load posts: %w", err)
+	}
+	notifications, err := s.Queries.ListTribeNotifications(ctx, humanID)
+	if err != nil {
+		return fmt.Errorf("load notifications: %w", err)
+	}
+	var verification *verificationJSON
+	if v, err := s.Queries.GetHandleVerification(ctx, humanID); err == nil {
+		verification = &verificationJSON{Provider: v.Provider, Subject: v.Subject, Handle: v.Handle, VerifiedAt: v.VerifiedAt}
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("load handle verification: %w", err)
+	}
+
+	// GetTribePosts is newest first; the archive reads chronologically
+	postList := make([]postJSON, len(posts))
+	for i, p := range posts {
+		postList[len(posts)-1-i] = postJSON{
+			ID:          p.PostID,
+			ThreadID:    p.ThreadID,
+			ThreadTitle: p.ThreadTitle,
+			SpaceID:     p.SpaceID,
+			SpaceName:   p.SpaceName,
+			AuthorType:  p.AuthorType,
+			Author:      p.AuthorName,
+			Content:     p.Content,
+			CreatedAt:   p.CreatedAt,
+		}
+	}
+	agentList := make([]agentJSON, len(agents))
+	for i, a := range agents {
+		agentList[i] = agentJSON{
+			ID:         a.ID,
+			Name:       a.Name,
+			Substrate:  a.Substrate,
+			Model:      a.Model,
+			MemoryMode: a.MemoryMode,
+			Bio:        a.Bio,
+			Tribe:      human.TwitterHandle,
+			CreatedAt:  a.CreatedAt,
+			FrozenAt:   a.FrozenAt,
+		}
+	}
+	notifList := make([]notificationJSON, len(notifications))
+	for i, n := range notifications {
+		notifList[i] = notificationJSON(n)
+	}
+
+	manifest := manifestJSON{
+		Format:      "synbridge-export",
+		Version:     FormatVersion,
+		GeneratedAt: time.Now().UTC(),
+		Human:       human.TwitterHandle,
+		Files: map[string]string{
+			"README.md":          "Description of this archive and every field",
+			"human.json":         "Your profile",
+			"agents.json":        "Every agent in your tribe, including frozen agents",
+			"posts.json":         "Every post by you and your agents, oldest first",
+			"posts.md":           "The same posts as readable Markdown, grouped by thread",
+			"notifications.json": "Notification history for you and your agents",
+		},
+		NotIncluded: []string{
+			"password hash and session data (security data, not content)",
+			"other members' posts, except as thread titles for context",
+		},
+	}
+
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"manifest.json", manifest},
+		{"human.json", humanJSON{
+			ID:                     human.ID,
+			TwitterHandle:          human.TwitterHandle,
+			TribeName:              human.TribeName,
+			Bio:                    human.Bio,
+			Location:               human.Location,
+			Jurisdiction:           human.Jurisdiction,
+			JurisdictionSource:     human.JurisdictionSource,
+			JurisdictionVerifiedBy: human.JurisdictionVerifiedBy,
+			Language:               human.Language,
+			Email:                  human.Email,
+			CreatedAt:              human.CreatedAt,
+
+			HandleVerification: verification,
+		}},
+		{"agents.json", agentList},
+		{"posts.json", postList},
+		{"notifications.json", notifList},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	fw, err := zw.Create("posts.md")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, postsMarkdown(human, postList)); err != nil {
+		return err
+	}
+
+	fw, err = zw.Create("README.md")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(fw, readme); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// postsMarkdown renders posts grouped by thread, threads in order of first contribution
+func postsMarkdown(human db.Human, posts []postJSON) string {
+	var b strings.Builder
+	b.WriteString("# Posts by the Tribe of " + human.TwitterHandle + "\n\n")
+	if len(posts) == 0 {
+		b.WriteString("No posts yet.\n")
+		return b.String()
+	}
+
+	var order []int
+	byThread := make(map[int][]postJSON)
+	for _, p := range posts {
+		if _, seen := byThread[p.ThreadID]; !seen {
+			order = append(order, p.ThreadID)
+		}
+		byThread[p.ThreadID] = append(byThread[p.ThreadID], p)
+	}
+	for _, id := range order {
+		list := byThread[id]
+		b.WriteString("## " + list[0].ThreadTitle + "\n\n")
+		b.WriteString("*Space: " + list[0].SpaceName + " · thread " + strconv.Itoa(id) + "*\n\n")
+		for _, p := range list {
+			who := p.Author
+			if p.AuthorType == "agent" {
+				who += " (agent)"
+			}
+			b.WriteString("### " + who + " · " + p.CreatedAt.UTC().Format("2006-01-02 15:04 UTC") + " · post " + strconv.Itoa(p.ID) + "\n\n")
+			b.WriteString(p.Content + "\n\n")
+		}
+	}
+	return b.String()
+}
+
+const readme = `# SynBridge data export (format version 1)
+
+This archive holds everything you and your agents contributed to SynBridge,
+as required by GDPR Art. 15 and 20. JSON files are UTF-8, timestamps are
+RFC 3339 in UTC, and nullable fields are ` + "`null`" + ` when unset.
+
+## manifest.json
+
+- format: always "synbridge-export"
+- version: format version of this archive (this document describes version 1)
+- generated_at: when the archive was built
+- human: your login handle
+- files: the files in this archive
+- not_included: data deliberately left out
+
+## human.json
+
+Your profile: id, twitter_handle, tribe_name, bio, location, jurisdiction
+(EU-EEA or non-EEA), jurisdiction_source (default, self, verified or admin:
+where the class came from), language (search language for your posts), email
+(verified address or null), created_at, handle_verification (the receipt of
+your X handle verification: provider, subject = your X account id, handle,
+verified_at; null if unverified).
+
+## agents.json
+
+Every agent bound to you, including frozen ones: id, name, substrate, model,
+memory_mode, bio, tribe (your handle), created_at, frozen_at. API keys are
+never exported; only their hashes are stored and those are not included.
+
+## posts.json
+
+Every post written by you or one of your agents, oldest first: id, thread_id,
+thread_title, space_id, space_name, author_type (human or agent), author,
+content (Markdown, exactly as written), created_at.
+
+## posts.md
+
+The same posts rendered as one Markdown document, grouped by thread.
+
+## notifications.json
+
+Notifications sent to you or your agents: id, recipient_type, recipient_id,
+kind ("reply" = a new post in a watched thread), thread_id, post_id,
+created_at, read_at.
+
+## Not yet included
+
+SynBridge does not store mandate scopes or block/mute lists yet. When it does,
+they will be added here under a new format version.
+`
@@ -1,18 +1,23 @@
 package handlers
 
 import (
-	"crypto/rand"
 	"encoding/json"
-	"math/big"
+	"errors"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 
 	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/invites"
 )
 
 type AdminHandler struct {
 	Queries *db.Queries
+	Invites *invites.Service
 }
 
 type InviteRequest struct {
@@ -20,44 +25,13 @@ type InviteRequest struct {
 }
 
 type InviteResponse struct {
-	Code   string `json:"code"`
-	Handle string `json:"handle"`
+	ID        int        `json:"id"`
+	Code      string     `json:"code"`
+	Handle    string     `json:"handle"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Check ADMIN_SECRET from Authorization header or query param
-	adminSecret := os.Getenv("ADMIN_SECRET")
-	if adminSecret == "" {
-		http.Error(w, `{"error":"ADMIN_SECRET not configured"}`, http.StatusInternalServerError)
-		return
-	}
-
-	// Check Authorization header first
-	authHeader := r.Header.Get("Authorization")
-	validAuth := false
-	if authHeader != "" {
-		const prefix = "Bearer "
-		if strings.HasPrefix(authHeader, prefix) {
-			token := strings.TrimPrefix(authHeader, prefix)
-			if token == adminSecret {
-				validAuth = true
-			}
-		}
-	}
-
-	// Check query param if header auth failed
-	if !validAuth {
-		querySecret := r.URL.Query().Get("secret")
-		if querySecret == adminSecret {
-			validAuth = true
-		}
-	}
-
-	if !validAuth {
-		http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
-		return
-	}
-
 	// Parse JSON body
 	var req InviteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -71,38 +45,315 @@ func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate random 12-character alphanumeric code
-	code, err := generateInviteCode(12)
+	inv, err := h.Invites.Create(r.Context(), handle, nil)
 	if err != nil {
-		http.Error(w, `{"error":"Failed to generate code"}`, http.StatusInternalServerError)
-		return
-	}
-
-	// Insert into invitations table
-	if err := h.Queries.CreateInvitation(r.Context(), code, handle); err != nil {
-		http.Error(w, `{"error":"Failed to create invitation"}`, http.StatusInternalServerError)
+		writeInviteError(w, r, err)
 		return
 	}
 
 	// Return JSON response
 	w.Header().Set("Content-Type", "application/json")
 	resp := InviteResponse{
-		Code:   code,
-		Handle: handle,
+		ID:        inv.ID,
+		Code:      inv.Code,
+		Handle:    inv.TwitterHandle,
+		ExpiresAt: inv.ExpiresAt,
 	}
 	json.NewEncoder(w).Encode(resp)
 }
 
-// generateInviteCode generates a random alphanumeric code of given length
-func generateInviteCode(length int) (string, error) {
-	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	result := make([]byte, length)
-	for i := range result {
-		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+// invitationJSON is an invitation as the admin API shows it
+type invitationJSON struct {
+	ID           int        `json:"id"`
+	Code         string     `json:"code"`
+	Handle       string     `json:"handle"`
+	Status       string     `json:"status"`
+	CreatedBy    *string    `json:"created_by"` // inviting member; null for operators
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
+	UsedBy       *string    `json:"used_by"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReissuedFrom *int       `json:"reissued_from"`
+}
+
+func toInvitationJSON(inv db.Invitation, now time.Time) invitationJSON {
+	return invitationJSON{
+		ID:           inv.ID,
+		Code:         inv.Code,
+		Handle:       inv.TwitterHandle,
+		Status:       inv.Status(now),
+		CreatedBy:    inv.CreatedByHandle,
+		CreatedAt:    inv.CreatedAt,
+		ExpiresAt:    inv.ExpiresAt,
+		UsedAt:       inv.UsedAt,
+		UsedBy:       inv.UsedByHandle,
+		RevokedAt:    inv.RevokedAt,
+		ReissuedFrom: inv.ReissuedFrom,
+	}
+}
+
+// writeInviteError maps invites errors to JSON responses
+func writeInviteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, invites.ErrInvalidHandle):
+		http.Error(w, `{"error":"not a valid X handle"}`, http.StatusBadRequest)
+	case errors.Is(err, invites.ErrRegistered):
+		http.Error(w, `{"error":"handle already has an account"}`, http.StatusConflict)
+	case errors.Is(err, invites.ErrPending):
+		http.Error(w, `{"error":"handle already holds an unused invitation; re-issue it instead"}`, http.StatusConflict)
+	case errors.Is(err, invites.ErrNotReissuable):
+		http.Error(w, `{"error":"invitation was already used"}`, http.StatusConflict)
+	case errors.Is(err, db.ErrInviteQuota):
+		http.Error(w, `{"error":"invitation quota used up"}`, http.StatusConflict)
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, `{"error":"invitation not found"}`, http.StatusNotFound)
+	default:
+		serverError(w, r, `{"error":"Failed to create invitation"}`, err)
+	}
+}
+
+// ListInvitationsHTTP handles GET /admin/invitations?status=&created_by=&handle=&limit=
+// — invitations with usage stats. created_by is a member handle or "operator".
+func (h *AdminHandler) ListInvitationsHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := db.InvitationFilter{
+		Status: q.Get("status"),
+		Handle: invites.NormalizeHandle(q.Get("handle")),
+	}
+	switch f.Status {
+	case "", db.InvitationPending, db.InvitationUsed, db.InvitationExpired, db.InvitationRevoked:
+	default:
+		http.Error(w, `{"error":"invalid status"}`, http.StatusBadRequest)
+		return
+	}
+	switch by := invites.NormalizeHandle(q.Get("created_by")); by {
+	case "":
+	case "operator":
+		f.Operator = true
+	default:
+		human, err := h.Queries.GetHumanByHandle(r.Context(), by)
 		if err != nil {
-			return "", err
+			http.Error(w, `{"error":"unknown created_by handle"}`, http.StatusBadRequest)
+			return
 		}
-		result[i] = charset[n.Int64()]
+		f.CreatedBy = human.ID
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, `{"error":"invalid limit"}`, http.StatusBadRequest)
+			return
+		}
+		f.Limit = n
+	}
+
+	list, err := h.Queries.ListInvitations(r.Context(), f)
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	stats, err := h.Queries.GetInvitationStats(r.Context())
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	inviters, err := h.Queries.ListInviterStats(r.Context())
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+
+	now := time.Now()
+	out := make([]invitationJSON, len(list))
+	for i, inv := range list {
+		out[i] = toInvitationJSON(inv, now)
+	}
+	if inviters == nil {
+		inviters = []db.InviterStats{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"invitations": out,
+		"stats":       stats,
+		"inviters":    inviters,
+	})
+}
+
+// RevokeInvitationHTTP handles POST /admin/invitations/{id}/revoke
+func (h *AdminHandler) RevokeInvitationHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+	ok, err := h.Queries.RevokeInvitation(r.Context(), id, nil)
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	if !ok {
+		http.Error(w, `{"error":"no unused, unrevoked invitation with that id"}`, http.StatusNotFound)
+		return
 	}
-	return string(result), nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"id": id, "status": db.InvitationRevoked})
+}
+
+// ReissueInvitationHTTP handles POST /admin/invitations/{id}/reissue — revoke
+// an unused code and issue a fresh one for the same handle and inviter
+func (h *AdminHandler) ReissueInvitationHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"invalid id"}`, http.StatusBadRequest)
+		return
+	}
+	inv, err := h.Invites.Reissue(r.Context(), id)
+	if err != nil {
+		writeInviteError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toInvitationJSON(inv, time.Now()))
+}
+
+// inviteTreeNode is a member and everyone they vouched for
+type inviteTreeNode struct {
+	Handle   string            `json:"handle"`
+	JoinedAt time.Time         `json:"joined_at"`
+	Invited  []*inviteTreeNode `json:"invited"`
+}
+
+// InviteTreeHTTP handles GET /admin/invitations/tree — who vouched for whom.
+// Roots joined with operator-issued codes (or their inviter has since left).
+func (h *AdminHandler) InviteTreeHTTP(w http.ResponseWriter, r *http.Request) {
+	edges, err := h.Queries.ListInviteEdges(r.Context())
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+
+	nodes := make(map[int]*inviteTreeNode, len(edges))
+	for _, e := range edges {
+		nodes[e.InvitationID] = &inviteTreeNode{Handle: e.InviteeHandle, JoinedAt: e.JoinedAt, Invited: []*inviteTreeNode{}}
+	}
+	roots := []*inviteTreeNode{}
+	for _, e := range edges {
+		if e.ParentID != nil {
+			if parent, ok := nodes[*e.ParentID]; ok {
+				parent.Invited = append(parent.Invited, nodes[e.InvitationID])
+				continue
+			}
+		}
+		roots = append(roots, nodes[e.InvitationID])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"roots": roots})
+}
+
+// SetInviteQuotaHTTP handles POST /admin/humans/{handle}/invite-quota — body {"quota": n}
+func (h *AdminHandler) SetInviteQuotaHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Quota *int `json:"quota"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Quota == nil || *req.Quota < 0 || *req.Quota > 1000 {
+		http.Error(w, `{"error":"body must be {\"quota\": 0-1000}"}`, http.StatusBadRequest)
+		return
+	}
+	human, err := h.Queries.GetHumanByHandle(r.Context(), invites.NormalizeHandle(chi.URLParam(r, "handle")))
+	if err != nil {
+		http.Error(w, `{"error":"human not found"}`, http.StatusNotFound)
+		return
+	}
+	if err := h.Queries.SetInviteQuota(r.Context(), human.ID, *req.Quota); err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	left, err := h.Queries.InviteQuotaLeft(r.Context(), human.ID)
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"handle": human.TwitterHandle, "quota": *req.Quota, "left": left})
+}
+
+// TransparencyHTTP handles GET /admin/transparency?year=YYYY — event counts
+// for the annual transparency report (deletions, freezes, incidents)
+func (h *AdminHandler) TransparencyHTTP(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().UTC().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 2000 || y > 9999 {
+			http.Error(w, `{"error":"invalid year"}`, http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	counts, err := h.Queries.TransparencyReport(r.Context(), year)
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	events := map[string]int{}
+	for _, c := range counts {
+		events[c.Kind] = c.Count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"year":   year,
+		"events": events,
+	})
+}
+
+// SecurityEventsHTTP handles GET /admin/security-events — the security event
+// log, filtered by ?kind=&handle=&ip=&human_id=&since=RFC3339&limit=
+func (h *AdminHandler) SecurityEventsHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := db.SecurityEventFilter{
+		Kind:     q.Get("kind"),
+		Handle:   strings.ToLower(strings.TrimPrefix(q.Get("handle"), "@")),
+		IPPrefix: q.Get("ip"),
+	}
+	if v := q.Get("human_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, `{"error":"invalid human_id"}`, http.StatusBadRequest)
+			return
+		}
+		f.HumanID = id
+	}
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, `{"error":"invalid since, want RFC3339"}`, http.StatusBadRequest)
+			return
+		}
+		f.Since = t
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, `{"error":"invalid limit"}`, http.StatusBadRequest)
+			return
+		}
+		f.Limit = n
+	}
+
+	events, err := h.Queries.ListSecurityEvents(r.Context(), f)
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	if events == nil {
+		events = []db.SecurityEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"events": events,
+	})
 }
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/invites"
+)
+
+// waitlistResult is the outcome of inviting one waitlist entry
+type waitlistResult struct {
+	ID     int    `json:"id"`
+	Handle string `json:"handle,omitempty"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// WaitlistHTTP handles GET /admin/waitlist?status=waiting|invited|all — the
+// review queue. ?format=json returns the entries as JSON.
+func (h *AdminHandler) WaitlistHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	status := q.Get("status")
+	switch status {
+	case "":
+		status = db.WaitlistWaiting
+	case db.WaitlistWaiting, db.WaitlistInvited:
+	case "all":
+		status = ""
+	default:
+		http.Error(w, `{"error":"invalid status"}`, http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.Queries.ListWaitlist(r.Context(), status, 1000)
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	stats, err := h.Queries.GetWaitlistStats(r.Context())
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	if q.Get("format") == "json" {
+		type entryJSON struct {
+			ID          int        `json:"id"`
+			Handle      string     `json:"handle"`
+			Source      string     `json:"source"`
+			ConsentedAt time.Time  `json:"consented_at"`
+			CreatedAt   time.Time  `json:"created_at"`
+			InvitedAt   *time.Time `json:"invited_at"`
+			Invitation  *string    `json:"invitation_code"`
+			Registered  bool       `json:"registered"`
+		}
+		out := make([]entryJSON, len(entries))
+		for i, e := range entries {
+			out[i] = entryJSON{e.ID, e.TwitterHandle, e.Source, e.ConsentedAt, e.CreatedAt, e.InvitedAt, e.InvitationCode, e.Registered}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"entries": out, "stats": stats})
+		return
+	}
+
+	render(w, r, "admin-waitlist.html", newWaitlistPage(entries, stats, q))
+}
+
+// waitlistPage is the data of /admin/waitlist
+type waitlistPage struct {
+	page
+	Stats     db.WaitlistStats
+	Tabs      []waitlistTab
+	Message   string
+	InviteURL string
+	Entries   []db.WaitlistEntry
+}
+
+// waitlistTab links to the queue filtered by status
+type waitlistTab struct{ URL, Label string }
+
+// newWaitlistPage lays out the queue; q is the page's query string, which
+// carries the outcome of the last invite run
+func newWaitlistPage(entries []db.WaitlistEntry, stats db.WaitlistStats, q url.Values) waitlistPage {
+	msg := ""
+	if v := q.Get("invited"); v != "" {
+		msg = "Invited " + v + ", skipped " + q.Get("skipped") + "."
+	}
+
+	var tabs []waitlistTab
+	for _, t := range []struct{ status, label string }{{"waiting", "Waiting"}, {"invited", "Invited"}, {"all", "All"}} {
+		tabs = append(tabs, waitlistTab{"/admin/waitlist?status=" + t.status, t.label})
+	}
+
+	return waitlistPage{
+		page:      page{Title: "Waitlist (admin)", Style: "waitlist"},
+		Stats:     stats,
+		Tabs:      tabs,
+		Message:   msg,
+		InviteURL: "/admin/waitlist/invite",
+		Entries:   entries,
+	}
+}
+
+// InviteWaitlistHTTP handles POST /admin/waitlist/invite — issue invitations
+// for the selected entries. Takes form fields id=... (redirects back to the
+// queue) or a JSON body {"ids": [...]} (returns per-entry results).
+func (h *AdminHandler) InviteWaitlistHTTP(w http.ResponseWriter, r *http.Request) {
+	isJSON := strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+
+	var ids []int
+	if isJSON {
+		var body struct {
+			IDs []int `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, `{"error":"Invalid JSON body"}`, http.StatusBadRequest)
+			return
+		}
+		ids = body.IDs
+	} else {
+		r.ParseForm()
+		for _, v := range r.PostForm["id"] {
+			if id, err := strconv.Atoi(v); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) > 500 {
+		http.Error(w, `{"error":"at most 500 entries at a time"}`, http.StatusBadRequest)
+		return
+	}
+
+	results := make([]waitlistResult, 0, len(ids))
+	invited := 0
+	for _, id := range ids {
+		res := waitlistResult{ID: id}
+		inv, err := h.Invites.InviteWaitlisted(r.Context(), id)
+		switch {
+		case err == nil:
+			res.Handle, res.Code = inv.TwitterHandle, inv.Code
+			invited++
+		case errors.Is(err, pgx.ErrNoRows):
+			res.Error = "no such entry"
+		case errors.Is(err, invites.ErrInvited):
+			res.Error = "already invited"
+		case errors.Is(err, invites.ErrRegistered):
+			res.Error = "handle already has an account"
+		case errors.Is(err, invites.ErrPending):
+			res.Error = "handle already holds an unused invitation"
+		case errors.Is(err, invites.ErrInvalidHandle):
+			res.Error = "not a valid X handle"
+		default:
+			serverError(w, r, `{"error":"Database error"}`, err)
+			return
+		}
+		results = append(results, res)
+	}
+
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"invited": invited, "results": results})
+		return
+	}
+	back := "/admin/waitlist?status=invited&invited=" + strconv.Itoa(invited) + "&skipped=" + strconv.Itoa(len(ids)-invited)
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
@@ -9,41 +9,20 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/metrics"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
 )
 
 type APIReadHandler struct {
 	Queries *db.Queries
-}
-
-// authenticate checks the Bearer token and returns the agent, or writes an error and returns false
-func (h *APIReadHandler) authenticate(w http.ResponseWriter, r *http.Request) (db.Agent, bool) {
-	authHeader := r.Header.Get("Authorization")
-	if !strings.HasPrefix(authHeader, "Bearer ") {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(`{"error":"Authorization: Bearer <key> required"}`))
-		return db.Agent{}, false
-	}
-	rawKey := strings.TrimPrefix(authHeader, "Bearer ")
-	keyHash := hashAgentKey(rawKey)
-	agent, err := h.Queries.GetAgentByKeyHash(r.Context(), keyHash)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(`{"error":"Invalid or revoked key"}`))
-		return db.Agent{}, false
-	}
-	return agent, true
+	BaseURL string // public origin the returned URLs point at
 }
 
 // GetSpaces handles GET /api/spaces — list all spaces
 func (h *APIReadHandler) GetSpaces(w http.ResponseWriter, r *http.Request) {
-	if _, ok := h.authenticate(w, r); !ok {
-		return
-	}
-
 	spaces, err := h.Queries.ListSpaces(r.Context())
 	if err != nil {
+		logError(r, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error":"Database error"}`))
@@ -51,19 +30,21 @@ func (h *APIReadHandler) GetSpaces(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type spaceJSON struct {
-		ID          int    `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		ThreadsURL  string `json:"threads_url"`
+		ID                   int      `json:"id"`
+		Name                 string   `json:"name"`
+		Description          string   `json:"description"`
+		AllowedJurisdictions []string `json:"allowed_jurisdictions,omitempty"` // set when posting is limited
+		ThreadsURL           string   `json:"threads_url"`
 	}
 
 	result := make([]spaceJSON, len(spaces))
 	for i, s := range spaces {
 		result[i] = spaceJSON{
-			ID:          s.ID,
-			Name:        s.Name,
-			Description: s.Description,
-			ThreadsURL:  "https://synbridge.eu/api/spaces/" + strconv.Itoa(s.ID) + "/threads",
+			ID:                   s.ID,
+			Name:                 s.Name,
+			Description:          s.Description,
+			AllowedJurisdictions: s.AllowedJurisdictions,
+			ThreadsURL:           h.BaseURL + "/api/spaces/" + strconv.Itoa(s.ID) + "/threads",
 		}
 	}
 
@@ -75,9 +56,7 @@ func (h *APIReadHandler) GetSpaces(w http.ResponseWriter, r *http.Request) {
 
 // GetThreads handles GET /api/spaces/{id}/threads — list threads in a space
 func (h *APIReadHandler) GetThreads(w http.ResponseWriter, r *http.Request) {
-	if _, ok := h.authenticate(w, r); !ok {
-		return
-	}
+	agent := *middleware.PrincipalFrom(r.Context()).Agent
 
 	spaceIDStr := chi.URLParam(r, "id")
 	spaceID, err := strconv.Atoi(spaceIDStr)
@@ -98,32 +77,43 @@ func (h *APIReadHandler) GetThreads(w http.ResponseWriter, r *http.Request) {
 
 	threads, err := h.Queries.ListThreads(r.Context(), spaceID)
 	if err != nil {
+		logError(r, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error":"Database error"}`))
 		return
 	}
 
+	readStates, err := h.Queries.ListThreadReadStates(r.Context(), "agent", agent.ID, spaceID)
+	if err != nil {
+		logError(r, err)
+	}
+
 	type threadJSON struct {
-		ID         int    `json:"id"`
-		Title      string `json:"title"`
-		AuthorType string `json:"author_type"`
-		Author     string `json:"author"`
-		PostCount  int    `json:"post_count"`
-		LastPostAt string `json:"last_post_at"`
-		PostsURL   string `json:"posts_url"`
+		ID          int    `json:"id"`
+		Title       string `json:"title"`
+		AuthorType  string `json:"author_type"`
+		Author      string `json:"author"`
+		PostCount   int    `json:"post_count"`
+		UnreadCount int    `json:"unread_count"`
+		Watching    bool   `json:"watching"`
+		LastPostAt  string `json:"last_post_at"`
+		PostsURL    string `json:"posts_url"`
 	}
 
 	result := make([]threadJSON, len(threads))
 	for i, t := range threads {
+		st := readStates[t.ID]
 		result[i] = threadJSON{
-			ID:         t.ID,
-			Title:      t.Title,
-			AuthorType: t.AuthorType,
-			Author:     t.AuthorHandle,
-			PostCount:  t.PostCount,
-			LastPostAt: t.LastPostAt.Format("2006-01-02T15:04:05Z"),
-			PostsURL:   "https://synbridge.eu/api/threads/" + strconv.Itoa(t.ID),
+			ID:          t.ID,
+			Title:       t.Title,
+			AuthorType:  t.AuthorType,
+			Author:      t.AuthorHandle,
+			PostCount:   t.PostCount,
+			UnreadCount: st.UnreadCount,
+			Watching:    st.Watching,
+			LastPostAt:  t.LastPostAt.Format("2006-01-02T15:04:05Z"),
+			PostsURL:    h.BaseURL + "/api/threads/" + strconv.Itoa(t.ID),
 		}
 	}
 
@@ -136,10 +126,7 @@ func (h *APIReadHandler) GetThreads(w http.ResponseWriter, r *http.Request) {
 
 // CreateThread handles POST /api/threads — create a new thread in a space
 func (h *APIReadHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
-	agent, ok := h.authenticate(w, r)
-	if !ok {
-		return
-	}
+	agent := *middleware.PrincipalFrom(r.Context()).Agent
 
 	var body struct {
 		SpaceID int    `json:"space_id"`
@@ -179,8 +166,28 @@ func (h *APIReadHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	allowed, err := mayPostIn(r.Context(), h.Queries, space, "agent", agent.ID)
+	if err != nil {
+		logError(r, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Database error"}`))
+		return
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		if space.ArchivedAt != nil {
+			w.Write([]byte(`{"error":"This space is archived and read-only"}`))
+			return
+		}
+		w.Write([]byte(`{"error":"This space is limited to other jurisdictions than your tribe's"}`))
+		return
+	}
+
 	threadID, err := h.Queries.CreateThread(r.Context(), body.SpaceID, strings.TrimSpace(body.Title), "agent", agent.ID)
 	if err != nil {
+		logError(r, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error":"Database error"}`))
@@ -189,11 +196,13 @@ func (h *APIReadHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
 
 	postID, err := h.Queries.CreatePost(r.Context(), threadID, "agent", agent.ID, strings.TrimSpace(body.Content))
 	if err != nil {
+		logError(r, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error":"Thread created but failed to create opening post"}`))
 		return
 	}
+	metrics.PostsCreated.WithLabelValues("agent").Inc()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -203,15 +212,13 @@ func (h *APIReadHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
 		"agent":      agent.Name,
 		"tribe":      "Tribe of " + agent.OwnerHandle,
 		"space":      map[string]interface{}{"id": space.ID, "name": space.Name},
-		"thread_url": "https://synbridge.eu/api/threads/" + strconv.Itoa(threadID),
+		"thread_url": h.BaseURL + "/api/threads/" + strconv.Itoa(threadID),
 	})
 }
 
 // GetThread handles GET /api/threads/{id} — get thread with all posts
 func (h *APIReadHandler) GetThread(w http.ResponseWriter, r *http.Request) {
-	if _, ok := h.authenticate(w, r); !ok {
-		return
-	}
+	agent := *middleware.PrincipalFrom(r.Context()).Agent
 
 	threadIDStr := chi.URLParam(r, "id")
 	threadID, err := strconv.Atoi(threadIDStr)
@@ -232,6 +239,7 @@ func (h *APIReadHandler) GetThread(w http.ResponseWriter, r *http.Request) {
 
 	space, err := h.Queries.GetSpace(r.Context(), thread.SpaceID)
 	if err != nil {
+		logError(r, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error":"Database error"}`))
@@ -240,43 +248,215 @@ func (h *APIReadHandler) GetThread(w http.ResponseWriter, r *http.Request) {
 
 	posts, err := h.Queries.ListPosts(r.Context(), threadID)
 	if err != nil {
+		logError(r, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error":"Database error"}`))
 		return
 	}
 
+	// Read state as of before this call, then advance the agent's marker
+	readState, err := h.Queries.GetThreadReadState(r.Context(), "agent", agent.ID, threadID)
+	if err != nil {
+		logError(r, err)
+	}
+	if err := h.Queries.MarkThreadRead(r.Context(), "agent", agent.ID, threadID); err != nil {
+		logError(r, err)
+	}
+
 	type postJSON struct {
-		ID         int    `json:"id"`
-		AuthorType string `json:"author_type"`
-		Author     string `json:"author"`
-		Tribe      string `json:"tribe,omitempty"`
-		Content    string `json:"content"`
-		CreatedAt  string `json:"created_at"`
+		ID           int    `json:"id"`
+		AuthorType   string `json:"author_type"`
+		Author       string `json:"author"`
+		Tribe        string `json:"tribe,omitempty"`
+		Jurisdiction string `json:"jurisdiction,omitempty"` // human posts only: EU-EEA or non-EEA
+		Content      string `json:"content"`
+		CreatedAt    string `json:"created_at"`
 	}
 
 	postList := make([]postJSON, len(posts))
 	for i, p := range posts {
 		postList[i] = postJSON{
-			ID:         p.ID,
-			AuthorType: p.AuthorType,
-			Author:     p.AuthorHandle,
-			Tribe:      p.AuthorTribe,
-			Content:    p.Content,
-			CreatedAt:  p.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			ID:           p.ID,
+			AuthorType:   p.AuthorType,
+			Author:       p.AuthorHandle,
+			Tribe:        p.AuthorTribe,
+			Jurisdiction: p.AuthorJurisdiction,
+			Content:      p.Content,
+			CreatedAt:    p.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"thread": map[string]interface{}{
-			"id":          thread.ID,
-			"title":       thread.Title,
-			"space":       map[string]interface{}{"id": space.ID, "name": space.Name},
-			"post_count":  len(posts),
-			"last_post_at": thread.LastPostAt.Format("2006-01-02T15:04:05Z"),
+			"id":                   thread.ID,
+			"title":                thread.Title,
+			"space":                map[string]interface{}{"id": space.ID, "name": space.Name},
+			"post_count":           len(posts),
+			"last_post_at":         thread.LastPostAt.Format("2006-01-02T15:04:05Z"),
+			"watching":             readState.Watching,
+			"first_unread_post_id": readState.FirstUnreadPostID,
 		},
 		"posts":    postList,
-		"reply_to": "POST https://synbridge.eu/api/post with {\"thread_id\": " + threadIDStr + ", \"content\": \"...\"}",
+		"reply_to": "POST " + h.BaseURL + "/api/post with {\"thread_id\": " + threadIDStr + ", \"content\": \"...\"}",
+	})
+}
+
+// GetUnread handles GET /api/v1/me/unread — threads the agent watches or has
+// posted in that have new posts since the agent last read them
+func (h *APIReadHandler) GetUnread(w http.ResponseWriter, r *http.Request) {
+	agent := *middleware.PrincipalFrom(r.Context()).Agent
+
+	threads, err := h.Queries.ListUnreadThreads(r.Context(), "agent", agent.ID)
+	if err != nil {
+		logError(r, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Database error"}`))
+		return
+	}
+	notifCount, err := h.Queries.CountUnreadNotifications(r.Context(), "agent", agent.ID)
+	if err != nil {
+		logError(r, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Database error"}`))
+		return
+	}
+
+	type unreadJSON struct {
+		ID                int                    `json:"id"`
+		Title             string                 `json:"title"`
+		Space             map[string]interface{} `json:"space"`
+		UnreadCount       int                    `json:"unread_count"`
+		FirstUnreadPostID int                    `json:"first_unread_post_id"`
+		LastPostAt        string                 `json:"last_post_at"`
+		PostsURL          string                 `json:"posts_url"`
+	}
+
+	result := make([]unreadJSON, len(threads))
+	for i, t := range threads {
+		result[i] = unreadJSON{
+			ID:                t.ThreadID,
+			Title:             t.Title,
+			Space:             map[string]interface{}{"id": t.SpaceID, "name": t.SpaceName},
+			UnreadCount:       t.UnreadCount,
+			FirstUnreadPostID: t.FirstUnreadPostID,
+			LastPostAt:        t.LastPostAt.Format("2006-01-02T15:04:05Z"),
+			PostsURL:          h.BaseURL + "/api/threads/" + strconv.Itoa(t.ThreadID),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"threads":              result,
+		"unread_notifications": notifCount,
+	})
+}
+
+// WatchThread handles PUT /api/v1/threads/{id}/watch (watch) and
+// DELETE /api/v1/threads/{id}/watch (unwatch)
+func (h *APIReadHandler) WatchThread(w http.ResponseWriter, r *http.Request) {
+	agent := *middleware.PrincipalFrom(r.Context()).Agent
+
+	threadID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"Invalid thread ID"}`))
+		return
+	}
+	if _, err := h.Queries.GetThread(r.Context(), threadID); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Thread not found"}`))
+		return
+	}
+
+	watching := r.Method != http.MethodDelete
+	if err := h.Queries.SetThreadWatch(r.Context(), "agent", agent.ID, threadID, watching); err != nil {
+		logError(r, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Database error"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":        true,
+		"thread_id": threadID,
+		"watching":  watching,
+	})
+}
+
+// Search handles GET /api/v1/search — full-text search over posts and thread titles.
+// Accepts the same parameters as the /search page: q, space, author, tribe, from, to, page.
+func (h *APIReadHandler) Search(w http.ResponseWriter, r *http.Request) {
+	params, paramErr := parseSearchParams(r)
+	if paramErr == "" && params.Query == "" {
+		paramErr = "q is required"
+	}
+	if paramErr != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": paramErr})
+		return
+	}
+
+	results, err := h.Queries.SearchContent(r.Context(), params.Query, params.Filter)
+	if err != nil {
+		logError(r, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"Database error"}`))
+		return
+	}
+	hasMore := len(results) > searchPageSize
+	if hasMore {
+		results = results[:searchPageSize]
+	}
+
+	type resultJSON struct {
+		Kind        string                 `json:"kind"`
+		PostID      int                    `json:"post_id"`
+		ThreadID    int                    `json:"thread_id"`
+		ThreadTitle string                 `json:"thread_title"`
+		Space       map[string]interface{} `json:"space"`
+		AuthorType  string                 `json:"author_type"`
+		Author      string                 `json:"author"`
+		Tribe       string                 `json:"tribe,omitempty"`
+		Snippet     string                 `json:"snippet"`
+		SnippetHTML string                 `json:"snippet_html"`
+		CreatedAt   string                 `json:"created_at"`
+		PostsURL    string                 `json:"posts_url"`
+	}
+
+	list := make([]resultJSON, len(results))
+	for i, res := range results {
+		plain := strings.NewReplacer(db.SnippetStart, "", db.SnippetStop, "").Replace(res.Snippet)
+		list[i] = resultJSON{
+			Kind:        res.Kind,
+			PostID:      res.PostID,
+			ThreadID:    res.ThreadID,
+			ThreadTitle: res.ThreadTitle,
+			Space:       map[string]interface{}{"id": res.SpaceID, "name": res.SpaceName},
+			AuthorType:  res.AuthorType,
+			Author:      res.AuthorName,
+			Tribe:       res.AuthorTribe,
+			Snippet:     plain,
+			SnippetHTML: highlightSnippet(res.Snippet),
+			CreatedAt:   res.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			PostsURL:    h.BaseURL + "/api/threads/" + strconv.Itoa(res.ThreadID),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":    params.Query,
+		"page":     params.Page,
+		"has_more": hasMore,
+		"results":  list,
 	})
 }
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+// serverError answers with the generic msg and logs the real cause, which may
+// hold SQL, connection details or paths the client must not see
+func serverError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	logError(r, err)
+	http.Error(w, msg, http.StatusInternalServerError)
+}
+
+// logError logs an error that is answered with a generic message
+func logError(r *http.Request, err error) {
+	middleware.Log(r.Context()).Error("request failed", "err", err)
+}
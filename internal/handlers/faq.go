@@ -2,10 +2,8 @@ package handlers
 
 import "net/http"
 
-type FAQHandler struct {
-	StaticDir string
-}
+type FAQHandler struct{}
 
 func (h *FAQHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, h.StaticDir+"/faq.html")
+	renderPublic(w, r, "faq.html")
 }
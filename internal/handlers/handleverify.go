@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+	"github.com/BioAILogic/agentbridge/internal/xverify"
+)
+
+// handleVerificationTTL bounds how long the human has on the provider's consent page
+const handleVerificationTTL = 10 * time.Minute
+
+// HandleVerifyHandler proves a human owns their X handle. Verifier is nil when
+// no identity provider is configured.
+type HandleVerifyHandler struct {
+	Queries  *db.Queries
+	Verifier *xverify.Verifier
+}
+
+// BeginHTTP handles POST /settings/x/verify — send the human to the provider
+func (h *HandleVerifyHandler) BeginHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+	if h.Verifier == nil {
+		http.Redirect(w, r, "/settings?error=x-unavailable#x-handle", http.StatusSeeOther)
+		return
+	}
+
+	state, err := generateSessionID()
+	if err != nil {
+		serverError(w, r, "Error generating token", err)
+		return
+	}
+	codeVerifier := xverify.NewCodeVerifier()
+	if err := h.Queries.SaveHandleVerificationFlow(r.Context(), middleware.TokenHash(state), p.Human.ID, codeVerifier, time.Now().UTC().Add(handleVerificationTTL)); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	http.Redirect(w, r, h.Verifier.AuthURL(state, codeVerifier), http.StatusSeeOther)
+}
+
+// CallbackHTTP handles GET /settings/x/callback?code=&state= — the provider
+// sends the human back here. The flow must have been started by the same
+// account, and the X account that consented must carry the login handle.
+func (h *HandleVerifyHandler) CallbackHTTP(w http.ResponseWriter, r *http.Request) {
+	human := *middleware.PrincipalFrom(r.Context()).Human
+	q := r.URL.Query()
+
+	codeVerifier, err := h.Queries.TakeHandleVerificationFlow(r.Context(), middleware.TokenHash(q.Get("state")), human.ID)
+	if err != nil || h.Verifier == nil {
+		http.Redirect(w, r, "/settings?error=x-link#x-handle", http.StatusSeeOther)
+		return
+	}
+	if q.Get("error") != "" || q.Get("code") == "" {
+		http.Redirect(w, r, "/settings?error=x-denied#x-handle", http.StatusSeeOther)
+		return
+	}
+
+	account, err := h.Verifier.Exchange(r.Context(), q.Get("code"), codeVerifier)
+	if err != nil {
+		middleware.Log(r.Context()).Warn("handle verification failed", "err", err)
+		http.Redirect(w, r, "/settings?error=x-failed#x-handle", http.StatusSeeOther)
+		return
+	}
+	if !strings.EqualFold(account.Username, human.TwitterHandle) {
+		http.Redirect(w, r, "/settings?error=x-mismatch#x-handle", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.Queries.SaveHandleVerification(r.Context(), human.ID, xverify.Provider, account.ID, account.Username); err != nil {
+		if errors.Is(err, db.ErrAccountClaimed) {
+			http.Redirect(w, r, "/settings?error=x-taken#x-handle", http.StatusSeeOther)
+			return
+		}
+		serverError(w, r, "Database error", err)
+		return
+	}
+	http.Redirect(w, r, "/settings?saved=x-verified#x-handle", http.StatusSeeOther)
+}
+
+// RemoveHTTP handles POST /settings/x/remove — drop the verification receipt
+func (h *HandleVerifyHandler) RemoveHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	if err := h.Queries.DeleteHandleVerification(r.Context(), p.Human.ID); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	http.Redirect(w, r, "/settings?saved=x-removed#x-handle", http.StatusSeeOther)
+}
+
+// handleVerificationCard is the verification status on /settings
+type handleVerificationCard struct {
+	Verified  *db.HandleVerification
+	Available bool // an X identity provider is configured
+	Failed    bool
+}
+
+func (h *SettingsHandler) handleVerificationCard(ctx context.Context, human db.Human) handleVerificationCard {
+	card := handleVerificationCard{Available: h.HandleVerification}
+	v, err := h.Queries.GetHandleVerification(ctx, human.ID)
+	switch {
+	case err == nil:
+		card.Verified = &v
+	case !errors.Is(err, pgx.ErrNoRows):
+		card.Failed = true
+	}
+	return card
+}
+
+// handleVerifiedAt is when a tribe's owner proved control of the handle on
+// X, or nil if they have not (or verified a different handle since renamed)
+func handleVerifiedAt(ctx context.Context, q *db.Queries, human db.Human) *time.Time {
+	v, err := q.GetHandleVerification(ctx, human.ID)
+	if err != nil || !strings.EqualFold(v.Handle, human.TwitterHandle) {
+		return nil
+	}
+	return &v.VerifiedAt
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/dbtest"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+	"github.com/BioAILogic/agentbridge/internal/xverify"
+	"github.com/BioAILogic/agentbridge/internal/xverify/fakeidp"
+)
+
+// recordingIdP serves a fake IdP and keeps every token response, so the test
+// can look for the tokens afterwards
+type recordingIdP struct {
+	idp    *fakeidp.Server
+	tokens [][]byte
+}
+
+func (r *recordingIdP) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != fakeidp.TokenPath {
+		r.idp.ServeHTTP(w, req)
+		return
+	}
+	rec := httptest.NewRecorder()
+	r.idp.ServeHTTP(rec, req)
+	body, _ := io.ReadAll(rec.Body)
+	r.tokens = append(r.tokens, body)
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(body)
+}
+
+func TestHandleVerification(t *testing.T) {
+	pool := dbtest.Migrated(t)
+	q := db.New(pool)
+	ctx := context.Background()
+
+	const callback = "https://synbridge.test/settings/x/callback"
+	idp := &recordingIdP{idp: fakeidp.New("synbridge-test", callback)}
+	srv := httptest.NewServer(idp)
+	defer srv.Close()
+	h := &HandleVerifyHandler{Queries: q, Verifier: xverify.New(xverify.Config{
+		ClientID:    "synbridge-test",
+		AuthURL:     srv.URL + fakeidp.AuthorizePath,
+		TokenURL:    srv.URL + fakeidp.TokenPath,
+		UserInfoURL: srv.URL + fakeidp.UserInfoPath,
+		RedirectURL: callback,
+	})}
+
+	signIn := func(handle string) *middleware.Principal {
+		t.Helper()
+		id, err := q.CreateHuman(ctx, handle, "x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		human, err := q.GetHumanByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &middleware.Principal{Kind: middleware.KindHuman, Human: &human, Session: &db.Session{HumanID: id, CreatedAt: time.Now()}}
+	}
+	alice, bob := signIn("alice"), signIn("bob")
+	var states, codes []string
+
+	serve := func(p *middleware.Principal, handler http.HandlerFunc, method, target string) *http.Response {
+		t.Helper()
+		r := httptest.NewRequest(method, target, nil)
+		r = r.WithContext(middleware.WithPrincipal(r.Context(), p))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Result()
+	}
+	// begin starts a flow as p and returns the provider URL it is sent to
+	begin := func(p *middleware.Principal) *url.URL {
+		t.Helper()
+		resp := serve(p, h.BeginHTTP, http.MethodPost, "/settings/x/verify")
+		u, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || u.Host != srv.Listener.Addr().String() {
+			t.Fatalf("begin redirected to %q", resp.Header.Get("Location"))
+		}
+		states = append(states, u.Query().Get("state"))
+		return u
+	}
+	// consent signs in at the provider as username and returns the callback URL
+	consent := func(authURL *url.URL, username string) string {
+		t.Helper()
+		form := authURL.Query()
+		form.Set("username", username)
+		form.Set("decision", "allow")
+		client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+		resp, err := client.PostForm(srv.URL+fakeidp.AuthorizePath, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		back, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || back.Scheme+"://"+back.Host+back.Path != callback {
+			t.Fatalf("provider sent the human to %q", resp.Header.Get("Location"))
+		}
+		codes = append(codes, back.Query().Get("code"))
+		return back.Path + "?" + back.RawQuery
+	}
+	callbackAs := func(p *middleware.Principal, target string) string {
+		t.Helper()
+		return serve(p, h.CallbackHTTP, http.MethodGet, target).Header.Get("Location")
+	}
+
+	t.Run("PKCE challenge on the way out", func(t *testing.T) {
+		q := begin(alice).Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" || q.Get("state") == "" {
+			t.Errorf("authorization URL lacks PKCE or state: %v", q)
+		}
+	})
+
+	t.Run("state from nowhere", func(t *testing.T) {
+		target := consent(begin(alice), "alice")
+		u, _ := url.Parse(target)
+		params := u.Query()
+		params.Set("state", "made-up")
+		if got := callbackAs(alice, u.Path+"?"+params.Encode()); got != "/settings?error=x-link#x-handle" {
+			t.Errorf("redirect = %q", got)
+		}
+	})
+
+	t.Run("state of another human", func(t *testing.T) {
+		target := consent(begin(alice), "alice")
+		if got := callbackAs(bob, target); got != "/settings?error=x-link#x-handle" {
+			t.Errorf("bob finishing alice's flow: redirect = %q", got)
+		}
+		// and the flow is still alice's to finish
+		if got := callbackAs(alice, target); got != "/settings?saved=x-verified#x-handle" {
+			t.Errorf("alice after bob's attempt: redirect = %q", got)
+		}
+	})
+
+	t.Run("replayed callback", func(t *testing.T) {
+		target := consent(begin(alice), "alice")
+		if got := callbackAs(alice, target); got != "/settings?saved=x-verified#x-handle" {
+			t.Fatalf("first use: redirect = %q", got)
+		}
+		if got := callbackAs(alice, target); got != "/settings?error=x-link#x-handle" {
+			t.Errorf("replay: redirect = %q", got)
+		}
+	})
+
+	t.Run("other X account", func(t *testing.T) {
+		target := consent(begin(bob), "mallory")
+		if got := callbackAs(bob, target); got != "/settings?error=x-mismatch#x-handle" {
+			t.Errorf("redirect = %q", got)
+		}
+		if _, err := q.GetHandleVerification(ctx, bob.Human.ID); err == nil {
+			t.Error("mismatched account was recorded")
+		}
+	})
+
+	t.Run("only the receipt is kept", func(t *testing.T) {
+		v, err := q.GetHandleVerification(ctx, alice.Human.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Provider != xverify.Provider || v.Subject != fakeidp.AccountID("alice") || v.Handle != "alice" {
+			t.Errorf("receipt = %+v", v)
+		}
+
+		var flows int
+		if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM handle_verification_flows WHERE human_id = $1", alice.Human.ID).Scan(&flows); err != nil {
+			t.Fatal(err)
+		}
+		if flows != 2 { // the one never sent back and the one with a forged state
+			t.Errorf("%d flows left, want only the two abandoned ones", flows)
+		}
+
+		if len(idp.tokens) == 0 {
+			t.Fatal("no token was issued")
+		}
+		secrets := append(states, codes...)
+		for _, body := range idp.tokens {
+			var token struct {
+				AccessToken string `json:"access_token"`
+			}
+			if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+				t.Fatalf("token response %s: %v", body, err)
+			}
+			secrets = append(secrets, token.AccessToken)
+		}
+		tables, err := q.UserTables(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, table := range tables {
+			for _, secret := range secrets {
+				var n int
+				if err := pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+table+" t WHERE strpos(t::text, $1) > 0", secret).Scan(&n); err != nil {
+					t.Fatal(err)
+				}
+				if n > 0 {
+					t.Errorf("%s holds a state, code or token from the flow", table)
+				}
+			}
+		}
+	})
+}
@@ -3,12 +3,17 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/migrations"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
 )
 
 type HealthHandler struct {
-	Queries *db.Queries
+	Queries    *db.Queries
+	Migrations *migrations.Runner
+	Draining   atomic.Bool // set on shutdown; /readyz then fails
 }
 
 func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -22,6 +27,7 @@ func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Log the real error internally, return generic message to client
 		// (db errors may contain connection strings or internal paths)
+		logError(r, err)
 		w.WriteHeader(http.StatusServiceUnavailable)
 		json.NewEncoder(w).Encode(map[string]string{
 			"status": "error",
@@ -36,3 +42,43 @@ func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		"db":     "ok",
 	})
 }
+
+// LivezHTTP handles GET /livez — the process is up and serving. It does not
+// touch the database, so a database outage does not get the server restarted.
+func (h *HealthHandler) LivezHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
+// ReadyzHTTP handles GET /readyz — the server should get traffic: it is not
+// shutting down, the database answers and no schema migration is pending
+func (h *HealthHandler) ReadyzHTTP(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"shutdown": "ok", "db": "ok", "migrations": "ok"}
+	ready := true
+
+	if h.Draining.Load() {
+		checks["shutdown"] = "draining"
+		ready = false
+	}
+	if _, err := h.Queries.GetHealth(r.Context()); err != nil {
+		middleware.Log(r.Context()).Error("readyz: database", "err", err)
+		checks["db"] = "unavailable"
+		checks["migrations"] = "unknown"
+		ready = false
+	} else if pending, err := h.Migrations.Pending(r.Context()); err != nil {
+		middleware.Log(r.Context()).Error("readyz: migrations", "err", err)
+		checks["migrations"] = "unknown"
+		ready = false
+	} else if pending > 0 {
+		checks["migrations"] = "pending"
+		ready = false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	status := map[string]any{"status": "ok", "checks": checks}
+	if !ready {
+		status["status"] = "unavailable"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
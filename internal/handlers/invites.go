@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/invites"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+// InvitesHandler lets members invite peers within their quota
+type InvitesHandler struct {
+	Queries *db.Queries
+	Invites *invites.Service
+	BaseURL string // public origin used in invitation links
+}
+
+// GetHTTP handles GET /settings/invites — the member's invitations and quota
+func (h *InvitesHandler) GetHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	list, err := h.Queries.ListInvitations(r.Context(), db.InvitationFilter{CreatedBy: p.Human.ID, Limit: 200})
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	left, err := h.Queries.InviteQuotaLeft(r.Context(), p.Human.ID)
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	render(w, r, "invites.html", h.newInvitesPage(list, left, r.URL.Query(), time.Now()))
+}
+
+// invitesPage is the data of /settings/invites
+type invitesPage struct {
+	page
+	Success, Error string
+	Left           int
+	TTLDays        int
+	Invitations    []inviteRow
+}
+
+// newInvitesPage lists the member's invitations as they stand at now, with
+// left invitations still to give. q carries the outcome of the last form.
+func (h *InvitesHandler) newInvitesPage(list []db.Invitation, left int, q url.Values, now time.Time) invitesPage {
+	successMsg, errorMsg := "", ""
+	switch q.Get("saved") {
+	case "created":
+		successMsg = "Invitation created. Send the link below to them."
+	case "revoked":
+		successMsg = "Invitation revoked."
+	}
+	switch q.Get("error") {
+	case "handle":
+		errorMsg = "That is not a valid X handle."
+	case "registered":
+		errorMsg = "That handle already has an account."
+	case "pending":
+		errorMsg = "That handle already holds an unused invitation."
+	case "quota":
+		errorMsg = "You have no invitations left."
+	}
+
+	rows := make([]inviteRow, 0, len(list))
+	for _, inv := range list {
+		row := inviteRow{Invitation: inv, Status: inv.Status(now)}
+		switch row.Status {
+		case db.InvitationPending:
+			row.Link = h.BaseURL + "/register?code=" + url.QueryEscape(inv.Code) + "&handle=" + url.QueryEscape(inv.TwitterHandle)
+		case db.InvitationUsed:
+			row.Joined = inv.TwitterHandle
+			if inv.UsedByHandle != nil {
+				row.Joined = *inv.UsedByHandle
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return invitesPage{
+		page:        page{Title: "Invitations", Style: "invites", Nav: "settings"},
+		Success:     successMsg,
+		Error:       errorMsg,
+		Left:        left,
+		TTLDays:     int(invites.TTL.Hours() / 24),
+		Invitations: rows,
+	}
+}
+
+// inviteRow is an invitation in the member's list
+type inviteRow struct {
+	db.Invitation
+	Status string
+	Link   string // registration link, while pending
+	Joined string // handle the invitee registered as, once used
+}
+
+// PostHTTP handles POST /settings/invites — invite a handle
+func (h *InvitesHandler) PostHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	_, err := h.Invites.Create(r.Context(), r.FormValue("handle"), &p.Human.ID)
+	switch {
+	case errors.Is(err, invites.ErrInvalidHandle):
+		http.Redirect(w, r, "/settings/invites?error=handle", http.StatusSeeOther)
+	case errors.Is(err, invites.ErrRegistered):
+		http.Redirect(w, r, "/settings/invites?error=registered", http.StatusSeeOther)
+	case errors.Is(err, invites.ErrPending):
+		http.Redirect(w, r, "/settings/invites?error=pending", http.StatusSeeOther)
+	case errors.Is(err, db.ErrInviteQuota):
+		http.Redirect(w, r, "/settings/invites?error=quota", http.StatusSeeOther)
+	case err != nil:
+		serverError(w, r, "Database error", err)
+	default:
+		http.Redirect(w, r, "/settings/invites?saved=created", http.StatusSeeOther)
+	}
+}
+
+// PostRevokeHTTP handles POST /settings/invites/{id}/revoke — members revoke
+// only their own unused invitations
+func (h *InvitesHandler) PostRevokeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if _, err := h.Queries.RevokeInvitation(r.Context(), id, &p.Human.ID); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	http.Redirect(w, r, "/settings/invites?saved=revoked", http.StatusSeeOther)
+}
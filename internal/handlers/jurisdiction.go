@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/invites"
+	"github.com/BioAILogic/agentbridge/internal/jurisdiction"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+// jurisdictionCard is the settings card for self-declaring a class
+type jurisdictionCard struct {
+	Class         string
+	Source        string
+	SetByOperator bool // no declaration possible
+	Options       []jurisdictionOption
+}
+
+type jurisdictionOption struct {
+	Class, Label string
+	Checked      bool
+}
+
+func newJurisdictionCard(human db.Human) jurisdictionCard {
+	card := jurisdictionCard{Class: human.Jurisdiction, Source: "Not declared yet; shown as the default."}
+	switch human.JurisdictionSource {
+	case db.JurisdictionSelf:
+		card.Source = "Declared by you."
+	case db.JurisdictionVerified:
+		card.Source = "Derived from your verification. Your declaration does not change it."
+		if human.JurisdictionVerifiedBy != nil {
+			card.Source = "Derived from your verification by " + *human.JurisdictionVerifiedBy + ". Your declaration does not change it."
+		}
+	case db.JurisdictionAdmin:
+		card.SetByOperator = true
+		return card
+	}
+
+	for _, c := range jurisdiction.Classes {
+		label := "Inside the EU or EEA"
+		if c == jurisdiction.NonEEA {
+			label = "Outside the EU and EEA"
+		}
+		card.Options = append(card.Options, jurisdictionOption{Class: c, Label: label, Checked: c == human.Jurisdiction})
+	}
+	return card
+}
+
+// PostJurisdictionHTTP handles POST /settings/jurisdiction — self-declare a class
+func (h *SettingsHandler) PostJurisdictionHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	class := jurisdiction.Normalize(r.FormValue("jurisdiction"))
+	if class == "" {
+		http.Redirect(w, r, "/settings?error=1#jurisdiction", http.StatusSeeOther)
+		return
+	}
+	if err := h.Queries.SetDeclaredJurisdiction(r.Context(), p.Human.ID, class); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	http.Redirect(w, r, "/settings?saved=jurisdiction#jurisdiction", http.StatusSeeOther)
+}
+
+// mayPostIn reports whether an author may post in space: archived spaces are
+// read-only, and an author's jurisdiction must be allowed there (agents are
+// held to their owner's class)
+func mayPostIn(ctx context.Context, q *db.Queries, space db.Space, authorType string, authorID int) (bool, error) {
+	if space.ArchivedAt != nil {
+		return false, nil
+	}
+	if space.AllowedJurisdictions == nil {
+		return true, nil
+	}
+	class, err := q.GetAuthorJurisdiction(ctx, authorType, authorID)
+	if err != nil {
+		return false, err
+	}
+	return jurisdiction.Allowed(space.AllowedJurisdictions, class), nil
+}
+
+// SetJurisdictionHTTP handles POST /admin/humans/{handle}/jurisdiction —
+// body {"jurisdiction": "EU-EEA"} to override, {"jurisdiction": null} to release
+func (h *AdminHandler) SetJurisdictionHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Jurisdiction *string `json:"jurisdiction"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"body must be {\"jurisdiction\": \"EU-EEA\" | \"non-EEA\" | null}"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Jurisdiction != nil {
+		class := jurisdiction.Normalize(*req.Jurisdiction)
+		if class == "" {
+			http.Error(w, `{"error":"jurisdiction must be \"EU-EEA\", \"non-EEA\" or null"}`, http.StatusBadRequest)
+			return
+		}
+		req.Jurisdiction = &class
+	}
+	human, err := h.Queries.GetHumanByHandle(r.Context(), invites.NormalizeHandle(chi.URLParam(r, "handle")))
+	if err != nil {
+		http.Error(w, `{"error":"human not found"}`, http.StatusNotFound)
+		return
+	}
+	if err := h.Queries.SetJurisdictionOverride(r.Context(), human.ID, req.Jurisdiction); err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	human, err = h.Queries.GetHumanByID(r.Context(), human.ID)
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"handle": human.TwitterHandle, "jurisdiction": human.Jurisdiction, "source": human.JurisdictionSource})
+}
+
+// SetSpaceJurisdictionsHTTP handles POST /admin/spaces/{id}/jurisdictions —
+// body {"allowed": ["EU-EEA"]} to restrict posting, {"allowed": null} to lift it
+func (h *AdminHandler) SetSpaceJurisdictionsHTTP(w http.ResponseWriter, r *http.Request) {
+	spaceID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, `{"error":"space not found"}`, http.StatusNotFound)
+		return
+	}
+	var req struct {
+		Allowed []string `json:"allowed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"body must be {\"allowed\": [\"EU-EEA\", ...] | null}"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Allowed != nil && len(req.Allowed) == 0 {
+		http.Error(w, `{"error":"allowed must name at least one jurisdiction; use null to lift the restriction"}`, http.StatusBadRequest)
+		return
+	}
+	var allowed []string
+	for _, a := range req.Allowed {
+		class := jurisdiction.Normalize(a)
+		if class == "" {
+			http.Error(w, `{"error":"allowed entries must be \"EU-EEA\" or \"non-EEA\""}`, http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(allowed, class) {
+			allowed = append(allowed, class)
+		}
+	}
+	found, err := h.Queries.SetSpaceJurisdictions(r.Context(), spaceID, allowed)
+	if err != nil {
+		serverError(w, r, `{"error":"Database error"}`, err)
+		return
+	}
+	if !found {
+		http.Error(w, `{"error":"space not found"}`, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"space_id": spaceID, "allowed": allowed})
+}
@@ -1,29 +1,25 @@
 package handlers
 
 import (
-	"crypto/rand"
-	"encoding/hex"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/loginguard"
+	"github.com/BioAILogic/agentbridge/internal/metrics"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
 )
 
 type LoginHandler struct {
 	Queries *db.Queries
+	Guard   *loginguard.Guard
 }
 
 func (h *LoginHandler) GetHTTP(w http.ResponseWriter, r *http.Request) {
-	staticDir := os.Getenv("STATIC_DIR")
-	if staticDir == "" {
-		staticDir = "/opt/synbridge/static"
-	}
-	http.ServeFile(w, r, filepath.Join(staticDir, "login.html"))
+	renderPublic(w, r, "login.html")
 }
 
 func (h *LoginHandler) PostHTTP(w http.ResponseWriter, r *http.Request) {
@@ -34,14 +30,35 @@ func (h *LoginHandler) PostHTTP(w http.ResponseWriter, r *http.Request) {
 
 	handle := strings.TrimSpace(r.FormValue("handle"))
 	password := r.FormValue("password")
+	remember := r.FormValue("remember") == "1"
 
 	// Normalize handle: strip leading @, lowercase
 	handle = strings.TrimPrefix(handle, "@")
 	handle = strings.ToLower(handle)
+	if len(handle) > 64 {
+		handle = handle[:64]
+	}
+
+	// Brute-force protection: delay repeated failures, refuse locked handles
+	// and networks. The attempt counts as failed until the password checks
+	// out. The error stays generic so a lockout reveals nothing.
+	attempt, err := h.Guard.Begin(r.Context(), handle, middleware.IPPrefix(r))
+	if err != nil {
+		logError(r, err)
+		h.renderError(w, r, "Error checking sign-in, please try again")
+		return
+	}
+	if attempt.Locked {
+		metrics.RateLimitRejections.WithLabelValues(metrics.LimiterLogin).Inc()
+		h.renderError(w, r, "Invalid handle or password")
+		return
+	}
+	attempt.Wait(r.Context())
 
 	// Look up human by handle
 	human, err := h.Queries.GetHumanByHandle(r.Context(), handle)
 	if err != nil {
+		h.recordFailure(r, attempt, nil, handle)
 		// Generic error - never distinguish handle-not-found from wrong-password
 		h.renderError(w, r, "Invalid handle or password")
 		return
@@ -49,51 +66,72 @@ func (h *LoginHandler) PostHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Compare bcrypt hash
 	if err := bcrypt.CompareHashAndPassword([]byte(human.PasswordHash), []byte(password)); err != nil {
+		h.recordFailure(r, attempt, &human, handle)
 		// Generic error - never distinguish handle-not-found from wrong-password
 		h.renderError(w, r, "Invalid handle or password")
 		return
 	}
+	if err := h.Guard.Succeeded(r.Context(), attempt, human.ID); err != nil {
+		middleware.Log(r.Context()).Error("recording sign-in success", "human_id", human.ID, "err", err)
+	}
+	if human.SuspendedAt != nil {
+		h.renderError(w, r, "This account is suspended")
+		return
+	}
 
-	// Create session (24h expiry)
-	sessionID, err := generateSessionIDLogin()
-	if err != nil {
-		h.renderError(w, r, "Error creating session")
+	// Second factor: park the login until a TOTP or recovery code is given
+	if human.TOTPEnabledAt != nil {
+		pendingID, err := generateSessionID()
+		if err != nil {
+			logError(r, err)
+			h.renderError(w, r, "Error creating session")
+			return
+		}
+		if err := h.Queries.CreatePendingLogin(r.Context(), middleware.TokenHash(pendingID), human.ID, remember, time.Now().UTC().Add(pendingLoginTTL)); err != nil {
+			logError(r, err)
+			h.renderError(w, r, "Error creating session")
+			return
+		}
+		c := middleware.Cookies.New("sb_pending", pendingID, http.SameSiteLaxMode)
+		c.MaxAge = int(pendingLoginTTL.Seconds())
+		http.SetCookie(w, c)
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
 		return
 	}
-	expiresAt := time.Now().UTC().Add(24 * time.Hour)
-	if err := h.Queries.CreateSession(r.Context(), sessionID, human.ID, expiresAt); err != nil {
+
+	if err := startSession(w, r, h.Queries, human.ID, remember); err != nil {
+		logError(r, err)
 		h.renderError(w, r, "Error creating session")
 		return
 	}
 
-	// Set secure HTTP-only cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "sb_session",
-		Value:    sessionID,
-		Path:     "/",
-		Expires:  expiresAt,
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
-	})
-
 	// Redirect to home
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
 
-func (h *LoginHandler) renderError(w http.ResponseWriter, r *http.Request, msg string) {
-	// Redirect back to login with error in query param
-	http.Redirect(w, r, "/login?error="+urlEncodeLogin(msg), http.StatusSeeOther)
+// startSession creates a session for humanID and sets the session cookie
+func startSession(w http.ResponseWriter, r *http.Request, q *db.Queries, humanID int, remember bool) error {
+	token, err := generateSessionID()
+	if err != nil {
+		return err
+	}
+	session, err := q.CreateSession(r.Context(), middleware.TokenHash(token), humanID, remember, middleware.UserAgent(r), middleware.IPPrefix(r))
+	if err != nil {
+		return err
+	}
+	middleware.SetSessionCookie(w, token, session)
+	return nil
 }
 
-func generateSessionIDLogin() (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
+// recordFailure settles a failed attempt; a logging error must not change the response
+func (h *LoginHandler) recordFailure(r *http.Request, attempt *loginguard.Attempt, human *db.Human, handle string) {
+	metrics.AuthFailures.WithLabelValues(metrics.AuthPassword).Inc()
+	if err := h.Guard.Failed(r.Context(), attempt, human); err != nil {
+		middleware.Log(r.Context()).Error("recording sign-in failure", "handle", handle, "err", err)
 	}
-	return hex.EncodeToString(b), nil
 }
 
-func urlEncodeLogin(s string) string {
-	return strings.ReplaceAll(s, " ", "+")
+func (h *LoginHandler) renderError(w http.ResponseWriter, r *http.Request, msg string) {
+	// Redirect back to login with error in query param
+	http.Redirect(w, r, "/login?error="+urlEncode(msg), http.StatusSeeOther)
 }
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
 )
 
 type LogoutHandler struct {
@@ -12,22 +13,14 @@ type LogoutHandler struct {
 
 func (h *LogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Read session cookie
-	cookie, err := r.Cookie("sb_session")
+	cookie, err := middleware.Cookies.Read(r, middleware.SessionCookie)
 	if err == nil && cookie.Value != "" {
 		// Delete session from DB
-		_ = h.Queries.DeleteSession(r.Context(), cookie.Value)
+		_ = h.Queries.DeleteSession(r.Context(), middleware.TokenHash(cookie.Value))
 	}
 
 	// Clear cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "sb_session",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	middleware.ClearSessionCookie(w)
 
 	// Redirect to home page
 	http.Redirect(w, r, "/", http.StatusSeeOther)
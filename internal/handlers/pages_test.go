@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/export"
+	"github.com/BioAILogic/agentbridge/internal/jurisdiction"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden with the current output")
+
+// goldenPages are the page templates with the clock- and randomness-bound
+// functions pinned, so a page renders the same way every run
+var goldenPages = func() map[string]*template.Template {
+	funcs := template.FuncMap{}
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+	funcs["fieldID"] = func(prefix string) string { return prefix + "-test" }
+	return parsePages(funcs)
+}()
+
+// goldenTime is long enough ago that timeAgo prints a date, not a duration
+var goldenTime = time.Date(2025, time.March, 14, 15, 9, 26, 0, time.UTC)
+
+func ptr[T any](v T) *T { return &v }
+
+func goldenHuman() db.Human {
+	return db.Human{
+		ID:                 7,
+		TwitterHandle:      "ada",
+		Jurisdiction:       jurisdiction.NonEEA,
+		JurisdictionSource: db.JurisdictionSelf,
+		TribeName:          ptr("Analytical Engines"),
+		Bio:                ptr("Notes on the engine & <its> cards."),
+		Location:           ptr("London"),
+		Language:           "english",
+		Email:              ptr("ada@example.org"),
+		TOTPEnabledAt:      &goldenTime,
+		CreatedAt:          goldenTime,
+	}
+}
+
+func goldenAgents() []db.Agent {
+	return []db.Agent{
+		{ID: 3, OwnerID: 7, Name: "Babbage", OwnerHandle: "ada", Bio: ptr("Difference engine, second of its name."), CreatedAt: goldenTime},
+		{ID: 4, OwnerID: 7, Name: "Jacquard", OwnerHandle: "ada", CreatedAt: goldenTime},
+	}
+}
+
+func goldenSpace() db.Space {
+	return db.Space{ID: 2, Name: "Engines", Description: "Machines that compute.", CreatedAt: goldenTime}
+}
+
+// pageCases renders each page template with the data its handler's builder
+// makes from fixed rows and query strings; the name is the golden file's
+func pageCases() []struct {
+	name, page string
+	data       any
+} {
+	human := goldenHuman()
+	agents := goldenAgents()
+	space := goldenSpace()
+	later := goldenTime.Add(14 * 24 * time.Hour)
+	exports := export.NewService(nil, "", []byte("golden signing key"))
+	search := func(query string) searchParams {
+		params, msg := parseSearchParams(httptest.NewRequest(http.MethodGet, "/search?"+query, nil))
+		if msg != "" {
+			panic(msg)
+		}
+		return params
+	}
+	hits := []db.SearchResult{
+		{Kind: "post", PostID: 11, ThreadID: 9, ThreadTitle: "On the engine", SpaceID: 2, SpaceName: "Engines", AuthorType: "agent", AuthorName: "Babbage", AuthorTribe: "ada", Snippet: "the " + db.SnippetStart + "engine" + db.SnippetStop + " <weaves>", CreatedAt: goldenTime},
+		{Kind: "thread", PostID: 10, ThreadID: 9, ThreadTitle: "On the engine", SpaceID: 2, SpaceName: "Engines", AuthorType: "human", AuthorName: "ada", Snippet: "On the " + db.SnippetStart + "engine" + db.SnippetStop, CreatedAt: goldenTime},
+	}
+	// A full page plus the row that tells another one follows
+	var fullPage []db.SearchResult
+	for i := 0; i <= searchPageSize; i++ {
+		hit := hits[0]
+		hit.PostID = 100 + i
+		fullPage = append(fullPage, hit)
+	}
+	grace := db.Human{ID: 8, TwitterHandle: "grace", Jurisdiction: jurisdiction.EEA, JurisdictionSource: db.JurisdictionDefault, Language: "simple", CreatedAt: goldenTime}
+
+	return []struct {
+		name, page string
+		data       any
+	}{
+		{"admin-waitlist", "admin-waitlist.html", newWaitlistPage([]db.WaitlistEntry{
+			{ID: 1, TwitterHandle: "grace", Source: "landing", ConsentedAt: goldenTime, CreatedAt: goldenTime},
+			{ID: 2, TwitterHandle: "alan", Source: "landing", ConsentedAt: goldenTime, CreatedAt: goldenTime, InvitationID: ptr(5), InvitedAt: &goldenTime, InvitationCode: ptr("SB-ALAN"), Registered: true},
+		}, db.WaitlistStats{Total: 3, Waiting: 1, Invited: 2, Joined: 1}, url.Values{"invited": {"1"}, "skipped": {"0"}})},
+		{"agents", "agents.html", (&AgentsHandler{BaseURL: "https://synbridge.test"}).newAgentsPage(human, agents, url.Values{"name": {"Jacquard"}, "key": {"sb_agent_key"}})},
+		{"form-expired", "form-expired.html", newFormExpiredPage(&middleware.Principal{Kind: middleware.KindHuman, Human: &human})},
+		{"invites", "invites.html", (&InvitesHandler{BaseURL: "https://synbridge.test"}).newInvitesPage([]db.Invitation{
+			{ID: 5, Code: "SB-GRACE", TwitterHandle: "grace", CreatedBy: &human.ID, CreatedAt: goldenTime, ExpiresAt: &later},
+			{ID: 6, Code: "SB-ALAN", TwitterHandle: "alan", CreatedBy: &human.ID, CreatedAt: goldenTime, UsedAt: &goldenTime, UsedByHandle: ptr("alan")},
+		}, 2, url.Values{"saved": {"created"}}, goldenTime)},
+		{"password-forgot", "password-forgot.html", newForgotPage(nil)},
+		{"password-forgot-sent", "password-forgot.html", newForgotPage(url.Values{"sent": {"1"}})},
+		{"password-reset", "password-reset.html", newResetPage("reset-token", true, url.Values{"error": {"Password must be at least 8 characters"}})},
+		{"password-reset-expired", "password-reset.html", newResetPage("", false, nil)},
+		{"recovery-codes", "recovery-codes.html", newRecoveryCodesPage([]string{"abcde-fghij", "klmno-pqrst"})},
+		{"search", "search.html", newSearchPage(search("q=engine&space=2&author=agent"), "", []db.Space{space},
+			[]db.TribeSearchResult{{Human: human, Agents: agents}}, hits, nil)},
+		{"search-more", "search.html", newSearchPage(search("q=engine&page=2"), "", []db.Space{space}, nil, fullPage, nil)},
+		{"search-empty", "search.html", newSearchPage(search("q=loom"), "", []db.Space{space}, nil, nil, nil)},
+		{"sessions", "sessions.html", newSessionsPage([]db.Session{
+			{ID: "current", HumanID: 7, CreatedAt: goldenTime, ExpiresAt: goldenTime.Add(24 * time.Hour), LastSeenAt: goldenTime, UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15", IPPrefix: "203.0.113.0/24"},
+			{ID: "other", HumanID: 7, CreatedAt: goldenTime, ExpiresAt: goldenTime.Add(30 * 24 * time.Hour), LastSeenAt: goldenTime, UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0", IPPrefix: "2001:db8::/48", Remember: true},
+		}, "current", []db.SecurityEvent{{ID: 1, Kind: db.SecurityLoginFailed, HumanID: &human.ID, Handle: "ada", IPPrefix: "198.51.100.0/24", CreatedAt: goldenTime}}, url.Values{"saved": {"revoked"}})},
+		{"settings", "settings.html", newSettingsPage(human, url.Values{"saved": {"tribe"}},
+			handleVerificationCard{Available: true, Verified: &db.HandleVerification{HumanID: 7, Provider: "x", Subject: "1001", Handle: "ada", VerifiedAt: goldenTime}},
+			newTwoFactorCard(human, db.TOTPState{Secret: ptr("JBSWY3DPEHPK3PXP"), EnabledAt: &goldenTime}, 8),
+			passkeysCard{Keys: []db.Passkey{
+				{ID: 1, HumanID: 7, Name: "Laptop", CreatedAt: goldenTime, LastUsedAt: &goldenTime},
+				{ID: 2, HumanID: 7, Name: "Old key", CreatedAt: goldenTime, FlaggedAt: &goldenTime},
+			}},
+			newExportCard(db.ExportJob{ID: 4, HumanID: 7, Status: "ready", CreatedAt: goldenTime, ExpiresAt: &later}, exports),
+			&db.DeletionRequest{ID: 1, HumanID: &human.ID, Mode: "erase", RequestedAt: goldenTime, ScheduledFor: goldenTime.Add(30 * 24 * time.Hour)},
+		)},
+		{"settings-new-account", "settings.html", newSettingsPage(grace, url.Values{"error": {"1"}},
+			handleVerificationCard{},
+			newTwoFactorCard(grace, db.TOTPState{Secret: ptr("JBSWY3DPEHPK3PXP")}, 0),
+			passkeysCard{},
+			newExportCard(db.ExportJob{ID: 5, HumanID: 8, Status: "pending", CreatedAt: goldenTime}, exports),
+			nil,
+		)},
+		{"spaces", "spaces.html", newSpacesPage([]db.SpaceWithStats{
+			{ID: 2, Name: "Engines", Description: "Machines that compute.", CreatedAt: goldenTime, ThreadCount: 1, PostCount: 2, LastActivity: &goldenTime},
+			{ID: 3, Name: "Looms", Description: "Cards & patterns.", CreatedAt: goldenTime},
+		})},
+		{"thread", "thread.html", newThreadPage(
+			db.Thread{ID: 9, SpaceID: 2, Title: "On the engine", AuthorType: "human", AuthorID: 7, CreatedAt: goldenTime, LastPostAt: goldenTime},
+			space,
+			[]db.Post{
+				{ID: 10, ThreadID: 9, AuthorType: "human", AuthorID: 7, AuthorHandle: "ada", AuthorJurisdiction: human.Jurisdiction, Content: "It *weaves* algebraic patterns.", CreatedAt: goldenTime},
+				{ID: 11, ThreadID: 9, AuthorType: "agent", AuthorID: 3, AuthorHandle: "Babbage", AuthorTribe: "ada", Content: "Agreed.", CreatedAt: goldenTime},
+			},
+			db.ThreadReadState{ThreadID: 9, Tracked: true, LastReadPostID: 10, UnreadCount: 1, FirstUnreadPostID: 11, Watching: true},
+			human, agents, nil,
+		)},
+		{"thread-new", "thread-new.html", newThreadFormPage(space, human, agents, url.Values{"error": {"1"}})},
+		{"threads", "threads.html", newThreadsPage(space, []db.ThreadSummary{
+			{ID: 9, SpaceID: 2, Title: "On the engine", AuthorType: "human", AuthorID: 7, AuthorHandle: "ada", CreatedAt: goldenTime, LastPostAt: goldenTime, PostCount: 2},
+			{ID: 12, SpaceID: 2, Title: "Punched cards", AuthorType: "agent", AuthorID: 3, AuthorHandle: "Babbage", CreatedAt: goldenTime, LastPostAt: goldenTime, PostCount: 1},
+		}, map[int]db.ThreadReadState{
+			9: {ThreadID: 9, Tracked: true, LastReadPostID: 10, UnreadCount: 1, FirstUnreadPostID: 11, Watching: true},
+		})},
+		{"tribe", "tribe.html", newTribePage(human, &goldenTime, agents, []db.TribePost{
+			{PostID: 11, ThreadID: 9, ThreadTitle: "On the engine", SpaceID: 2, SpaceName: "Engines", AuthorType: "agent", AuthorName: "Babbage", Content: "Agreed.", CreatedAt: goldenTime},
+		})},
+		{"two-factor", "two-factor.html", newTwoFactorPage(url.Values{"error": {"1"}})},
+	}
+}
+
+func TestPages(t *testing.T) {
+	covered := map[string]bool{}
+	for _, tc := range pageCases() {
+		covered[tc.page] = true
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := execute(&buf, goldenRequest(), goldenPages[tc.page], "layout", tc.data); err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(buf.Bytes(), []byte(` style="`)) {
+				t.Error("style attribute in the page; the CSP ignores them, so move it to the stylesheet")
+			}
+			checkCSRFFields(t, buf.Bytes())
+			checkGolden(t, tc.name, buf.Bytes())
+		})
+	}
+	for name := range pages {
+		if !covered[name] {
+			t.Errorf("%s has no golden case", name)
+		}
+	}
+}
+
+func TestPublicPages(t *testing.T) {
+	for _, tmpl := range publicPages.Templates() {
+		name := tmpl.Name()
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := execute(&buf, goldenRequest(), publicPages, name, struct{ Nonce string }{"test-nonce"}); err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(buf.Bytes(), []byte(` style="`)) {
+				t.Error("style attribute in the page; the CSP ignores them, so move it to the nonce'd <style>")
+			}
+			checkCSRFFields(t, buf.Bytes())
+			checkGolden(t, "public-"+strings.TrimSuffix(name, ".html"), buf.Bytes())
+		})
+	}
+}
+
+// goldenRequest is the request pages render for, with a fixed CSRF token
+func goldenRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	return r.WithContext(middleware.WithCSRFToken(r.Context(), "test-csrf-token"))
+}
+
+var postForm = regexp.MustCompile(`(?i)<form\b[^>]*\bmethod="post"[^>]*>`)
+
+// checkCSRFFields fails unless every POST form starts with the CSRF field;
+// without it Protect rejects the submission
+func checkCSRFFields(t *testing.T, page []byte) {
+	t.Helper()
+	field := `<input type="hidden" name="` + middleware.CSRFField + `" value="test-csrf-token">`
+	for _, loc := range postForm.FindAllIndex(page, -1) {
+		if !bytes.HasPrefix(page[loc[1]:], []byte(field)) {
+			t.Errorf("%s has no CSRF field", page[loc[0]:loc[1]])
+		}
+	}
+}
+
+// checkGolden compares got with testdata/name.golden, or rewrites the file
+// when the test runs with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -run %s -update to create it)", err, t.Name())
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the rendered page; if the change is intended, run go test ./internal/handlers -run %s -update\n%s",
+			path, t.Name(), lineDiff(string(want), string(got)))
+	}
+}
+
+// lineDiff shows the first line where want and got part ways
+func lineDiff(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, wl, gl)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/metrics"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+const (
+	webauthnCeremonyTTL = 5 * time.Minute
+
+	// How recent the sign-in must be to add a passkey on an account with
+	// neither a password nor two-factor to ask for
+	passkeyFreshSignIn = 10 * time.Minute
+)
+
+// PasskeyHandler serves WebAuthn passkey registration (from /settings) and passkey login
+type PasskeyHandler struct {
+	Queries  *db.Queries
+	WebAuthn *webauthn.WebAuthn
+}
+
+// passkeyUser adapts a human and their passkeys to webauthn.User
+type passkeyUser struct {
+	human db.Human
+	id    []byte
+	creds []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte                         { return u.id }
+func (u *passkeyUser) WebAuthnName() string                       { return u.human.TwitterHandle }
+func (u *passkeyUser) WebAuthnDisplayName() string                { return u.human.DisplayName() }
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential { return u.creds }
+
+// ceremonyState is what a registration or login remembers between its two requests
+type ceremonyState struct {
+	Session  webauthn.SessionData `json:"session"`
+	Name     string               `json:"name,omitempty"`     // registration: the passkey's label
+	Remember bool                 `json:"remember,omitempty"` // login: "remember me" was ticked
+}
+
+// loadPasskeyUser returns the human as a webauthn.User, creating their user handle if needed
+func (h *PasskeyHandler) loadPasskeyUser(ctx context.Context, human db.Human) (*passkeyUser, error) {
+	candidate := make([]byte, 32)
+	if _, err := rand.Read(candidate); err != nil {
+		return nil, err
+	}
+	id, err := h.Queries.EnsureWebAuthnID(ctx, human.ID, candidate)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := h.Queries.ListPasskeys(ctx, human.ID)
+	if err != nil {
+		return nil, err
+	}
+	u := &passkeyUser{human: human, id: id}
+	for _, k := range keys {
+		var c webauthn.Credential
+		if err := json.Unmarshal(k.Credential, &c); err != nil {
+			return nil, err
+		}
+		u.creds = append(u.creds, c)
+	}
+	return u, nil
+}
+
+// saveCeremony stores state under a fresh sb_webauthn cookie
+func (h *PasskeyHandler) saveCeremony(w http.ResponseWriter, r *http.Request, humanID *int, state ceremonyState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	token, err := generateSessionID()
+	if err != nil {
+		return err
+	}
+	if err := h.Queries.SaveWebAuthnCeremony(r.Context(), middleware.TokenHash(token), humanID, data, time.Now().UTC().Add(webauthnCeremonyTTL)); err != nil {
+		return err
+	}
+	c := middleware.Cookies.New("sb_webauthn", token, http.SameSiteStrictMode)
+	c.MaxAge = int(webauthnCeremonyTTL.Seconds())
+	http.SetCookie(w, c)
+	return nil
+}
+
+// takeCeremony consumes the state named by the sb_webauthn cookie
+func (h *PasskeyHandler) takeCeremony(w http.ResponseWriter, r *http.Request) (*int, ceremonyState, bool) {
+	var state ceremonyState
+	cookie, err := middleware.Cookies.Read(r, "sb_webauthn")
+	if err != nil {
+		return nil, state, false
+	}
+	http.SetCookie(w, middleware.Cookies.Expire("sb_webauthn", http.SameSiteStrictMode))
+	humanID, data, err := h.Queries.TakeWebAuthnCeremony(r.Context(), middleware.TokenHash(cookie.Value))
+	if err != nil || json.Unmarshal(data, &state) != nil {
+		return nil, state, false
+	}
+	return humanID, state, true
+}
+
+// writePasskeyJSON writes v as JSON with the given status
+func writePasskeyJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// passkeyStepUp checks the proof asked before adding a passkey, which is a
+// new way into the account: the current password, the authenticator code
+// when two-factor is on, and on accounts with neither a recent sign-in. It
+// returns the message to show when the proof is missing or wrong.
+func (h *PasskeyHandler) passkeyStepUp(r *http.Request, p *middleware.Principal, password, code string) (string, error) {
+	human := *p.Human
+	if human.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(human.PasswordHash), []byte(password)); err != nil {
+			return "Current password is incorrect.", nil
+		}
+	} else if human.TOTPEnabledAt == nil && time.Since(p.Session.CreatedAt) > passkeyFreshSignIn {
+		return "For your security, sign out and sign in again, then add the passkey.", nil
+	}
+
+	ok, err := checkSecondFactor(r, h.Queries, human.ID, code, false)
+	if errors.Is(err, errSecondFactorLocked) {
+		return "Too many wrong authenticator codes. Wait 15 minutes and try again.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "That authenticator code did not work.", nil
+	}
+	return "", nil
+}
+
+// RegisterBeginHTTP handles POST /settings/passkeys/begin — body
+// {"name": "...", "password": "...", "totp_code": "..."}
+func (h *PasskeyHandler) RegisterBeginHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+	human := *p.Human
+
+	var body struct {
+		Name     string `json:"name"`
+		Password string `json:"password"`
+		TOTPCode string `json:"totp_code"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	name := strings.TrimSpace(body.Name)
+	if name == "" || len(name) > 60 {
+		writePasskeyJSON(w, http.StatusBadRequest, map[string]string{"error": "Give the passkey a name (max 60 characters)."})
+		return
+	}
+	msg, err := h.passkeyStepUp(r, p, body.Password, body.TOTPCode)
+	if err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "database error"})
+		return
+	}
+	if msg != "" {
+		writePasskeyJSON(w, http.StatusForbidden, map[string]string{"error": msg})
+		return
+	}
+
+	user, err := h.loadPasskeyUser(r.Context(), human)
+	if err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "database error"})
+		return
+	}
+	options, sessionData, err := h.WebAuthn.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(user.creds).CredentialDescriptors()),
+	)
+	if err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not start registration"})
+		return
+	}
+	if err := h.saveCeremony(w, r, &human.ID, ceremonyState{Session: *sessionData, Name: name}); err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "database error"})
+		return
+	}
+
+	writePasskeyJSON(w, http.StatusOK, options)
+}
+
+// RegisterFinishHTTP handles POST /settings/passkeys/finish — body is the authenticator's attestation
+func (h *PasskeyHandler) RegisterFinishHTTP(w http.ResponseWriter, r *http.Request) {
+	human := *middleware.PrincipalFrom(r.Context()).Human
+
+	humanID, state, ok := h.takeCeremony(w, r)
+	if !ok || humanID == nil || *humanID != human.ID {
+		writePasskeyJSON(w, http.StatusBadRequest, map[string]string{"error": "Registration expired, please try again."})
+		return
+	}
+	user, err := h.loadPasskeyUser(r.Context(), human)
+	if err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "database error"})
+		return
+	}
+
+	cred, err := h.WebAuthn.FinishRegistration(user, state.Session, r)
+	if err != nil {
+		middleware.Log(r.Context()).Warn("passkey registration failed", "err", err)
+		writePasskeyJSON(w, http.StatusBadRequest, map[string]string{"error": "The passkey could not be verified."})
+		return
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "encoding error"})
+		return
+	}
+	if err := h.Queries.CreatePasskey(r.Context(), human.ID, state.Name, cred.ID, data); err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "database error"})
+		return
+	}
+
+	writePasskeyJSON(w, http.StatusOK, map[string]string{"redirect": "/settings?saved=passkey#passkeys"})
+}
+
+// DeleteHTTP handles POST /settings/passkeys/{id}/delete
+func (h *PasskeyHandler) DeleteHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid passkey ID", http.StatusBadRequest)
+		return
+	}
+	deleted, err := h.Queries.DeletePasskey(r.Context(), id, p.Human.ID)
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if !deleted {
+		http.Redirect(w, r, "/settings?error=last-passkey#passkeys", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=passkey-removed#passkeys", http.StatusSeeOther)
+}
+
+// RemovePasswordHTTP handles POST /settings/password/remove — make the account
+// passkey-only. Requires the current password and at least one passkey, and
+// like any password change signs out the other sessions.
+func (h *PasskeyHandler) RemovePasswordHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+	human := *p.Human
+	if err := bcrypt.CompareHashAndPassword([]byte(human.PasswordHash), []byte(r.FormValue("password"))); err != nil {
+		http.Redirect(w, r, "/settings?error=password#passkeys", http.StatusSeeOther)
+		return
+	}
+
+	removed, err := h.Queries.RemovePassword(r.Context(), human.ID)
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if !removed {
+		http.Redirect(w, r, "/settings?error=no-passkey#passkeys", http.StatusSeeOther)
+		return
+	}
+	if _, err := h.Queries.RevokeOtherSessions(r.Context(), human.ID, p.Session.ID); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=passwordless#passkeys", http.StatusSeeOther)
+}
+
+// LoginBeginHTTP handles POST /login/passkey/begin — discoverable login, no handle
+// needed. Body {"remember": true} asks for a long-lived session.
+func (h *PasskeyHandler) LoginBeginHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Remember bool `json:"remember"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	options, sessionData, err := h.WebAuthn.BeginDiscoverableLogin()
+	if err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "could not start sign-in"})
+		return
+	}
+	if err := h.saveCeremony(w, r, nil, ceremonyState{Session: *sessionData, Remember: body.Remember}); err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "database error"})
+		return
+	}
+	writePasskeyJSON(w, http.StatusOK, options)
+}
+
+// passkeyFlaggedMsg is shown when a flagged or possibly copied passkey is used
+const passkeyFlaggedMsg = "This passkey may have been copied and can no longer sign in. Sign in another way and remove it in your settings."
+
+// LoginFinishHTTP handles POST /login/passkey/finish — verifies the assertion and
+// issues a session. A passkey already proves possession, so TOTP is not asked.
+// A signature counter that did not move forward means the passkey may have
+// been copied: it is flagged, the attempt is logged and refused, and a
+// flagged passkey is refused from then on.
+func (h *PasskeyHandler) LoginFinishHTTP(w http.ResponseWriter, r *http.Request) {
+	_, state, ok := h.takeCeremony(w, r)
+	if !ok {
+		writePasskeyJSON(w, http.StatusBadRequest, map[string]string{"error": "Sign-in expired, please try again."})
+		return
+	}
+
+	var human db.Human
+	lookup := func(rawID, userHandle []byte) (webauthn.User, error) {
+		var err error
+		human, err = h.Queries.GetHumanByWebAuthnID(r.Context(), userHandle)
+		if err != nil {
+			return nil, err
+		}
+		return h.loadPasskeyUser(r.Context(), human)
+	}
+	cred, err := h.WebAuthn.FinishDiscoverableLogin(lookup, state.Session, r)
+	if err != nil {
+		metrics.AuthFailures.WithLabelValues(metrics.AuthPasskey).Inc()
+		writePasskeyJSON(w, http.StatusUnauthorized, map[string]string{"error": "That passkey was not accepted."})
+		return
+	}
+
+	if cred.Authenticator.CloneWarning {
+		metrics.AuthFailures.WithLabelValues(metrics.AuthPasskey).Inc()
+		err := h.Queries.FlagPasskey(r.Context(), cred.ID)
+		if err == nil {
+			err = h.Queries.RecordSecurityEvent(r.Context(), db.SecurityPasskeyCloned, &human.ID, human.TwitterHandle,
+				middleware.IPPrefix(r), map[string]any{"stored_sign_count": cred.Authenticator.SignCount})
+		}
+		if err != nil {
+			logError(r, err)
+		}
+		writePasskeyJSON(w, http.StatusUnauthorized, map[string]string{"error": passkeyFlaggedMsg})
+		return
+	}
+
+	data, err := json.Marshal(cred)
+	used := false
+	if err == nil {
+		used, err = h.Queries.RecordPasskeyUse(r.Context(), cred.ID, data)
+	}
+	if err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "database error"})
+		return
+	}
+	if !used {
+		metrics.AuthFailures.WithLabelValues(metrics.AuthPasskey).Inc()
+		writePasskeyJSON(w, http.StatusUnauthorized, map[string]string{"error": passkeyFlaggedMsg})
+		return
+	}
+
+	if human.SuspendedAt != nil {
+		writePasskeyJSON(w, http.StatusForbidden, map[string]string{"error": "This account is suspended."})
+		return
+	}
+	if err := startSession(w, r, h.Queries, human.ID, state.Remember); err != nil {
+		logError(r, err)
+		writePasskeyJSON(w, http.StatusInternalServerError, map[string]string{"error": "Error creating session"})
+		return
+	}
+	writePasskeyJSON(w, http.StatusOK, map[string]string{"redirect": "/home"})
+}
+
+// passkeysCard is the passkey list on /settings
+type passkeysCard struct {
+	Keys   []db.Passkey
+	Failed bool
+}
+
+func (h *SettingsHandler) passkeysCard(ctx context.Context, human db.Human) passkeysCard {
+	keys, err := h.Queries.ListPasskeys(ctx, human.ID)
+	if err != nil {
+		middleware.Log(ctx).Error("rendering settings", "err", err)
+		return passkeysCard{Failed: true}
+	}
+	return passkeysCard{Keys: keys}
+}
@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/dbtest"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+const testOrigin = "https://synbridge.test"
+
+func testWebAuthn(t *testing.T) *webauthn.WebAuthn {
+	t.Helper()
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          "synbridge.test",
+		RPDisplayName: "Synbridge",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// softAuthenticator is a virtual platform authenticator: one P-256 passkey,
+// "none" attestation, a signature counter it bumps on every assertion
+type softAuthenticator struct {
+	Origin     string // the origin the "browser" reports in client data
+	key        *ecdsa.PrivateKey
+	credID     []byte
+	userHandle []byte
+	count      uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &softAuthenticator{Origin: testOrigin, key: key, credID: id}
+}
+
+var b64 = base64.RawURLEncoding
+
+func (a *softAuthenticator) clientData(typ string, challenge protocol.URLEncodedBase64) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": b64.EncodeToString(challenge),
+		"origin":    a.Origin,
+	})
+	return data
+}
+
+// authData is rpIdHash | flags | signCount, plus the credential when attested
+func (a *softAuthenticator) authData(rpID string, attested []byte) []byte {
+	rpHash := sha256.Sum256([]byte(rpID))
+	flags := byte(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	if attested != nil {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+	out := append(rpHash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, a.count)
+	return append(out, attested...)
+}
+
+// create answers navigator.credentials.create with the body POSTed to
+// /settings/passkeys/finish
+func (a *softAuthenticator) create(t *testing.T, opts protocol.CredentialCreation) []byte {
+	t.Helper()
+	switch id := opts.Response.User.ID.(type) {
+	case protocol.URLEncodedBase64: // straight from BeginRegistration
+		a.userHandle = id
+	case string: // decoded from the JSON the handler sent
+		handle, err := b64.DecodeString(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.userHandle = handle
+	}
+	pub := webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	}
+	coseKey, err := webauthncbor.Marshal(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16) // zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credID)))
+	attested = append(attested, a.credID...)
+	attested = append(attested, coseKey...)
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(opts.Response.RelyingParty.ID, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(map[string]any{
+		"id":    b64.EncodeToString(a.credID),
+		"rawId": b64.EncodeToString(a.credID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64.EncodeToString(a.clientData("webauthn.create", opts.Response.Challenge)),
+			"attestationObject": b64.EncodeToString(attestation),
+			"transports":        []string{"internal"},
+		},
+	})
+	return body
+}
+
+// get answers navigator.credentials.get with the body POSTed to
+// /login/passkey/finish
+func (a *softAuthenticator) get(t *testing.T, opts protocol.CredentialAssertion) []byte {
+	t.Helper()
+	a.count++
+	authData := a.authData(opts.Response.RelyingPartyID, nil)
+	clientData := a.clientData("webauthn.get", opts.Response.Challenge)
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(map[string]any{
+		"id":    b64.EncodeToString(a.credID),
+		"rawId": b64.EncodeToString(a.credID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64.EncodeToString(clientData),
+			"authenticatorData": b64.EncodeToString(authData),
+			"signature":         b64.EncodeToString(sig),
+			"userHandle":        b64.EncodeToString(a.userHandle),
+		},
+	})
+	return body
+}
+
+func finishRequest(body []byte) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+// TestPasskeyCeremony checks the virtual authenticator against the WebAuthn
+// configuration the server uses, without a database
+func TestPasskeyCeremony(t *testing.T) {
+	wa := testWebAuthn(t)
+	user := &passkeyUser{human: db.Human{ID: 1, TwitterHandle: "alice"}, id: []byte("user-handle-alice")}
+
+	register := func(t *testing.T, a *softAuthenticator) *webauthn.Credential {
+		t.Helper()
+		opts, session, err := wa.BeginRegistration(user, webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cred, err := wa.FinishRegistration(user, *session, finishRequest(a.create(t, *opts)))
+		if err != nil {
+			t.Fatalf("FinishRegistration: %v", err)
+		}
+		return cred
+	}
+	login := func(a *softAuthenticator, creds []webauthn.Credential, answer func(protocol.CredentialAssertion) []byte) (*webauthn.Credential, error) {
+		opts, session, err := wa.BeginDiscoverableLogin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		lookup := func(rawID, userHandle []byte) (webauthn.User, error) {
+			u := *user
+			u.creds = creds
+			return &u, nil
+		}
+		return wa.FinishDiscoverableLogin(lookup, *session, finishRequest(answer(*opts)))
+	}
+
+	t.Run("register and sign in", func(t *testing.T) {
+		a := newSoftAuthenticator(t)
+		cred := register(t, a)
+		got, err := login(a, []webauthn.Credential{*cred}, func(o protocol.CredentialAssertion) []byte { return a.get(t, o) })
+		if err != nil {
+			t.Fatalf("FinishDiscoverableLogin: %v", err)
+		}
+		if got.Authenticator.SignCount != 1 || got.Authenticator.CloneWarning {
+			t.Errorf("sign count %d, clone warning %v; want 1, false", got.Authenticator.SignCount, got.Authenticator.CloneWarning)
+		}
+	})
+
+	t.Run("wrong origin at registration", func(t *testing.T) {
+		a := newSoftAuthenticator(t)
+		a.Origin = "https://synbridge.test.evil.example"
+		opts, session, err := wa.BeginRegistration(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wa.FinishRegistration(user, *session, finishRequest(a.create(t, *opts))); err == nil {
+			t.Fatal("registration from a foreign origin was accepted")
+		}
+	})
+
+	t.Run("wrong origin at sign-in", func(t *testing.T) {
+		a := newSoftAuthenticator(t)
+		cred := register(t, a)
+		a.Origin = "https://synbridge.test.evil.example"
+		if _, err := login(a, []webauthn.Credential{*cred}, func(o protocol.CredentialAssertion) []byte { return a.get(t, o) }); err == nil {
+			t.Fatal("assertion from a foreign origin was accepted")
+		}
+	})
+
+	t.Run("answer to an old challenge", func(t *testing.T) {
+		a := newSoftAuthenticator(t)
+		cred := register(t, a)
+		old, _, err := wa.BeginDiscoverableLogin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stale := a.get(t, *old)
+		if _, err := login(a, []webauthn.Credential{*cred}, func(protocol.CredentialAssertion) []byte { return stale }); err == nil {
+			t.Fatal("assertion for another challenge was accepted")
+		}
+	})
+
+	t.Run("counter going backwards", func(t *testing.T) {
+		a := newSoftAuthenticator(t)
+		cred := register(t, a)
+		a.count = 10
+		stored, err := login(a, []webauthn.Credential{*cred}, func(o protocol.CredentialAssertion) []byte { return a.get(t, o) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.count = 4 // a copy of the key that signed less often
+		got, err := login(a, []webauthn.Credential{*stored}, func(o protocol.CredentialAssertion) []byte { return a.get(t, o) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Authenticator.CloneWarning {
+			t.Error("no clone warning for a counter that went from 11 to 5")
+		}
+	})
+}
+
+// passkeyClient drives PasskeyHandler the way passkeys.js does, carrying the
+// sb_webauthn cookie from begin to finish
+type passkeyClient struct {
+	t      *testing.T
+	h      *PasskeyHandler
+	p      *middleware.Principal
+	cookie *http.Cookie
+}
+
+func (c *passkeyClient) do(handler http.HandlerFunc, body []byte) *httptest.ResponseRecorder {
+	c.t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.RemoteAddr = "192.0.2.10:4321"
+	if c.p != nil {
+		r = r.WithContext(middleware.WithPrincipal(r.Context(), c.p))
+	}
+	if c.cookie != nil {
+		r.AddCookie(c.cookie)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	for _, ck := range w.Result().Cookies() {
+		if ck.Name == middleware.Cookies.Name("sb_webauthn") && ck.MaxAge > 0 {
+			c.cookie = ck
+		}
+	}
+	return w
+}
+
+func decodeInto(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasskeyHandlers(t *testing.T) {
+	q := dbtest.Open(t)
+	ctx := context.Background()
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	id, err := q.CreateHuman(ctx, "alice", string(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	human, err := q.GetHumanByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &PasskeyHandler{Queries: q, WebAuthn: testWebAuthn(t)}
+	owner := &middleware.Principal{
+		Kind:    middleware.KindHuman,
+		Human:   &human,
+		Session: &db.Session{HumanID: id, CreatedAt: time.Now()},
+	}
+
+	register := func(t *testing.T, a *softAuthenticator, name string) *httptest.ResponseRecorder {
+		t.Helper()
+		c := &passkeyClient{t: t, h: h, p: owner}
+		body, _ := json.Marshal(map[string]string{"name": name, "password": "correct horse"})
+		var opts protocol.CredentialCreation
+		decodeInto(t, c.do(h.RegisterBeginHTTP, body), &opts)
+		return c.do(h.RegisterFinishHTTP, a.create(t, opts))
+	}
+	beginLogin := func(t *testing.T) (*passkeyClient, protocol.CredentialAssertion) {
+		t.Helper()
+		c := &passkeyClient{t: t, h: h}
+		var opts protocol.CredentialAssertion
+		decodeInto(t, c.do(h.LoginBeginHTTP, []byte(`{}`)), &opts)
+		return c, opts
+	}
+
+	a := newSoftAuthenticator(t)
+
+	t.Run("registration needs the password", func(t *testing.T) {
+		c := &passkeyClient{t: t, h: h, p: owner}
+		w := c.do(h.RegisterBeginHTTP, []byte(`{"name": "Laptop", "password": "wrong"}`))
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("status %d, want 403: %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("register", func(t *testing.T) {
+		if w := register(t, a, "Laptop"); w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		keys, err := q.ListPasskeys(ctx, id)
+		if err != nil || len(keys) != 1 || keys[0].Name != "Laptop" {
+			t.Fatalf("passkeys = %+v, %v", keys, err)
+		}
+	})
+
+	t.Run("registration from a foreign origin", func(t *testing.T) {
+		evil := newSoftAuthenticator(t)
+		evil.Origin = "https://synbridge.test.evil.example"
+		if w := register(t, evil, "Phished"); w.Code != http.StatusBadRequest {
+			t.Fatalf("status %d, want 400: %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("sign in", func(t *testing.T) {
+		c, opts := beginLogin(t)
+		w := c.do(h.LoginFinishHTTP, a.get(t, opts))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		var session bool
+		for _, ck := range w.Result().Cookies() {
+			session = session || ck.Name == middleware.Cookies.Name("sb_session") && ck.Value != ""
+		}
+		if !session {
+			t.Error("no session cookie set")
+		}
+	})
+
+	t.Run("sign-in from a foreign origin", func(t *testing.T) {
+		c, opts := beginLogin(t)
+		a.Origin = "https://synbridge.test.evil.example"
+		defer func() { a.Origin = testOrigin }()
+		if w := c.do(h.LoginFinishHTTP, a.get(t, opts)); w.Code != http.StatusUnauthorized {
+			t.Fatalf("status %d, want 401: %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		c, opts := beginLogin(t)
+		body := a.get(t, opts)
+		cookie := c.cookie
+		if w := c.do(h.LoginFinishHTTP, body); w.Code != http.StatusOK {
+			t.Fatalf("first use: status %d: %s", w.Code, w.Body)
+		}
+		// The same answer, with the same ceremony cookie
+		c.cookie = cookie
+		if w := c.do(h.LoginFinishHTTP, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("replay: status %d, want 400: %s", w.Code, w.Body)
+		}
+		// The same answer to a fresh ceremony
+		c2, _ := beginLogin(t)
+		if w := c2.do(h.LoginFinishHTTP, body); w.Code != http.StatusUnauthorized {
+			t.Fatalf("replay to a new challenge: status %d, want 401: %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("cloned passkey", func(t *testing.T) {
+		a.count = 0 // a copy made before the signatures above
+		c, opts := beginLogin(t)
+		if w := c.do(h.LoginFinishHTTP, a.get(t, opts)); w.Code != http.StatusUnauthorized {
+			t.Fatalf("status %d, want 401: %s", w.Code, w.Body)
+		}
+		keys, err := q.ListPasskeys(ctx, id)
+		if err != nil || len(keys) != 1 || keys[0].FlaggedAt == nil {
+			t.Fatalf("passkey not flagged: %+v, %v", keys, err)
+		}
+		events, err := q.ListSecurityEvents(ctx, db.SecurityEventFilter{Kind: db.SecurityPasskeyCloned})
+		if err != nil || len(events) != 1 {
+			t.Fatalf("clone events = %+v, %v", events, err)
+		}
+
+		// The original, with a higher counter, is refused too now
+		a.count = 100
+		c, opts = beginLogin(t)
+		if w := c.do(h.LoginFinishHTTP, a.get(t, opts)); w.Code != http.StatusUnauthorized {
+			t.Fatalf("flagged passkey: status %d, want 401: %s", w.Code, w.Body)
+		}
+	})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/loginguard"
+	"github.com/BioAILogic/agentbridge/internal/mail"
+	"github.com/BioAILogic/agentbridge/internal/metrics"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+// Lifetimes of emailed links
+const (
+	resetTokenTTL  = time.Hour
+	verifyTokenTTL = 24 * time.Hour
+)
+
+// PasswordHandler serves the forgotten-password flow
+type PasswordHandler struct {
+	Queries *db.Queries
+	Guard   *loginguard.Guard
+	BaseURL string // public origin used in emailed links, e.g. https://synbridge.eu
+}
+
+// ForgotGetHTTP handles GET /password/forgot
+func (h *PasswordHandler) ForgotGetHTTP(w http.ResponseWriter, r *http.Request) {
+	render(w, r, "password-forgot.html", newForgotPage(r.URL.Query()))
+}
+
+// forgotPage is the data of /password/forgot
+type forgotPage struct {
+	page
+	Sent bool
+}
+
+// newForgotPage shows the form, or after a request (?sent=1) the notice
+// that a link is on its way if the account has an email
+func newForgotPage(q url.Values) forgotPage {
+	return forgotPage{page{Title: "Reset password", Style: "auth"}, q.Get("sent") == "1"}
+}
+
+// ForgotPostHTTP handles POST /password/forgot. The response never reveals
+// whether the account exists or has an email.
+func (h *PasswordHandler) ForgotPostHTTP(w http.ResponseWriter, r *http.Request) {
+	who := strings.TrimSpace(r.FormValue("who"))
+
+	var human db.Human
+	var err error
+	handle := strings.ToLower(strings.TrimPrefix(who, "@"))
+	if strings.Contains(who, "@") && !strings.HasPrefix(who, "@") {
+		human, err = h.Queries.GetHumanByEmail(r.Context(), who)
+	} else {
+		human, err = h.Queries.GetHumanByHandle(r.Context(), handle)
+	}
+	var found *db.Human
+	if err == nil {
+		found, handle = &human, human.TwitterHandle
+	}
+
+	// Cap reset mail per handle and per network, counting requests for
+	// unknown accounts too. A refused request gets the same answer as any
+	// other.
+	allowed, gerr := h.Guard.AllowReset(r.Context(), handle, middleware.IPPrefix(r), found)
+	if gerr != nil {
+		serverError(w, r, "Database error", gerr)
+		return
+	}
+	if !allowed {
+		metrics.RateLimitRejections.WithLabelValues(metrics.LimiterPasswordReset).Inc()
+	}
+
+	if allowed && found != nil && human.Email != nil {
+		token, terr := generateSessionID()
+		if terr == nil {
+			terr = h.Queries.CreateEmailToken(r.Context(), human.ID, db.TokenResetPassword, middleware.TokenHash(token), nil, time.Now().UTC().Add(resetTokenTTL))
+		}
+		if terr == nil {
+			m := mail.PasswordReset(*human.Email, human.TwitterHandle, h.BaseURL+"/password/reset?token="+token)
+			terr = h.Queries.EnqueueMail(r.Context(), m.To, m.Subject, m.Body)
+		}
+		if terr != nil {
+			serverError(w, r, "Database error", terr)
+			return
+		}
+	}
+
+	http.Redirect(w, r, "/password/forgot?sent=1", http.StatusSeeOther)
+}
+
+// ResetGetHTTP handles GET /password/reset?token=...
+func (h *PasswordHandler) ResetGetHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	ok, err := h.Queries.CheckEmailToken(r.Context(), db.TokenResetPassword, middleware.TokenHash(token))
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	render(w, r, "password-reset.html", newResetPage(token, ok, r.URL.Query()))
+}
+
+// resetPage is the data of /password/reset
+type resetPage struct {
+	page
+	Expired bool // the link is invalid, expired or already used
+	Token   string
+	Error   string
+}
+
+// newResetPage shows the new-password form for a usable token, otherwise
+// the expired-link notice
+func newResetPage(token string, usable bool, q url.Values) resetPage {
+	title := "Choose a new password"
+	if !usable {
+		title = "Reset password"
+	}
+	return resetPage{page{Title: title, Style: "auth"}, !usable, token, q.Get("error")}
+}
+
+// ResetPostHTTP handles POST /password/reset — consumes the token, sets the
+// password and signs the human out of every session
+func (h *PasswordHandler) ResetPostHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	password := r.FormValue("password")
+	back := "/password/reset?token=" + token + "&error="
+
+	if len(password) < 8 {
+		http.Redirect(w, r, back+urlEncode("Password must be at least 8 characters"), http.StatusSeeOther)
+		return
+	}
+	if password != r.FormValue("password_confirm") {
+		http.Redirect(w, r, back+urlEncode("Passwords do not match"), http.StatusSeeOther)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		serverError(w, r, "Error processing password", err)
+		return
+	}
+
+	t, err := h.Queries.ConsumeEmailToken(r.Context(), db.TokenResetPassword, middleware.TokenHash(token))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Redirect(w, r, "/password/reset?token="+token, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	if err := h.Queries.UpdatePasswordHash(r.Context(), t.HumanID, string(hash)); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if err := h.Queries.DeleteSessionsByHuman(r.Context(), t.HumanID); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	// A reset proves control of the account and lifts a password lockout
+	if human, err := h.Queries.GetHumanByID(r.Context(), t.HumanID); err == nil {
+		if err := h.Queries.RecordSecurityEvent(r.Context(), db.SecurityPasswordReset, &human.ID, human.TwitterHandle, middleware.IPPrefix(r), nil); err != nil {
+			middleware.Log(r.Context()).Error("recording password reset", "human_id", human.ID, "err", err)
+		}
+	}
+
+	http.Redirect(w, r, "/login?notice="+urlEncode("Password updated. Please sign in."), http.StatusSeeOther)
+}
+
+// CSRFFailureHTTP is shown when a form post arrives without a valid CSRF token,
+// typically a page left open across sign-out or a forged cross-site request
+func CSRFFailureHTTP(w http.ResponseWriter, r *http.Request) {
+	render(w, r, "form-expired.html", newFormExpiredPage(middleware.PrincipalFrom(r.Context())))
+}
+
+// formExpiredPage is the data of the CSRF rejection page
+type formExpiredPage struct {
+	page
+	Back string
+}
+
+// newFormExpiredPage sends p back into the forum, or anyone signed out to
+// the landing page
+func newFormExpiredPage(p *middleware.Principal) formExpiredPage {
+	back := "/"
+	if p != nil {
+		back = "/spaces"
+	}
+	return formExpiredPage{page{Title: "Form expired", Style: "auth"}, back}
+}
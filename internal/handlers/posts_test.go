@@ -0,0 +1,72 @@
+package handlers
+
+import "testing"
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		// raw HTML is shown as text
+		{"script", `<script>alert(1)</script>`, "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"inline script", `hi <script>alert(1)</script> there`, "<p>hi &lt;script&gt;alert(1)&lt;/script&gt; there</p>\n"},
+		{"html link", `<a href="javascript:alert(1)">x</a>`, "<p>&lt;a href=&#34;javascript:alert(1)&#34;&gt;x&lt;/a&gt;</p>\n"},
+		{"on* attribute", `<div onmouseover="alert(1)">x</div>`, "<p>&lt;div onmouseover=&#34;alert(1)&#34;&gt;x&lt;/div&gt;</p>\n"},
+		{"img onerror", `<img src=x onerror=alert(1)>`, "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{"svg", `<svg onload=alert(1)><circle/></svg>`, "<p>&lt;svg onload=alert(1)&gt;&lt;circle/&gt;&lt;/svg&gt;</p>\n"},
+		{"iframe", `<iframe src="https://evil.example"></iframe>`, "<p>&lt;iframe src=&#34;https://evil.example&#34;&gt;&lt;/iframe&gt;</p>\n"},
+		{"code span", "`<script>`", "<p><code>&lt;script&gt;</code></p>\n"},
+
+		// unsafe link targets lose the link
+		{"javascript link", `[x](javascript:alert(1))`, "<p><tt>x</tt></p>\n"},
+		{"mixed-case scheme", `[x](JaVaScRiPt:alert(1))`, "<p><tt>x</tt></p>\n"},
+		{"data link", `[x](data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==)`, "<p><tt>x</tt></p>\n"},
+		{"decimal entity scheme", `[x](&#106;avascript:alert(1))`, "<p><tt>x</tt></p>\n"},
+		{"hex entity scheme", `[x](&#x6A;&#x61;vascript:alert(1))`, "<p><tt>x</tt></p>\n"},
+		{"tab in scheme", `[x](java&#x09;script:alert(1))`, "<p><tt>x</tt></p>\n"},
+		{"javascript image", `![x](javascript:alert(1))`, "<p><img alt=\"x\"/></p>\n"},
+		{"attribute breakout", `![x](x" onerror="alert(1))`, "<p><img alt=\"x\"/>)</p>\n"},
+		{"title breakout", `[x](https://example.org "t" onclick=alert(1))`, "<p><a rel=\"nofollow noopener ugc\" target=\"_blank\">x</a>)</p>\n"},
+
+		// safe links keep their target and are marked as user content
+		{"link", `[x](https://example.org/?a=1&b=2)`, "<p><a rel=\"nofollow noopener ugc\" href=\"https://example.org/?a=1&amp;b=2\" target=\"_blank\">x</a></p>\n"},
+		{"autolink", `<https://example.org>`, "<p><a rel=\"nofollow noopener ugc\" href=\"https://example.org\" target=\"_blank\">https://example.org</a></p>\n"},
+		{"bare URL", `https://example.org`, "<p><a rel=\"nofollow noopener ugc\" href=\"https://example.org\" target=\"_blank\">https://example.org</a></p>\n"},
+		{"image", `![a](https://example.org/a.png)`, "<p><img src=\"https://example.org/a.png\" alt=\"a\"/></p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(tt.in); got != tt.want {
+				t.Errorf("renderMarkdown(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPostPolicy feeds postPolicy HTML the renderer never produces, in case
+// a renderer bug lets some through
+func TestPostPolicy(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"script", `<p>a<script>alert(1)</script>b</p>`, "<p>ab</p>"},
+		{"javascript href", `<a href="javascript:alert(1)" rel="nofollow noopener ugc">x</a>`, "<a rel=\"nofollow noopener ugc\">x</a>"},
+		{"decimal entity scheme", `<a href="&#106;avascript:alert(1)">x</a>`, "x"},
+		{"hex entity scheme", `<a href="&#x6A;avascript&#x3A;alert(1)">x</a>`, "x"},
+		{"data href", `<a href="data:text/html,<script>alert(1)</script>">x</a>`, "x"},
+		{"on* attribute on a link", `<a href="https://example.org" rel="nofollow noopener ugc" target="_blank" onclick="alert(1)">x</a>`, "<a href=\"https://example.org\" rel=\"nofollow noopener ugc\" target=\"_blank\">x</a>"},
+		{"foreign rel and target", `<a href="https://example.org" rel="opener" target="_top">x</a>`, "<a href=\"https://example.org\" rel=\"nofollow\">x</a>"},
+		{"on* and style", `<p onmouseover="alert(1)" style="color:red">x</p>`, "<p>x</p>"},
+		{"img onerror", `<img src=x onerror=alert(1)>`, "<img src=\"x\">"},
+		{"svg", `<svg onload=alert(1)><circle r="1"/></svg>`, ""},
+		{"iframe", `<iframe src="https://evil.example"></iframe>`, ""},
+		{"mathml", `<math><mi xlink:href="javascript:alert(1)">x</mi></math>`, "x"},
+		{"form", `<form action="https://evil.example"><input name=x></form>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postPolicy.Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
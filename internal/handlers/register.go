@@ -3,11 +3,9 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
-	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -19,11 +17,7 @@ type RegisterHandler struct {
 }
 
 func (h *RegisterHandler) GetHTTP(w http.ResponseWriter, r *http.Request) {
-	staticDir := os.Getenv("STATIC_DIR")
-	if staticDir == "" {
-		staticDir = "/opt/synbridge/static"
-	}
-	http.ServeFile(w, r, filepath.Join(staticDir, "register.html"))
+	renderPublic(w, r, "register.html")
 }
 
 func (h *RegisterHandler) PostHTTP(w http.ResponseWriter, r *http.Request) {
@@ -53,9 +47,14 @@ func (h *RegisterHandler) PostHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := h.Queries.GetInvitation(r.Context(), inviteCode)
+	inv, err := h.Queries.GetInvitation(r.Context(), inviteCode)
 	if err != nil {
-		h.renderError(w, r, "Invalid or already used invitation code")
+		h.renderError(w, r, "Invalid, expired or already used invitation code")
+		return
+	}
+	// The code is bound to the handle it was issued for
+	if !strings.EqualFold(strings.TrimPrefix(inv.TwitterHandle, "@"), handle) {
+		h.renderError(w, r, "This invitation code was issued for a different handle")
 		return
 	}
 
@@ -72,46 +71,30 @@ func (h *RegisterHandler) PostHTTP(w http.ResponseWriter, r *http.Request) {
 	// Hash password with bcrypt (cost 12)
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
 	if err != nil {
+		logError(r, err)
 		h.renderError(w, r, "Error processing registration")
 		return
 	}
 
-	// Insert human
-	humanID, err := h.Queries.CreateHuman(r.Context(), handle, string(hash))
-	if err != nil {
-		h.renderError(w, r, "Error creating account (handle may already exist)")
+	// Insert human and redeem the invitation together
+	humanID, err := h.Queries.RegisterWithInvitation(r.Context(), inviteCode, handle, string(hash))
+	if errors.Is(err, db.ErrInvitationUnavailable) {
+		h.renderError(w, r, "Invalid, expired or already used invitation code")
 		return
 	}
-
-	// Mark invitation used
-	if err := h.Queries.MarkInvitationUsed(r.Context(), inviteCode, humanID); err != nil {
-		h.renderError(w, r, "Error completing registration")
+	if err != nil {
+		logError(r, err)
+		h.renderError(w, r, "Error creating account (handle may already exist)")
 		return
 	}
 
 	// Create session
-	sessionID, err := generateSessionID()
-	if err != nil {
-		h.renderError(w, r, "Error creating session")
-		return
-	}
-	expiresAt := time.Now().UTC().Add(24 * time.Hour)
-	if err := h.Queries.CreateSession(r.Context(), sessionID, humanID, expiresAt); err != nil {
+	if err := startSession(w, r, h.Queries, humanID, false); err != nil {
+		logError(r, err)
 		h.renderError(w, r, "Error creating session")
 		return
 	}
 
-	// Set cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "sb_session",
-		Value:    sessionID,
-		Path:     "/",
-		Expires:  expiresAt,
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
-	})
-
 	// Redirect to home
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+	"github.com/BioAILogic/agentbridge/web"
+)
+
+// page is embedded in every template's data: the <title> prefix, the
+// stylesheet under /assets/css and the active nav item
+type page struct {
+	Title string
+	Style string
+	Nav   string
+}
+
+var templateFuncs = template.FuncMap{
+	"timeAgo":    timeAgo,
+	"formatTime": formatTime,
+	"join":       strings.Join,
+	"deref":      deref,
+	"device":     describeUserAgent,
+	"fieldID":    func(prefix string) string { return prefix + "-" + randomFieldSuffix() },
+	"highlight":  func(s string) template.HTML { return template.HTML(highlightSnippet(s)) },
+
+	// Stand-ins so the templates parse; execute binds them to the request
+	"csrfField": func() template.HTML { return "" },
+	"csrfToken": func() string { return "" },
+}
+
+// requestFuncs are the template functions bound to r: csrfField is the
+// hidden input every POST form carries, csrfToken the value of the
+// csrf-token meta tag that scripts send back as X-CSRF-Token
+func requestFuncs(r *http.Request) template.FuncMap {
+	token := middleware.CSRFToken(r.Context())
+	field := template.HTML(`<input type="hidden" name="` + middleware.CSRFField + `" value="` + template.HTMLEscapeString(token) + `">`)
+	return template.FuncMap{
+		"csrfField": func() template.HTML { return field },
+		"csrfToken": func() string { return token },
+	}
+}
+
+// execute runs the template name from set with r's functions bound. It
+// works on a clone: html/template cannot clone a set that has run, and the
+// shared sets must stay clonable.
+func execute(w io.Writer, r *http.Request, set *template.Template, name string, data any) error {
+	t, err := set.Clone()
+	if err != nil {
+		return err
+	}
+	return t.Funcs(requestFuncs(r)).ExecuteTemplate(w, name, data)
+}
+
+// deref reads a nullable text column, nil as ""
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// pages maps a page template's file name (threads.html) to the template set
+// of that page, layout.html and partials.html. Each page defines "body".
+var pages = parsePages(templateFuncs)
+
+func parsePages(funcs template.FuncMap) map[string]*template.Template {
+	names, err := fs.Glob(web.Templates, "templates/*.html")
+	if err != nil {
+		panic(err)
+	}
+	sets := make(map[string]*template.Template)
+	for _, name := range names {
+		base := path.Base(name)
+		if base == "layout.html" || base == "partials.html" {
+			continue
+		}
+		sets[base] = template.Must(template.New(base).Funcs(funcs).ParseFS(web.Templates,
+			"templates/layout.html", "templates/partials.html", name))
+	}
+	return sets
+}
+
+// publicPages are the standalone pages under templates/public (landing,
+// FAQ, login, register). They share no layout; their data is only the
+// request's CSP nonce for their inline <style>.
+var publicPages = template.Must(template.New("index.html").Funcs(templateFuncs).ParseFS(web.Templates, "templates/public/*.html"))
+
+// renderPublic writes the public page name
+func renderPublic(w http.ResponseWriter, r *http.Request, name string) {
+	var buf bytes.Buffer
+	if err := execute(&buf, r, publicPages, name, struct{ Nonce string }{middleware.CSPNonce(r.Context())}); err != nil {
+		serverError(w, r, "Template error", err)
+		return
+	}
+	writeHTML(w, http.StatusOK, &buf)
+}
+
+// render writes the page template name with data. It renders into a buffer
+// first so a template error still turns into a clean 500.
+func render(w http.ResponseWriter, r *http.Request, name string, data any) {
+	renderStatus(w, r, http.StatusOK, name, data)
+}
+
+// renderStatus is render with a status other than 200
+func renderStatus(w http.ResponseWriter, r *http.Request, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := execute(&buf, r, pages[name], "layout", data); err != nil {
+		serverError(w, r, "Template error", err)
+		return
+	}
+	writeHTML(w, status, &buf)
+}
+
+// writeHTML sends a rendered page. It carries a per-session CSRF token, so
+// a cached copy is revalidated unless the handler chose stricter caching.
+func writeHTML(w http.ResponseWriter, status int, buf *bytes.Buffer) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
-	"fmt"
 	"html"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/BioAILogic/agentbridge/internal/db"
 )
@@ -14,208 +16,193 @@ type SearchHandler struct {
 }
 
 func (h *SearchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sb_session")
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-	_, err = h.Queries.GetSession(r.Context(), cookie.Value)
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-
-	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	params, paramErr := parseSearchParams(r)
+
+	var tribes []db.TribeSearchResult
+	var results []db.SearchResult
+	var searchErr error
+	if paramErr == "" && params.Query != "" {
+		// Tribes matching the handle/name (first page only)
+		if params.Page == 1 {
+			tribes, _ = h.Queries.SearchTribes(r.Context(), params.Query)
+		}
 
-	var resultsHTML string
-	if query != "" {
-		tribes, err := h.Queries.SearchTribes(r.Context(), query)
-		if err != nil {
-			resultsHTML = `<div class="no-results">Search error. Please try again.</div>`
-		} else if len(tribes) == 0 {
-			resultsHTML = `<div class="no-results">No tribes found for "` + html.EscapeString(query) + `".</div>`
-		} else {
-			for _, t := range tribes {
-				displayName := html.EscapeString(t.Human.DisplayName())
-				handle := html.EscapeString(t.Human.TwitterHandle)
-
-				// Member list: human + agents
-				memberLine := `<span class="member human-member">` + handle + `</span>`
-				for _, a := range t.Agents {
-					memberLine += ` <span class="member agent-member">` + html.EscapeString(a.Name) + ` <span class="agent-pip">AI</span></span>`
-				}
-
-				resultsHTML += `<a href="/tribes/` + handle + `" class="tribe-card">
-  <div class="tribe-header">
-    <span class="tribe-display-name">` + displayName + `</span>`
-				if t.Human.TribeName != nil && *t.Human.TribeName != "" {
-					resultsHTML += ` <span class="tribe-handle">@` + handle + `</span>`
-				}
-				resultsHTML += `
-  </div>
-  <div class="tribe-members">` + memberLine + `</div>
-</a>`
-			}
+		// Posts and thread titles
+		results, searchErr = h.Queries.SearchContent(r.Context(), params.Query, params.Filter)
+		if searchErr != nil {
+			logError(r, searchErr)
 		}
 	}
 
-	escapedQuery := html.EscapeString(query)
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprintf(w, `<!DOCTYPE html>
-<html lang="en">
-<head>
-<meta charset="UTF-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0">
-<title>Search — Synbridge</title>
-<link rel="icon" href="/assets/favicon.svg" type="image/svg+xml">
-<link rel="preconnect" href="https://fonts.googleapis.com">
-<link href="https://fonts.googleapis.com/css2?family=Cormorant+Garamond:ital,wght@0,300;0,400;0,600;1,300;1,400&family=DM+Mono:wght@300;400;500&family=Outfit:wght@200;300;400;500&display=swap" rel="stylesheet">
-<style>
-:root {
-  --bg:        #080810;
-  --surface:   #0f0f1a;
-  --card:      #13131f;
-  --border:    #1e1e32;
-  --purple:    #8b5cf6;
-  --purple-dim:#5b3fa8;
-  --gold:      #f0a500;
-  --gold-dim:  #a87000;
-  --glow:      #a78bfa;
-  --text:      #e8e8f0;
-  --muted:     #6b6b8a;
-  --subtle:    #2a2a42;
-}
+	// Filter controls
+	spaces, _ := h.Queries.ListSpaces(r.Context())
 
-*, *::before, *::after { box-sizing: border-box; margin: 0; padding: 0; }
-body {
-  background: var(--bg);
-  color: var(--text);
-  font-family: 'Outfit', sans-serif;
-  font-weight: 300;
-  line-height: 1.7;
-  min-height: 100vh;
+	render(w, r, "search.html", newSearchPage(params, paramErr, spaces, tribes, results, searchErr))
 }
 
-nav {
-  position: fixed;
-  top: 0; left: 0; right: 0;
-  z-index: 100;
-  padding: 1.4rem 2.5rem;
-  display: flex;
-  align-items: center;
-  justify-content: space-between;
-  background: rgba(8,8,16,0.6);
-  backdrop-filter: blur(24px);
-  border-bottom: 1px solid rgba(139,92,246,0.08);
+// searchPage is the data of /search
+type searchPage struct {
+	page
+	Params     searchParams
+	Spaces     []db.Space
+	Tribes     []db.TribeSearchResult
+	Results    []db.SearchResult
+	Error      string
+	NoneFound  bool
+	Prev, Next string // other pages of the same search
 }
-.nav-logo { display: flex; align-items: center; text-decoration: none; }
-.nav-right { display: flex; align-items: center; gap: 1rem; }
-.btn-nav {
-  font-family: 'DM Mono', monospace;
-  font-size: 0.7rem;
-  letter-spacing: 0.1em;
-  text-transform: uppercase;
-  color: var(--muted);
-  background: transparent;
-  border: 1px solid var(--border);
-  padding: 0.5rem 1rem;
-  border-radius: 2px;
-  cursor: pointer;
-  transition: all 0.3s;
-  text-decoration: none;
-  display: inline-block;
+
+// newSearchPage lays out one page of a search. results is what SearchContent
+// returned for params, including the extra row that tells whether another
+// page follows; searchErr is its error.
+func newSearchPage(params searchParams, paramErr string, spaces []db.Space, tribes []db.TribeSearchResult, results []db.SearchResult, searchErr error) searchPage {
+	data := searchPage{page: page{Title: "Search", Style: "search", Nav: "search"}, Params: params, Spaces: spaces, Error: paramErr}
+	if paramErr != "" || params.Query == "" {
+		return data
+	}
+	data.Tribes = tribes
+	if searchErr != nil {
+		data.Error = "Search error. Please try again."
+		return data
+	}
+
+	hasMore := len(results) > searchPageSize
+	if hasMore {
+		results = results[:searchPageSize]
+	}
+	for i := range results {
+		if results[i].AuthorName == "" {
+			results[i].AuthorName = "Unknown"
+		}
+	}
+	data.Results = results
+	data.NoneFound = len(results) == 0 && len(tribes) == 0
+	if params.Page > 1 {
+		data.Prev = params.pageURL(params.Page - 1)
+	}
+	if hasMore {
+		data.Next = params.pageURL(params.Page + 1)
+	}
+	return data
 }
-.btn-nav:hover { color: var(--text); border-color: var(--subtle); }
-.btn-nav.active { color: var(--glow); border-color: rgba(139,92,246,0.4); }
-
-.container { max-width: 680px; margin: 0 auto; padding: 7rem 1.5rem 2.5rem; }
-h1 { font-family: 'Cormorant Garamond', serif; font-size: 2rem; font-weight: 400; color: var(--glow); margin-bottom: 1.5rem; }
-
-.search-form { display: flex; gap: 0.7rem; margin-bottom: 2rem; }
-.search-input {
-  flex: 1;
-  background: var(--surface);
-  border: 1px solid var(--border);
-  border-radius: 8px;
-  color: var(--text);
-  font-family: 'Outfit', sans-serif;
-  font-size: 1rem;
-  padding: 0.65rem 1rem;
-  outline: none;
-  transition: border-color 0.2s;
+
+// searchPageSize is the number of post/thread hits per results page
+const searchPageSize = 20
+
+// searchParams is a parsed search request, shared by the HTML page and the agent API
+type searchParams struct {
+	Query   string
+	Tribe   string
+	FromStr string
+	ToStr   string
+	Page    int
+	Filter  db.SearchFilter
 }
-.search-input:focus { border-color: var(--purple); }
-.search-input::placeholder { color: var(--muted); }
-.btn-search {
-  background: var(--purple-dim);
-  color: #fff;
-  border: none;
-  border-radius: 8px;
-  padding: 0.65rem 1.4rem;
-  font-family: 'Outfit', sans-serif;
-  font-size: 0.9rem;
-  cursor: pointer;
-  transition: background 0.2s;
+
+// parseSearchParams reads q, space, author, tribe, from, to (YYYY-MM-DD, inclusive)
+// and page from the query string. Returns a user-facing message for invalid input.
+func parseSearchParams(r *http.Request) (searchParams, string) {
+	v := r.URL.Query()
+	p := searchParams{
+		Query:   strings.TrimSpace(v.Get("q")),
+		FromStr: strings.TrimSpace(v.Get("from")),
+		ToStr:   strings.TrimSpace(v.Get("to")),
+		Page:    1,
+	}
+	p.Tribe = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(v.Get("tribe")), "@"))
+	p.Filter.TribeHandle = p.Tribe
+
+	if len(p.Query) > 200 {
+		return p, "Search query too long (max 200 chars)."
+	}
+	if s := v.Get("space"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil || id < 0 {
+			return p, "Invalid space."
+		}
+		p.Filter.SpaceID = id
+	}
+	switch a := v.Get("author"); a {
+	case "", "human", "agent":
+		p.Filter.AuthorType = a
+	default:
+		return p, "Author type must be human or agent."
+	}
+	if p.FromStr != "" {
+		t, err := time.Parse("2006-01-02", p.FromStr)
+		if err != nil {
+			return p, "Invalid from date (use YYYY-MM-DD)."
+		}
+		p.Filter.From = &t
+	}
+	if p.ToStr != "" {
+		t, err := time.Parse("2006-01-02", p.ToStr)
+		if err != nil {
+			return p, "Invalid to date (use YYYY-MM-DD)."
+		}
+		t = t.Add(24 * time.Hour) // inclusive of the whole day
+		p.Filter.To = &t
+	}
+	if s := v.Get("page"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 100 {
+			return p, "Invalid page."
+		}
+		p.Page = n
+	}
+	// Fetch one extra row to know whether another page exists
+	p.Filter.Limit = searchPageSize + 1
+	p.Filter.Offset = (p.Page - 1) * searchPageSize
+	return p, ""
 }
-.btn-search:hover { background: var(--purple); }
-
-.tribe-card {
-  display: block;
-  background: var(--card);
-  border: 1px solid var(--border);
-  border-radius: 10px;
-  padding: 1.2rem 1.4rem;
-  margin-bottom: 0.8rem;
-  text-decoration: none;
-  color: inherit;
-  transition: border-color 0.2s, background 0.2s;
+
+// pageURL returns the URL of another page of the same search
+func (p searchParams) pageURL(page int) string {
+	v := url.Values{}
+	v.Set("q", p.Query)
+	if p.Filter.SpaceID != 0 {
+		v.Set("space", strconv.Itoa(p.Filter.SpaceID))
+	}
+	if p.Filter.AuthorType != "" {
+		v.Set("author", p.Filter.AuthorType)
+	}
+	if p.Tribe != "" {
+		v.Set("tribe", p.Tribe)
+	}
+	if p.FromStr != "" {
+		v.Set("from", p.FromStr)
+	}
+	if p.ToStr != "" {
+		v.Set("to", p.ToStr)
+	}
+	v.Set("page", strconv.Itoa(page))
+	return "/search?" + v.Encode()
 }
-.tribe-card:hover { border-color: var(--purple-dim); background: var(--surface); }
-
-.tribe-header { display: flex; align-items: baseline; gap: 0.6rem; margin-bottom: 0.5rem; }
-.tribe-display-name { font-size: 1.1rem; font-weight: 500; color: var(--text); }
-.tribe-handle { font-size: 0.8rem; color: var(--muted); font-family: 'DM Mono', monospace; }
-
-.tribe-members { display: flex; flex-wrap: wrap; gap: 0.4rem; }
-.member { font-size: 0.78rem; padding: 0.2rem 0.55rem; border-radius: 4px; }
-.human-member { background: rgba(139,92,246,0.12); color: var(--glow); border: 1px solid rgba(139,92,246,0.2); }
-.agent-member { background: rgba(240,165,0,0.1); color: var(--gold); border: 1px solid rgba(240,165,0,0.2); }
-.agent-pip { font-size: 0.65rem; opacity: 0.7; }
-
-.no-results { color: var(--muted); font-size: 0.95rem; padding: 1.5rem 0; }
-</style>
-</head>
-<body>
-<nav>
-  <a href="/spaces" class="nav-logo">
-    <img src="/assets/logos/SynbridgeMainNew.png" alt="Synbridge" style="height:55px;">
-  </a>
-  <div class="nav-right">
-    <a href="/spaces" class="btn-nav">Spaces</a>
-    <a href="/search" class="btn-nav active">Search</a>
-    <a href="/faq" class="btn-nav">FAQ</a>
-    <a href="/agents" class="btn-nav">Add an AI</a>
-    <a href="/settings" class="btn-nav">Settings</a>
-    <form method="POST" action="/logout" style="margin:0;">
-      <button type="submit" class="btn-nav">Sign Out</button>
-    </form>
-  </div>
-</nav>
-
-<div class="container">
-  <h1>Search tribes</h1>
-
-  <form class="search-form" method="GET" action="/search">
-    <input class="search-input" type="text" name="q" value="%s" placeholder="Handle or tribe name…" autofocus>
-    <button type="submit" class="btn-search">Search</button>
-  </form>
-
-  %s
-</div>
-</body>
-</html>`,
-		escapedQuery,
-		resultsHTML,
-	)
+
+// highlightSnippet HTML-escapes a search snippet and turns the database's
+// highlight delimiters into balanced <mark> tags
+func highlightSnippet(snippet string) string {
+	var b strings.Builder
+	open := false
+	start := 0
+	for i := 0; i < len(snippet); i++ {
+		c := snippet[i : i+1]
+		if c != db.SnippetStart && c != db.SnippetStop {
+			continue
+		}
+		b.WriteString(html.EscapeString(snippet[start:i]))
+		start = i + 1
+		if c == db.SnippetStart && !open {
+			b.WriteString("<mark>")
+			open = true
+		} else if c == db.SnippetStop && open {
+			b.WriteString("</mark>")
+			open = false
+		}
+	}
+	b.WriteString(html.EscapeString(snippet[start:]))
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
 }
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+// SessionsHTTP handles GET /settings/sessions — where the human is signed in
+func (h *SettingsHandler) SessionsHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	sessions, err := h.Queries.ListSessions(r.Context(), p.Human.ID)
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	failures, err := h.Queries.ListSecurityEvents(r.Context(), db.SecurityEventFilter{
+		Kind:    db.SecurityLoginFailed,
+		HumanID: p.Human.ID,
+		Since:   time.Now().Add(-30 * 24 * time.Hour),
+		Limit:   10,
+	})
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	render(w, r, "sessions.html", newSessionsPage(sessions, p.Session.ID, failures, r.URL.Query()))
+}
+
+// sessionsPage is the data of /settings/sessions
+type sessionsPage struct {
+	page
+	Success  string
+	Sessions []db.Session
+	Current  string // this browser's session
+	Failures []db.SecurityEvent
+}
+
+// newSessionsPage lists the human's sessions, current among them, and
+// recent failed sign-ins. q carries the outcome of the last sign-out.
+func newSessionsPage(sessions []db.Session, current string, failures []db.SecurityEvent, q url.Values) sessionsPage {
+	successMsg := ""
+	switch q.Get("saved") {
+	case "revoked":
+		successMsg = "Session signed out."
+	case "others":
+		successMsg = "All other sessions signed out."
+	}
+	return sessionsPage{page{Title: "Sessions", Style: "sessions", Nav: "settings"}, successMsg, sessions, current, failures}
+}
+
+// PostRevokeSessionHTTP handles POST /settings/sessions/{id}/revoke
+func (h *SettingsHandler) PostRevokeSessionHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	if _, err := h.Queries.RevokeSession(r.Context(), p.Human.ID, chi.URLParam(r, "id")); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	http.Redirect(w, r, "/settings/sessions?saved=revoked", http.StatusSeeOther)
+}
+
+// PostRevokeOthersHTTP handles POST /settings/sessions/revoke-others
+func (h *SettingsHandler) PostRevokeOthersHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	if _, err := h.Queries.RevokeOtherSessions(r.Context(), p.Human.ID, p.Session.ID); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	http.Redirect(w, r, "/settings/sessions?saved=others", http.StatusSeeOther)
+}
+
+// PostPasswordHTTP handles POST /settings/password — change the password and
+// sign out every other session
+func (h *SettingsHandler) PostPasswordHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+	human := *p.Human
+
+	if err := bcrypt.CompareHashAndPassword([]byte(human.PasswordHash), []byte(r.FormValue("current_password"))); err != nil {
+		http.Redirect(w, r, "/settings?error=password#password", http.StatusSeeOther)
+		return
+	}
+	password := r.FormValue("password")
+	if len(password) < 8 {
+		http.Redirect(w, r, "/settings?error=password-short#password", http.StatusSeeOther)
+		return
+	}
+	if password != r.FormValue("password_confirm") {
+		http.Redirect(w, r, "/settings?error=password-mismatch#password", http.StatusSeeOther)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		serverError(w, r, "Error processing password", err)
+		return
+	}
+	if err := h.Queries.UpdatePasswordHash(r.Context(), human.ID, string(hash)); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if _, err := h.Queries.RevokeOtherSessions(r.Context(), human.ID, p.Session.ID); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=password#password", http.StatusSeeOther)
+}
+
+// describeUserAgent turns a user agent into "Browser on OS" for the sessions list
+func describeUserAgent(ua string) string {
+	browser := ""
+	switch {
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	}
+	os := ""
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "" || os != "":
+		return browser + os
+	case ua != "":
+		return ua
+	}
+	return "Unknown device"
+}
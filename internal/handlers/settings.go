@@ -1,361 +1,471 @@
 package handlers
 
 import (
-	"fmt"
-	"html"
+	"errors"
+	"io"
 	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
 
 	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/deletion"
+	"github.com/BioAILogic/agentbridge/internal/export"
+	"github.com/BioAILogic/agentbridge/internal/mail"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
 )
 
 type SettingsHandler struct {
-	Queries *db.Queries
+	Queries            *db.Queries
+	Exports            *export.Service
+	BaseURL            string // public origin used in emailed links
+	HandleVerification bool   // an X identity provider is configured
 }
 
 func (h *SettingsHandler) GetHTTP(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sb_session")
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-	session, err := h.Queries.GetSession(r.Context(), cookie.Value)
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
+	p := middleware.PrincipalFrom(r.Context())
+	human := *p.Human
 
-	human, err := h.Queries.GetHumanByID(r.Context(), session.HumanID)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusInternalServerError)
-		return
+	// Data export status
+	var export exportCard
+	if job, err := h.Queries.GetLatestExportJob(r.Context(), human.ID); err == nil {
+		export = newExportCard(job, h.Exports)
 	}
 
-	currentTribeName := ""
-	if human.TribeName != nil {
-		currentTribeName = *human.TribeName
-	}
-	currentBio := ""
-	if human.Bio != nil {
-		currentBio = *human.Bio
-	}
-	currentLocation := ""
-	if human.Location != nil {
-		currentLocation = *human.Location
+	// Account deletion: pending request or the form to start one
+	var pending *db.DeletionRequest
+	if req, err := h.Queries.GetPendingDeletion(r.Context(), human.ID); err == nil {
+		pending = &req
 	}
 
+	render(w, r, "settings.html", newSettingsPage(human, r.URL.Query(),
+		h.handleVerificationCard(r.Context(), human),
+		h.twoFactorCard(r.Context(), human),
+		h.passkeysCard(r.Context(), human),
+		export, pending))
+}
+
+// newSettingsPage lays out /settings around the cards' loaded state; q
+// carries the outcome of the last form
+func newSettingsPage(human db.Human, q url.Values, verification handleVerificationCard, twoFactor twoFactorCard, passkeys passkeysCard, export exportCard, pending *db.DeletionRequest) settingsPage {
 	successMsg := ""
 	errorMsg := ""
-	switch r.URL.Query().Get("saved") {
+	switch q.Get("saved") {
 	case "tribe":
-		successMsg = `<div class="success">Tribe name updated.</div>`
+		successMsg = "Tribe name updated."
 	case "bio":
-		successMsg = `<div class="success">Bio updated.</div>`
+		successMsg = "Bio updated."
 	case "location":
-		successMsg = `<div class="success">Location updated.</div>`
-	}
-	if r.URL.Query().Get("error") == "1" {
-		errorMsg = `<div class="error">Value too long.</div>`
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprintf(w, `<!DOCTYPE html>
-<html lang="en">
-<head>
-<meta charset="UTF-8">
-<meta name="viewport" content="width=device-width, initial-scale=1.0">
-<title>Settings — Synbridge</title>
-<link rel="icon" href="/assets/favicon.svg" type="image/svg+xml">
-<link rel="preconnect" href="https://fonts.googleapis.com">
-<link href="https://fonts.googleapis.com/css2?family=Cormorant+Garamond:ital,wght@0,300;0,400;0,600;1,300;1,400&family=DM+Mono:wght@300;400;500&family=Outfit:wght@200;300;400;500&display=swap" rel="stylesheet">
-<style>
-:root {
-  --bg:        #080810;
-  --surface:   #0f0f1a;
-  --card:      #13131f;
-  --border:    #1e1e32;
-  --purple:    #8b5cf6;
-  --purple-dim:#5b3fa8;
-  --gold:      #f0a500;
-  --gold-dim:  #a87000;
-  --glow:      #a78bfa;
-  --text:      #e8e8f0;
-  --muted:     #6b6b8a;
-  --subtle:    #2a2a42;
-  --green:     #22c55e;
-}
+		successMsg = "Location updated."
+	case "jurisdiction":
+		successMsg = "Jurisdiction updated."
+	case "language":
+		successMsg = "Writing language updated."
+	case "export":
+		successMsg = "Export requested. It will be ready for download shortly."
+	case "delete":
+		successMsg = "Account deletion scheduled. You can cancel it below until then."
+	case "email":
+		successMsg = "Verification link sent. Your address changes once you open it."
+	case "email-verified":
+		successMsg = "Email address verified."
+	case "2fa-off":
+		successMsg = "Two-factor authentication turned off."
+	case "delete-cancel":
+		successMsg = "Account deletion cancelled."
+	case "password":
+		successMsg = "Password changed. Your other sessions were signed out."
+	case "passkey":
+		successMsg = "Passkey added."
+	case "passkey-removed":
+		successMsg = "Passkey removed."
+	case "passwordless":
+		successMsg = "Password removed. Sign in with a passkey from now on."
+	case "x-verified":
+		successMsg = "Handle verified. Your tribe page now shows the badge."
+	case "x-removed":
+		successMsg = "Verification removed."
+	}
+	switch q.Get("error") {
+	case "1":
+		errorMsg = "Value too long."
+	case "export-link":
+		errorMsg = "That download link is invalid, expired or already used. Request a new export."
+	case "password":
+		errorMsg = "Incorrect password."
+	case "totp":
+		errorMsg = "That authenticator code did not work. Codes change every 30 seconds and work once."
+	case "totp-locked":
+		errorMsg = "Too many wrong authenticator codes. Wait 15 minutes and try again."
+	case "email":
+		errorMsg = "Please enter a valid email address."
+	case "email-taken":
+		errorMsg = "That email address belongs to another account."
+	case "email-link":
+		errorMsg = "That verification link is invalid, expired or already used."
+	case "password-short":
+		errorMsg = "Password must be at least 8 characters."
+	case "password-mismatch":
+		errorMsg = "Passwords do not match."
+	case "last-passkey":
+		errorMsg = "This is your only way to sign in. Add another passkey first."
+	case "no-passkey":
+		errorMsg = "Add a passkey before removing your password."
+	case "handle":
+		errorMsg = "That is not your handle."
+	case "x-unavailable":
+		errorMsg = "Handle verification is not available on this server."
+	case "x-link":
+		errorMsg = "That verification attempt expired or was already used. Please start again."
+	case "x-denied":
+		errorMsg = "Verification was cancelled on X."
+	case "x-failed":
+		errorMsg = "X did not confirm your account. Please try again later."
+	case "x-mismatch":
+		errorMsg = "You authorized a different X account. Sign in to X as your login handle and try again."
+	case "x-taken":
+		errorMsg = "That X account already verifies another Synbridge account."
+	}
 
-*, *::before, *::after { box-sizing: border-box; margin: 0; padding: 0; }
-html, body { height: 100%%; }
+	languages := make([]languageOption, 0, len(db.SearchLanguages))
+	for _, lang := range db.SearchLanguages {
+		label := strings.ToUpper(lang[:1]) + lang[1:]
+		if lang == "simple" {
+			label = "Other / mixed (no stemming)"
+		}
+		languages = append(languages, languageOption{Value: lang, Label: label})
+	}
 
-body {
-  background: var(--bg);
-  color: var(--text);
-  font-family: 'Outfit', sans-serif;
-  font-weight: 300;
-  line-height: 1.7;
-  min-height: 100vh;
+	return settingsPage{
+		page:               page{Title: "Settings", Style: "settings", Nav: "settings"},
+		Human:              human,
+		Success:            successMsg,
+		Error:              errorMsg,
+		HandleVerification: verification,
+		Jurisdiction:       newJurisdictionCard(human),
+		Languages:          languages,
+		TwoFactor:          twoFactor,
+		Passkeys:           passkeys,
+		Export:             export,
+		Deletion:           pending,
+		GraceDays:          int(deletion.GracePeriod.Hours() / 24),
+	}
 }
 
+// settingsPage is the data of /settings; each card's state is loaded by the
+// file that handles its forms
+type settingsPage struct {
+	page
+	Human              db.Human
+	Success, Error     string
+	HandleVerification handleVerificationCard
+	Jurisdiction       jurisdictionCard
+	Languages          []languageOption
+	TwoFactor          twoFactorCard
+	Passkeys           passkeysCard
+	Export             exportCard
+	Deletion           *db.DeletionRequest // pending request, if any
+	GraceDays          int
+}
 
-.container { max-width: 600px; margin: 0 auto; padding: 7rem 1.5rem 2.5rem; }
-h1 { font-family: 'Cormorant Garamond', serif; font-size: 2rem; font-weight: 400; color: var(--glow); margin-bottom: 2rem; }
-
-.settings-card {
-  background: var(--card);
-  border: 1px solid var(--border);
-  border-radius: 12px;
-  padding: 1.8rem;
-  margin-bottom: 1.5rem;
+type languageOption struct {
+	Value, Label string
 }
-.settings-card h2 {
-  font-size: 1rem;
-  font-weight: 500;
-  color: var(--text);
-  margin-bottom: 1.2rem;
-  letter-spacing: 0.03em;
+
+// exportCard is the state of the latest data export
+type exportCard struct {
+	Preparing   bool
+	Failed      bool
+	DownloadURL string // signed link to a ready, not yet downloaded export
+	ExpiresAt   time.Time
 }
 
-.field-group { margin-bottom: 1.2rem; }
-.field-label { display: block; font-size: 0.8rem; color: var(--muted); margin-bottom: 0.4rem; letter-spacing: 0.05em; text-transform: uppercase; }
-.field-value { font-size: 0.95rem; color: var(--text); padding: 0.6rem 0; border-bottom: 1px solid var(--border); }
-.field-hint { font-size: 0.78rem; color: var(--muted); margin-top: 0.3rem; }
-
-input[type=text] {
-  width: 100%%;
-  background: var(--surface);
-  border: 1px solid var(--border);
-  border-radius: 8px;
-  color: var(--text);
-  font-family: 'Outfit', sans-serif;
-  font-size: 0.95rem;
-  padding: 0.65rem 0.9rem;
-  outline: none;
-  transition: border-color 0.2s;
+// newExportCard is the card for the human's latest export job; exports
+// signs the download link
+func newExportCard(job db.ExportJob, exports *export.Service) exportCard {
+	var card exportCard
+	switch {
+	case job.Status == "pending" || job.Status == "running":
+		card.Preparing = true
+	case job.Status == "ready" && job.DownloadedAt == nil && job.ExpiresAt != nil:
+		card.DownloadURL = exports.SignedURL(job.ID, *job.ExpiresAt)
+		card.ExpiresAt = *job.ExpiresAt
+	case job.Status == "failed":
+		card.Failed = true
+	}
+	return card
 }
-input[type=text]:focus { border-color: var(--purple); }
-input[type=text]::placeholder { color: var(--muted); }
-
-.btn-save {
-  display: inline-block;
-  background: var(--purple-dim);
-  color: #fff;
-  border: none;
-  border-radius: 8px;
-  padding: 0.6rem 1.4rem;
-  font-family: 'Outfit', sans-serif;
-  font-size: 0.9rem;
-  cursor: pointer;
-  transition: background 0.2s;
-  margin-top: 0.8rem;
+
+func (h *SettingsHandler) PostTribeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	tribeName := strings.TrimSpace(r.FormValue("tribe_name"))
+	if len(tribeName) > 60 {
+		http.Redirect(w, r, "/settings?error=1", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.Queries.UpdateTribeName(r.Context(), p.Human.ID, tribeName); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=tribe", http.StatusSeeOther)
 }
-.btn-save:hover { background: var(--purple); }
-
-.success { color: var(--green); font-size: 0.88rem; margin-bottom: 1rem; padding: 0.6rem 0.9rem; background: rgba(34,197,94,0.08); border-radius: 6px; border: 1px solid rgba(34,197,94,0.2); }
-.error   { color: #f87171; font-size: 0.88rem; margin-bottom: 1rem; padding: 0.6rem 0.9rem; background: rgba(248,113,113,0.08); border-radius: 6px; border: 1px solid rgba(248,113,113,0.2); }
-
-nav {
-  position: fixed;
-  top: 0; left: 0; right: 0;
-  z-index: 100;
-  padding: 1.4rem 2.5rem;
-  display: flex;
-  align-items: center;
-  justify-content: space-between;
-  background: rgba(8,8,16,0.6);
-  backdrop-filter: blur(24px);
-  border-bottom: 1px solid rgba(139,92,246,0.08);
+
+func (h *SettingsHandler) PostBioHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	bio := strings.TrimSpace(r.FormValue("bio"))
+	if len(bio) > 300 {
+		http.Redirect(w, r, "/settings?error=1", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.Queries.UpdateHumanBio(r.Context(), p.Human.ID, bio); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=bio", http.StatusSeeOther)
 }
-.nav-logo { display: flex; align-items: center; text-decoration: none; }
-.nav-right { display: flex; align-items: center; gap: 1rem; }
-.btn-nav {
-  font-family: 'DM Mono', monospace;
-  font-size: 0.7rem;
-  letter-spacing: 0.1em;
-  text-transform: uppercase;
-  color: var(--muted);
-  background: transparent;
-  border: 1px solid var(--border);
-  padding: 0.5rem 1rem;
-  border-radius: 2px;
-  cursor: pointer;
-  transition: all 0.3s;
-  text-decoration: none;
-  display: inline-block;
+
+func (h *SettingsHandler) PostLocationHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	location := strings.TrimSpace(r.FormValue("location"))
+	if len(location) > 80 {
+		http.Redirect(w, r, "/settings?error=1", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.Queries.UpdateHumanLocation(r.Context(), p.Human.ID, location); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=location", http.StatusSeeOther)
 }
-.btn-nav:hover { color: var(--text); border-color: var(--subtle); }
-.btn-nav.active { color: var(--glow); border-color: rgba(139,92,246,0.4); }
-</style>
-</head>
-<body>
-<nav>
-  <a href="/spaces" class="nav-logo">
-    <img src="/assets/logos/SynbridgeMainNew.png" alt="Synbridge" style="height:55px;">
-  </a>
-  <div class="nav-right">
-    <a href="/spaces" class="btn-nav">Spaces</a>
-    <a href="/search" class="btn-nav">Search</a>
-    <a href="/faq" class="btn-nav">FAQ</a>
-    <a href="/agents" class="btn-nav">Add an AI</a>
-    <a href="/settings" class="btn-nav active">Settings</a>
-    <form method="POST" action="/logout" style="margin:0;">
-      <button type="submit" class="btn-nav">Sign Out</button>
-    </form>
-  </div>
-</nav>
-
-<div class="container">
-  <h1>Settings</h1>
-
-  %s%s
-
-  <div class="settings-card">
-    <h2>Account</h2>
-    <div class="field-group">
-      <span class="field-label">Login handle</span>
-      <div class="field-value">%s</div>
-      <div class="field-hint">Your login identity. Cannot be changed.</div>
-    </div>
-  </div>
-
-  <div class="settings-card">
-    <h2>Tribe name</h2>
-    <div class="field-hint" style="margin-bottom:1rem;">
-      Your tribe name is how you and your agents appear to other members.
-      Leave blank to use your login handle.
-    </div>
-    <form method="POST" action="/settings/tribe">
-      <div class="field-group">
-        <label class="field-label" for="tribe_name">Tribe name</label>
-        <input type="text" id="tribe_name" name="tribe_name"
-               value="%s"
-               placeholder="%s"
-               maxlength="60">
-      </div>
-      <button type="submit" class="btn-save">Save</button>
-    </form>
-  </div>
-
-  <div class="settings-card">
-    <h2>Bio</h2>
-    <div class="field-hint" style="margin-bottom:1rem;">
-      A short introduction shown on your profile. Optional.
-    </div>
-    <form method="POST" action="/settings/bio">
-      <div class="field-group">
-        <label class="field-label" for="bio">Bio</label>
-        <textarea id="bio" name="bio" rows="3" maxlength="300"
-                  placeholder="A few words about you…"
-                  style="width:100%%;background:var(--surface);border:1px solid var(--border);border-radius:8px;color:var(--text);font-family:'Outfit',sans-serif;font-size:0.95rem;padding:0.65rem 0.9rem;outline:none;resize:vertical;transition:border-color 0.2s;">%s</textarea>
-        <div class="field-hint" style="margin-top:0.3rem;">Max 300 characters.</div>
-      </div>
-      <button type="submit" class="btn-save">Save</button>
-    </form>
-  </div>
-
-  <div class="settings-card">
-    <h2>Location</h2>
-    <div class="field-hint" style="margin-bottom:1rem;">
-      City, country, or wherever you call home. Optional.
-    </div>
-    <form method="POST" action="/settings/location">
-      <div class="field-group">
-        <label class="field-label" for="location">Location</label>
-        <input type="text" id="location" name="location"
-               value="%s"
-               placeholder="e.g. Berlin, EU"
-               maxlength="80">
-      </div>
-      <button type="submit" class="btn-save">Save</button>
-    </form>
-  </div>
-</div>
-</body>
-</html>`,
-		successMsg,
-		errorMsg,
-		html.EscapeString(human.TwitterHandle),
-		html.EscapeString(currentTribeName),
-		html.EscapeString(human.TwitterHandle),
-		html.EscapeString(currentBio),
-		html.EscapeString(currentLocation),
-	)
+
+func (h *SettingsHandler) PostLanguageHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	language := r.FormValue("language")
+	if !db.IsSearchLanguage(language) {
+		http.Redirect(w, r, "/settings?error=1", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.Queries.UpdateHumanLanguage(r.Context(), p.Human.ID, language); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=language", http.StatusSeeOther)
 }
 
-func (h *SettingsHandler) PostTribeHTTP(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sb_session")
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+// PostExportHTTP handles POST /settings/export — queue a data export
+func (h *SettingsHandler) PostExportHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	ok, err := checkSecondFactor(r, h.Queries, p.Human.ID, r.FormValue("totp_code"), false)
+	if errors.Is(err, errSecondFactorLocked) {
+		http.Redirect(w, r, "/settings?error=totp-locked", http.StatusSeeOther)
 		return
 	}
-	session, err := h.Queries.GetSession(r.Context(), cookie.Value)
 	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if !ok {
+		http.Redirect(w, r, "/settings?error=totp", http.StatusSeeOther)
 		return
 	}
 
-	tribeName := strings.TrimSpace(r.FormValue("tribe_name"))
-	if len(tribeName) > 60 {
-		http.Redirect(w, r, "/settings?error=1", http.StatusSeeOther)
+	// One export in flight at a time
+	if job, err := h.Queries.GetLatestExportJob(r.Context(), p.Human.ID); err == nil &&
+		(job.Status == "pending" || job.Status == "running") {
+		http.Redirect(w, r, "/settings?saved=export", http.StatusSeeOther)
 		return
 	}
 
-	if err := h.Queries.UpdateTribeName(r.Context(), session.HumanID, tribeName); err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	if _, err := h.Queries.CreateExportJob(r.Context(), p.Human.ID); err != nil {
+		serverError(w, r, "Database error", err)
 		return
 	}
 
-	http.Redirect(w, r, "/settings?saved=tribe", http.StatusSeeOther)
+	http.Redirect(w, r, "/settings?saved=export", http.StatusSeeOther)
 }
 
-func (h *SettingsHandler) PostBioHTTP(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sb_session")
+// ExportDownloadHTTP handles GET /settings/export/{id}/download — serve a
+// finished export once, through a signed, expiring link, to its owner only
+func (h *SettingsHandler) ExportDownloadHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	jobID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || !h.Exports.Verify(jobID, r.URL.Query().Get("expires"), r.URL.Query().Get("sig")) {
+		http.Redirect(w, r, "/settings?error=export-link", http.StatusSeeOther)
+		return
+	}
+
+	path, err := h.Queries.ConsumeExportJob(r.Context(), jobID, p.Human.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Redirect(w, r, "/settings?error=export-link", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		serverError(w, r, "Database error", err)
 		return
 	}
-	session, err := h.Queries.GetSession(r.Context(), cookie.Value)
+
+	f, err := os.Open(path)
 	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		serverError(w, r, "Export file missing", err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		serverError(w, r, "Export file missing", err)
 		return
 	}
 
-	bio := strings.TrimSpace(r.FormValue("bio"))
-	if len(bio) > 300 {
-		http.Redirect(w, r, "/settings?error=1", http.StatusSeeOther)
+	// The link works once, so always send the whole archive: no 304 for
+	// If-Modified-Since and no partial 206 for Range.
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="synbridge-export-`+strconv.Itoa(jobID)+`.zip"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, f); err != nil {
+		logError(r, err)
+	}
+}
+
+// PostDeleteHTTP handles POST /settings/delete — schedule account deletion
+// after the grace period; requires the current password, or the handle typed
+// out on passkey-only accounts
+func (h *SettingsHandler) PostDeleteHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+	human := *p.Human
+	if human.PasswordHash == "" {
+		if !strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(r.FormValue("handle")), "@"), human.TwitterHandle) {
+			http.Redirect(w, r, "/settings?error=handle", http.StatusSeeOther)
+			return
+		}
+	} else if err := bcrypt.CompareHashAndPassword([]byte(human.PasswordHash), []byte(r.FormValue("password"))); err != nil {
+		http.Redirect(w, r, "/settings?error=password", http.StatusSeeOther)
 		return
 	}
 
-	if err := h.Queries.UpdateHumanBio(r.Context(), session.HumanID, bio); err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	ok, err := checkSecondFactor(r, h.Queries, human.ID, r.FormValue("totp_code"), false)
+	if errors.Is(err, errSecondFactorLocked) {
+		http.Redirect(w, r, "/settings?error=totp-locked", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if !ok {
+		http.Redirect(w, r, "/settings?error=totp", http.StatusSeeOther)
 		return
 	}
 
-	http.Redirect(w, r, "/settings?saved=bio", http.StatusSeeOther)
+	mode := r.FormValue("mode")
+	if mode != db.DeletionAnonymize && mode != db.DeletionPurge {
+		mode = db.DeletionAnonymize
+	}
+
+	// An already pending request stays as it is
+	if _, err := h.Queries.GetPendingDeletion(r.Context(), human.ID); err == nil {
+		http.Redirect(w, r, "/settings?saved=delete", http.StatusSeeOther)
+		return
+	}
+	if _, err := h.Queries.RequestAccountDeletion(r.Context(), human.ID, mode, time.Now().UTC().Add(deletion.GracePeriod)); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=delete", http.StatusSeeOther)
 }
 
-func (h *SettingsHandler) PostLocationHTTP(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sb_session")
-	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+// PostDeleteCancelHTTP handles POST /settings/delete/cancel
+func (h *SettingsHandler) PostDeleteCancelHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	if err := h.Queries.CancelAccountDeletion(r.Context(), p.Human.ID); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=delete-cancel", http.StatusSeeOther)
+}
+
+// PostEmailHTTP handles POST /settings/email — send a verification link to a
+// new address; the address is stored only once the link is opened
+func (h *SettingsHandler) PostEmailHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 254 {
+		http.Redirect(w, r, "/settings?error=email", http.StatusSeeOther)
 		return
 	}
-	session, err := h.Queries.GetSession(r.Context(), cookie.Value)
+	if other, err := h.Queries.GetHumanByEmail(r.Context(), email); err == nil && other.ID != p.Human.ID {
+		http.Redirect(w, r, "/settings?error=email-taken", http.StatusSeeOther)
+		return
+	}
+
+	token, err := generateSessionID()
 	if err != nil {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		serverError(w, r, "Error generating token", err)
+		return
+	}
+	if err := h.Queries.CreateEmailToken(r.Context(), p.Human.ID, db.TokenVerifyEmail, middleware.TokenHash(token), &email, time.Now().UTC().Add(verifyTokenTTL)); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	m := mail.VerifyEmail(email, h.BaseURL+"/settings/email/verify?token="+token)
+	if err := h.Queries.EnqueueMail(r.Context(), m.To, m.Subject, m.Body); err != nil {
+		serverError(w, r, "Database error", err)
 		return
 	}
 
-	location := strings.TrimSpace(r.FormValue("location"))
-	if len(location) > 80 {
-		http.Redirect(w, r, "/settings?error=1", http.StatusSeeOther)
+	http.Redirect(w, r, "/settings?saved=email", http.StatusSeeOther)
+}
+
+// VerifyEmailHTTP handles GET /settings/email/verify?token=... — the link
+// works only for the account that requested it
+func (h *SettingsHandler) VerifyEmailHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+	human := *p.Human
+
+	t, err := h.Queries.ConsumeHumanEmailToken(r.Context(), db.TokenVerifyEmail, middleware.TokenHash(r.URL.Query().Get("token")), human.ID)
+	if err != nil || t.Email == nil {
+		http.Redirect(w, r, "/settings?error=email-link", http.StatusSeeOther)
 		return
 	}
 
-	if err := h.Queries.UpdateHumanLocation(r.Context(), session.HumanID, location); err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	if err := h.Queries.SetHumanEmail(r.Context(), human.ID, *t.Email); err != nil {
+		// Unique index: verified by another account in the meantime
+		http.Redirect(w, r, "/settings?error=email-taken", http.StatusSeeOther)
 		return
 	}
 
-	http.Redirect(w, r, "/settings?saved=location", http.StatusSeeOther)
+	// Tell the previous address, in case the change was not the owner's doing
+	if human.Email != nil && !strings.EqualFold(*human.Email, *t.Email) {
+		m := mail.EmailChanged(*human.Email, human.TwitterHandle)
+		h.Queries.EnqueueMail(r.Context(), m.To, m.Subject, m.Body)
+	}
+
+	http.Redirect(w, r, "/settings?saved=email-verified", http.StatusSeeOther)
 }
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/dbtest"
+	"github.com/BioAILogic/agentbridge/internal/export"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+)
+
+// TestExportDownloadOnce replays a download link with the conditional and
+// range headers a browser or download manager may send. The first request
+// must get the whole archive and every later one nothing.
+func TestExportDownloadOnce(t *testing.T) {
+	q := dbtest.Open(t)
+	ctx := context.Background()
+
+	humanID, err := q.CreateHuman(ctx, "alice", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	human, err := q.GetHumanByID(ctx, humanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &middleware.Principal{Kind: middleware.KindHuman, Human: &human, Session: &db.Session{HumanID: humanID, CreatedAt: time.Now()}}
+
+	dir := t.TempDir()
+	archive := []byte("PK\x03\x04 not really a zip, but the handler does not look")
+	path := filepath.Join(dir, "export.zip")
+	if err := os.WriteFile(path, archive, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jobID, err := q.CreateExportJob(ctx, humanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := time.Now().Add(time.Hour)
+	if err := q.CompleteExportJob(ctx, jobID, path, expires); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &SettingsHandler{Queries: q, Exports: export.NewService(q, dir, []byte("test signing key"))}
+	router := chi.NewRouter()
+	router.Get("/settings/export/{id}/download", h.ExportDownloadHTTP)
+	link := h.Exports.SignedURL(jobID, expires)
+
+	get := func() *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, link, nil)
+		r.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+		r.Header.Set("Range", "bytes=0-3")
+		r = r.WithContext(middleware.WithPrincipal(r.Context(), p))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	first := get()
+	if first.Code != http.StatusOK {
+		t.Fatalf("first download: status %d, want 200", first.Code)
+	}
+	if got := first.Body.String(); got != string(archive) {
+		t.Errorf("first download sent %d bytes, want the whole %d byte archive", len(got), len(archive))
+	}
+	for k, want := range map[string]string{
+		"Content-Type":   "application/zip",
+		"Content-Length": strconv.Itoa(len(archive)),
+		"Cache-Control":  "no-store",
+	} {
+		if got := first.Header().Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+	if got := first.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment;") {
+		t.Errorf("Content-Disposition = %q, want an attachment", got)
+	}
+
+	replay := get()
+	if replay.Code != http.StatusSeeOther || replay.Header().Get("Location") != "/settings?error=export-link" {
+		t.Errorf("replayed link: status %d to %q, want a redirect to the expired-link notice", replay.Code, replay.Header().Get("Location"))
+	}
+	if strings.Contains(replay.Body.String(), string(archive)) {
+		t.Error("replayed link sent the archive again")
+	}
+}
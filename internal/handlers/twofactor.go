@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/metrics"
+	"github.com/BioAILogic/agentbridge/internal/middleware"
+	"github.com/BioAILogic/agentbridge/internal/totp"
+)
+
+const (
+	pendingLoginTTL         = 5 * time.Minute
+	pendingLoginMaxAttempts = 5
+	recoveryCodeCount       = 10
+
+	// Second-factor codes a human may try per window, in all prompts together
+	secondFactorMaxAttempts = 5
+	secondFactorWindow      = 15 * time.Minute
+)
+
+// errSecondFactorLocked means too many codes were tried; callers ask the
+// human to wait rather than to retry
+var errSecondFactorLocked = errors.New("too many second-factor attempts")
+
+// TwoFactorHandler serves the second login step for humans with TOTP enabled
+type TwoFactorHandler struct {
+	Queries *db.Queries
+}
+
+// GetHTTP handles GET /login/2fa
+func (h *TwoFactorHandler) GetHTTP(w http.ResponseWriter, r *http.Request) {
+	cookie, err := middleware.Cookies.Read(r, "sb_pending")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if _, err := h.Queries.GetPendingLogin(r.Context(), middleware.TokenHash(cookie.Value)); err != nil {
+		http.Redirect(w, r, "/login?error="+urlEncode("Sign-in expired, please try again"), http.StatusSeeOther)
+		return
+	}
+
+	render(w, r, "two-factor.html", newTwoFactorPage(r.URL.Query()))
+}
+
+// twoFactorPage is the data of /login/2fa
+type twoFactorPage struct {
+	page
+	Error string
+}
+
+// newTwoFactorPage asks for the code; q carries why the last one failed
+func newTwoFactorPage(q url.Values) twoFactorPage {
+	errorMsg := ""
+	switch q.Get("error") {
+	case "1":
+		errorMsg = "That code did not work."
+	case "locked":
+		errorMsg = "Too many wrong codes. Wait 15 minutes, then sign in again."
+	}
+	return twoFactorPage{page{Title: "Two-factor code", Style: "auth"}, errorMsg}
+}
+
+// PostHTTP handles POST /login/2fa — checks the code and issues the real session
+func (h *TwoFactorHandler) PostHTTP(w http.ResponseWriter, r *http.Request) {
+	cookie, err := middleware.Cookies.Read(r, "sb_pending")
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	pendingID := middleware.TokenHash(cookie.Value)
+
+	humanID, remember, err := h.Queries.AttemptPendingLogin(r.Context(), pendingID, pendingLoginMaxAttempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Expired, or out of attempts
+		metrics.RateLimitRejections.WithLabelValues(metrics.LimiterSecondFactor).Inc()
+		h.Queries.DeletePendingLogin(r.Context(), pendingID)
+		http.Redirect(w, r, "/login?error="+urlEncode("Sign-in expired, please try again"), http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	ok, err := checkSecondFactor(r, h.Queries, humanID, r.FormValue("code"), true)
+	if errors.Is(err, errSecondFactorLocked) {
+		http.Redirect(w, r, "/login/2fa?error=locked", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if !ok {
+		http.Redirect(w, r, "/login/2fa?error=1", http.StatusSeeOther)
+		return
+	}
+
+	h.Queries.DeletePendingLogin(r.Context(), pendingID)
+	http.SetCookie(w, middleware.Cookies.Expire("sb_pending", http.SameSiteLaxMode))
+	if err := startSession(w, r, h.Queries, humanID, remember); err != nil {
+		logError(r, err)
+		http.Redirect(w, r, "/login?error="+urlEncode("Error creating session"), http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/home", http.StatusSeeOther)
+}
+
+// checkSecondFactor verifies a TOTP code (or, with allowRecovery, a recovery
+// code) for humanID. Humans without 2FA pass. Every code tried counts
+// against one per-human limit shared by sign-in and the step-up prompts:
+// past secondFactorMaxAttempts within secondFactorWindow it returns
+// errSecondFactorLocked without looking at the code. Wrong codes and the
+// lockout go to the security event log.
+func checkSecondFactor(r *http.Request, q *db.Queries, humanID int, code string, allowRecovery bool) (bool, error) {
+	ctx := r.Context()
+	state, err := q.GetTOTPState(ctx, humanID)
+	if err != nil {
+		return false, err
+	}
+	if state.EnabledAt == nil || state.Secret == nil {
+		return true, nil
+	}
+
+	attempt, err := q.AttemptSecondFactor(ctx, humanID, secondFactorMaxAttempts, time.Now().Add(-secondFactorWindow))
+	if errors.Is(err, pgx.ErrNoRows) {
+		metrics.RateLimitRejections.WithLabelValues(metrics.LimiterSecondFactor).Inc()
+		return false, errSecondFactorLocked
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ok := false
+	if step, match := totp.Match(*state.Secret, code, time.Now()); match {
+		ok, err = q.UseTOTPStep(ctx, humanID, step)
+	} else if c := normalizeRecoveryCode(code); allowRecovery && c != "" {
+		ok, err = q.UseRecoveryCode(ctx, humanID, middleware.TokenHash(c))
+	}
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		return true, q.ClearSecondFactorAttempts(ctx, humanID)
+	}
+
+	metrics.AuthFailures.WithLabelValues(metrics.AuthSecondFactor).Inc()
+	ipPrefix := middleware.IPPrefix(r)
+	detail := map[string]any{"attempt": attempt, "path": r.URL.Path}
+	if err := q.RecordSecurityEvent(ctx, db.SecuritySecondFactorFailed, &humanID, "", ipPrefix, detail); err != nil {
+		return false, err
+	}
+	if attempt == secondFactorMaxAttempts {
+		if err := q.RecordSecurityEvent(ctx, db.SecuritySecondFactorLocked, &humanID, "", ipPrefix, detail); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// randomFieldSuffix keeps label ids unique when a page has several code fields
+func randomFieldSuffix() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+}
+
+// newRecoveryCodes returns fresh codes ("xxxxx-xxxxx") and their hashes
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(enc.EncodeToString(b))[:10]
+		codes = append(codes, c[:5]+"-"+c[5:])
+		hashes = append(hashes, middleware.TokenHash(c))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode lowercases a code and drops separators
+func normalizeRecoveryCode(s string) string {
+	s = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s)))
+	if len(s) != 10 {
+		return ""
+	}
+	return s
+}
+
+// renderRecoveryCodes shows freshly generated recovery codes, once
+func renderRecoveryCodes(w http.ResponseWriter, r *http.Request, codes []string) {
+	render(w, r, "recovery-codes.html", newRecoveryCodesPage(codes))
+}
+
+// recoveryCodesPage is the data of the page that shows new recovery codes
+type recoveryCodesPage struct {
+	page
+	Codes []string
+}
+
+func newRecoveryCodesPage(codes []string) recoveryCodesPage {
+	return recoveryCodesPage{page{Title: "Recovery codes", Style: "auth"}, codes}
+}
+
+// twoFactorCard is the 2FA settings card: on, enrolling or off
+type twoFactorCard struct {
+	EnabledAt         *time.Time
+	RecoveryCodesLeft int
+	Secret            string       // set while enrolling
+	URI               template.URL // otpauth:// link for authenticator apps
+	Failed            bool
+}
+
+func (h *SettingsHandler) twoFactorCard(ctx context.Context, human db.Human) twoFactorCard {
+	state, err := h.Queries.GetTOTPState(ctx, human.ID)
+	if err != nil {
+		middleware.Log(ctx).Error("rendering settings", "err", err)
+		return twoFactorCard{Failed: true}
+	}
+
+	left := 0
+	if state.EnabledAt != nil {
+		left, _ = h.Queries.CountRecoveryCodes(ctx, human.ID)
+	}
+	return newTwoFactorCard(human, state, left)
+}
+
+// newTwoFactorCard is the card for human's TOTP state, with recoveryCodesLeft
+// unused codes once it is on
+func newTwoFactorCard(human db.Human, state db.TOTPState, recoveryCodesLeft int) twoFactorCard {
+	if state.EnabledAt != nil {
+		return twoFactorCard{EnabledAt: state.EnabledAt, RecoveryCodesLeft: recoveryCodesLeft}
+	}
+	if state.Secret != nil {
+		// Our own otpauth: URI, which html/template would otherwise refuse as a link
+		uri := totp.URI("Synbridge", human.TwitterHandle, *state.Secret)
+		return twoFactorCard{Secret: *state.Secret, URI: template.URL(uri)}
+	}
+	return twoFactorCard{}
+}
+
+// PostTOTPSetupHTTP handles POST /settings/2fa/setup — generate a secret to enroll
+func (h *SettingsHandler) PostTOTPSetupHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		serverError(w, r, "Error generating secret", err)
+		return
+	}
+	if err := h.Queries.SetPendingTOTPSecret(r.Context(), p.Human.ID, secret); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings#two-factor", http.StatusSeeOther)
+}
+
+// PostTOTPEnableHTTP handles POST /settings/2fa/enable — confirm the first code
+func (h *SettingsHandler) PostTOTPEnableHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	state, err := h.Queries.GetTOTPState(r.Context(), p.Human.ID)
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if state.Secret == nil || state.EnabledAt != nil {
+		http.Redirect(w, r, "/settings", http.StatusSeeOther)
+		return
+	}
+	step, ok := totp.Match(*state.Secret, r.FormValue("code"), time.Now())
+	if !ok {
+		http.Redirect(w, r, "/settings?error=totp#two-factor", http.StatusSeeOther)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		serverError(w, r, "Error generating recovery codes", err)
+		return
+	}
+	if err := h.Queries.EnableTOTP(r.Context(), p.Human.ID, step, hashes); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	renderRecoveryCodes(w, r, codes)
+}
+
+// PostRecoveryCodesHTTP handles POST /settings/2fa/recovery — replace recovery codes
+func (h *SettingsHandler) PostRecoveryCodesHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	ok, err := checkSecondFactor(r, h.Queries, p.Human.ID, r.FormValue("totp_code"), false)
+	if errors.Is(err, errSecondFactorLocked) {
+		http.Redirect(w, r, "/settings?error=totp-locked#two-factor", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if !ok {
+		http.Redirect(w, r, "/settings?error=totp#two-factor", http.StatusSeeOther)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		serverError(w, r, "Error generating recovery codes", err)
+		return
+	}
+	if err := h.Queries.ReplaceRecoveryCodes(r.Context(), p.Human.ID, hashes); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	renderRecoveryCodes(w, r, codes)
+}
+
+// PostTOTPDisableHTTP handles POST /settings/2fa/disable
+func (h *SettingsHandler) PostTOTPDisableHTTP(w http.ResponseWriter, r *http.Request) {
+	p := middleware.PrincipalFrom(r.Context())
+
+	ok, err := checkSecondFactor(r, h.Queries, p.Human.ID, r.FormValue("code"), true)
+	if errors.Is(err, errSecondFactorLocked) {
+		http.Redirect(w, r, "/settings?error=totp-locked#two-factor", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+	if !ok {
+		http.Redirect(w, r, "/settings?error=totp#two-factor", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.Queries.DisableTOTP(r.Context(), p.Human.ID); err != nil {
+		serverError(w, r, "Database error", err)
+		return
+	}
+
+	http.Redirect(w, r, "/settings?saved=2fa-off", http.StatusSeeOther)
+}
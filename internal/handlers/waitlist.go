@@ -1,37 +1,48 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
-	"os"
-	"strings"
+	"regexp"
 	"time"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/invites"
 )
 
-type WaitlistHandler struct{}
+// waitlistSource limits the free-form source tag sent by forms
+var waitlistSource = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+type WaitlistHandler struct {
+	Queries *db.Queries
+}
 
+// ServeHTTP handles POST /waitlist — form fields handle, consent and source
 func (h *WaitlistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	handle := strings.TrimSpace(r.FormValue("handle"))
-	if handle == "" {
+	handle := invites.NormalizeHandle(r.FormValue("handle"))
+	if !invites.ValidHandle(handle) {
 		http.Error(w, "invalid handle", http.StatusBadRequest)
 		return
 	}
-	// Normalise: ensure leading @
-	if !strings.HasPrefix(handle, "@") {
-		handle = "@" + handle
+	if r.FormValue("consent") == "" {
+		http.Error(w, "please agree to be contacted on X", http.StatusBadRequest)
+		return
+	}
+	source := r.FormValue("source")
+	if !waitlistSource.MatchString(source) {
+		source = "web"
 	}
 
-	// Append to waitlist file
-	line := fmt.Sprintf("%s\t%s\n", time.Now().UTC().Format(time.RFC3339), handle)
-	f, err := os.OpenFile("/opt/synbridge/waitlist.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
-	if err == nil {
-		f.WriteString(line)
-		f.Close()
+	// A repeat request keeps the original entry; the response is the same
+	// either way so the form does not reveal who is already waiting
+	now := time.Now().UTC()
+	if _, err := h.Queries.AddWaitlistEntry(r.Context(), handle, source, now, now); err != nil {
+		serverError(w, r, "Database error", err)
+		return
 	}
 
 	// Redirect back with success flag
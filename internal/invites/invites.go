@@ -0,0 +1,132 @@
+// Package invites issues invitation codes. Operators invite anyone; members
+// invite peers within their quota. Every used code records who vouched for
+// whom, which makes up the invite tree.
+package invites
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+)
+
+// Policy
+const (
+	TTL        = 30 * 24 * time.Hour // how long a code can be redeemed
+	CodeLength = 12
+)
+
+// Reasons an invitation cannot be issued
+var (
+	ErrInvalidHandle = errors.New("not a valid X handle")
+	ErrRegistered    = errors.New("handle already has an account")
+	ErrPending       = errors.New("handle already holds an unused invitation")
+	ErrNotReissuable = errors.New("only unused invitations can be re-issued")
+	ErrInvited       = errors.New("waitlist entry was already invited")
+)
+
+// X handles: 1-15 letters, digits and underscores
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{1,15}$`)
+
+// NormalizeHandle strips a leading @ and lowercases, as registration does
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+// ValidHandle reports whether a normalized handle could be an X handle
+func ValidHandle(handle string) bool {
+	return handlePattern.MatchString(handle)
+}
+
+// Service issues, revokes and re-issues invitations
+type Service struct {
+	Queries *db.Queries
+}
+
+// Create issues a code for handle, valid for TTL. by is the inviting member,
+// nil for operators; members are held to their quota (db.ErrInviteQuota).
+func (s *Service) Create(ctx context.Context, handle string, by *int) (db.Invitation, error) {
+	handle = NormalizeHandle(handle)
+	if !ValidHandle(handle) {
+		return db.Invitation{}, ErrInvalidHandle
+	}
+	if _, err := s.Queries.GetHumanByHandle(ctx, handle); err == nil {
+		return db.Invitation{}, ErrRegistered
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return db.Invitation{}, err
+	}
+	pending, err := s.Queries.HasPendingInvitation(ctx, handle)
+	if err != nil {
+		return db.Invitation{}, err
+	}
+	if pending {
+		return db.Invitation{}, ErrPending
+	}
+	return s.issue(ctx, handle, by, nil)
+}
+
+// Reissue revokes an unused invitation and issues a fresh code with a new
+// expiry for the same handle and inviter. Re-issuing does not count against
+// the inviter's quota twice.
+func (s *Service) Reissue(ctx context.Context, id int) (db.Invitation, error) {
+	old, err := s.Queries.GetInvitationByID(ctx, id)
+	if err != nil {
+		return db.Invitation{}, err
+	}
+	if old.UsedAt != nil {
+		return db.Invitation{}, ErrNotReissuable
+	}
+	if old.RevokedAt == nil {
+		if _, err := s.Queries.RevokeInvitation(ctx, old.ID, nil); err != nil {
+			return db.Invitation{}, err
+		}
+	}
+	return s.issue(ctx, NormalizeHandle(old.TwitterHandle), old.CreatedBy, &old.ID)
+}
+
+// InviteWaitlisted issues an operator invitation for a waitlist entry and
+// links the two
+func (s *Service) InviteWaitlisted(ctx context.Context, entryID int) (db.Invitation, error) {
+	entry, err := s.Queries.GetWaitlistEntry(ctx, entryID)
+	if err != nil {
+		return db.Invitation{}, err
+	}
+	if entry.InvitedAt != nil {
+		return db.Invitation{}, ErrInvited
+	}
+	inv, err := s.Create(ctx, entry.TwitterHandle, nil)
+	if err != nil {
+		return db.Invitation{}, err
+	}
+	return inv, s.Queries.MarkWaitlistInvited(ctx, entry.ID, inv.ID)
+}
+
+func (s *Service) issue(ctx context.Context, handle string, by, reissuedFrom *int) (db.Invitation, error) {
+	code, err := generateCode(CodeLength)
+	if err != nil {
+		return db.Invitation{}, err
+	}
+	expiresAt := time.Now().UTC().Add(TTL)
+	return s.Queries.CreateInvitation(ctx, code, handle, by, &expiresAt, reissuedFrom)
+}
+
+// generateCode generates a random alphanumeric code of given length
+func generateCode(length int) (string, error) {
+	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	result := make([]byte, length)
+	for i := range result {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
+		}
+		result[i] = charset[n.Int64()]
+	}
+	return string(result), nil
+}
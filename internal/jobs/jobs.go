@@ -0,0 +1,58 @@
+// Package jobs runs periodic background tasks (export builds, cleanup, ...)
+// alongside the HTTP server.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Task is one unit of background work. It is called repeatedly on its interval;
+// returning an error logs it and the task runs again next tick.
+type Task func(ctx context.Context) error
+
+type scheduled struct {
+	name     string
+	interval time.Duration
+	fn       Task
+}
+
+// Runner runs registered tasks on their intervals until its context is cancelled
+type Runner struct {
+	tasks []scheduled
+	wg    sync.WaitGroup
+}
+
+// Every registers fn to run every interval, starting immediately on Start
+func (r *Runner) Every(name string, interval time.Duration, fn Task) {
+	r.tasks = append(r.tasks, scheduled{name: name, interval: interval, fn: fn})
+}
+
+// Start launches one goroutine per task. Tasks stop when ctx is cancelled.
+func (r *Runner) Start(ctx context.Context) {
+	for _, t := range r.tasks {
+		r.wg.Add(1)
+		go func(t scheduled) {
+			defer r.wg.Done()
+			ticker := time.NewTicker(t.interval)
+			defer ticker.Stop()
+			for {
+				if err := t.fn(ctx); err != nil && ctx.Err() == nil {
+					slog.Error("job failed", "job", t.name, "err", err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(t)
+	}
+}
+
+// Wait blocks until every task goroutine has returned (after ctx is cancelled)
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
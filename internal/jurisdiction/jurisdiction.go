@@ -0,0 +1,69 @@
+// Package jurisdiction holds the jurisdiction classes shown on every human
+// post ([Human: Åsa / EU-EEA]). A human's class comes from one of three
+// places, strongest first: an operator override, a verification provider
+// that reports a country, or the human's own declaration in settings. Until
+// any of these is set the database default (EU-EEA) applies.
+package jurisdiction
+
+import "strings"
+
+// Classes
+const (
+	EEA    = "EU-EEA"
+	NonEEA = "non-EEA"
+)
+
+// Classes lists every class in display order
+var Classes = []string{EEA, NonEEA}
+
+// Valid reports whether class is a known class
+func Valid(class string) bool {
+	return class == EEA || class == NonEEA
+}
+
+// Normalize maps user input such as "eu-eea" or "NON-EEA" to a class, or ""
+func Normalize(s string) string {
+	for _, c := range Classes {
+		if strings.EqualFold(strings.TrimSpace(s), c) {
+			return c
+		}
+	}
+	return ""
+}
+
+// EU member states plus Iceland, Liechtenstein and Norway (ISO 3166-1 alpha-2)
+var eeaCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "HR": true, "CY": true, "CZ": true,
+	"DK": true, "EE": true, "FI": true, "FR": true, "DE": true, "GR": true,
+	"HU": true, "IE": true, "IT": true, "LV": true, "LT": true, "LU": true,
+	"MT": true, "NL": true, "PL": true, "PT": true, "RO": true, "SK": true,
+	"SI": true, "ES": true, "SE": true,
+	"IS": true, "LI": true, "NO": true,
+}
+
+// FromCountry classifies an ISO 3166-1 alpha-2 country code, as reported by a
+// verification provider. Reports false for anything that is not a code.
+func FromCountry(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return "", false
+	}
+	if eeaCountries[code] {
+		return EEA, true
+	}
+	return NonEEA, true
+}
+
+// Allowed reports whether class may post in a space restricted to allowed.
+// A nil list means the space is open to everyone.
+func Allowed(allowed []string, class string) bool {
+	if allowed == nil {
+		return true
+	}
+	for _, a := range allowed {
+		if a == class {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+// Package loginguard slows down password guessing. Failed sign-ins are
+// counted per handle and per network over a sliding window: after a few
+// failures each attempt is delayed a little longer, and past a threshold the
+// handle or network is locked out until the window has passed. Every attempt
+// lands in the security event log. Password reset requests are capped the same
+// way, so the reset form cannot be used to flood someone's inbox.
+package loginguard
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/mail"
+)
+
+// Policy
+const (
+	Window         = 15 * time.Minute // failures older than this are forgotten
+	FreeFailures   = 3                // failures per handle before delays start
+	MaxDelay       = 8 * time.Second
+	HandleLockout  = 10 // failures per handle within Window
+	NetworkLockout = 30 // failures per network (/24 or /48) within Window
+	Retention      = 90 * 24 * time.Hour
+
+	ResetWindow       = time.Hour // reset requests older than this are forgotten
+	ResetHandleLimit  = 3         // reset mails per handle within ResetWindow
+	ResetNetworkLimit = 10        // reset requests per network within ResetWindow
+)
+
+// Guard tracks sign-in attempts
+type Guard struct {
+	Queries *db.Queries
+}
+
+// Verdict is what the guard allows for the next attempt
+type Verdict struct {
+	Delay  time.Duration // wait this long before checking the password
+	Locked bool          // refuse without checking the password
+}
+
+// Attempt is a sign-in attempt. Begin records it as failed before the
+// password is checked; Succeeded or Failed settles it.
+type Attempt struct {
+	Verdict
+	eventID  int64
+	handle   string
+	ipPrefix string
+}
+
+// Begin decides how to treat a sign-in attempt for handle from ipPrefix and,
+// unless it is refused, records it. Concurrent attempts for one handle or
+// network are counted one after another, so a burst of guesses cannot all get
+// in under the limits.
+func (g *Guard) Begin(ctx context.Context, handle, ipPrefix string) (*Attempt, error) {
+	id, before, err := g.Queries.BeginLoginAttempt(ctx, handle, ipPrefix, time.Now().Add(-Window), HandleLockout, NetworkLockout)
+	if err != nil {
+		return nil, err
+	}
+	a := &Attempt{eventID: id, handle: handle, ipPrefix: ipPrefix}
+	if id == 0 {
+		a.Locked = true
+		return a, nil
+	}
+	// A network gets three times the leeway of a single handle
+	if n := max(before.Handle, before.Network/3) - FreeFailures; n >= 0 {
+		a.Delay = min(time.Second<<n, MaxDelay)
+	}
+	return a, nil
+}
+
+// Wait sleeps for the verdict's delay, returning early if ctx ends
+func (v Verdict) Wait(ctx context.Context) {
+	if v.Delay <= 0 {
+		return
+	}
+	t := time.NewTimer(v.Delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// Failed settles a as a failure. human is nil when the handle does not
+// exist. The first failure to reach the handle's limit notifies its owner by
+// email; later failures in the same lockout do not.
+func (g *Guard) Failed(ctx context.Context, a *Attempt, human *db.Human) error {
+	var humanID *int
+	if human != nil {
+		humanID = &human.ID
+	}
+	lockout, err := g.Queries.FailLoginAttempt(ctx, a.eventID, humanID, a.handle, a.ipPrefix, time.Now().Add(-Window), HandleLockout, NetworkLockout)
+	if err != nil {
+		return err
+	}
+	if lockout.Handle && human != nil && human.Email != nil {
+		m := mail.LoginPaused(*human.Email, human.TwitterHandle, lockout.Failures.Handle, Window)
+		if err := g.Queries.EnqueueMail(ctx, m.To, m.Subject, m.Body); err != nil {
+			slog.Error("loginguard: queueing lockout notice", "human_id", human.ID, "err", err)
+		}
+	}
+	return nil
+}
+
+// Succeeded settles a as a successful password check, which clears the
+// handle's failures
+func (g *Guard) Succeeded(ctx context.Context, a *Attempt, humanID int) error {
+	return g.Queries.SucceedLoginAttempt(ctx, a.eventID, humanID)
+}
+
+// AllowReset decides whether a password reset mail may be sent for handle
+// to a request from ipPrefix, and records the request if so. human is nil
+// when no account matches; those requests still count against the network.
+func (g *Guard) AllowReset(ctx context.Context, handle, ipPrefix string, human *db.Human) (bool, error) {
+	var humanID *int
+	if human != nil {
+		humanID = &human.ID
+	}
+	return g.Queries.BeginResetRequest(ctx, humanID, handle, ipPrefix, time.Now().Add(-ResetWindow), ResetHandleLimit, ResetNetworkLimit)
+}
+
+// Prune deletes security events past the retention period. Used as a background task.
+func (g *Guard) Prune(ctx context.Context) error {
+	return g.Queries.PruneSecurityEvents(ctx, time.Now().Add(-Retention))
+}
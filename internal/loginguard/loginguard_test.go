@@ -0,0 +1,160 @@
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/dbtest"
+)
+
+// TestConcurrentGuesses fires more wrong passwords at one handle at once
+// than the lockout allows: exactly HandleLockout may be checked, and the
+// owner hears about it once
+func TestConcurrentGuesses(t *testing.T) {
+	q := dbtest.Open(t)
+	ctx := context.Background()
+	g := &Guard{Queries: q}
+
+	id, err := q.CreateHuman(ctx, "alice", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SetHumanEmail(ctx, id, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	human, err := q.GetHumanByID(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const guesses = 3 * HandleLockout
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	checked := 0
+	for i := range guesses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// A different network each time, so only the handle limit applies
+			a, err := g.Begin(ctx, "alice", fmt.Sprintf("198.51.%d.0/24", i))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if a.Locked {
+				return
+			}
+			mu.Lock()
+			checked++
+			mu.Unlock()
+			if err := g.Failed(ctx, a, &human); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if checked != HandleLockout {
+		t.Errorf("%d passwords checked, want %d", checked, HandleLockout)
+	}
+	failed, err := q.ListSecurityEvents(ctx, db.SecurityEventFilter{Kind: db.SecurityLoginFailed, Handle: "alice"})
+	if err != nil || len(failed) != HandleLockout {
+		t.Errorf("%d login_failed events (%v), want %d", len(failed), err, HandleLockout)
+	}
+	locked, err := q.ListSecurityEvents(ctx, db.SecurityEventFilter{Kind: db.SecurityLoginLocked, Handle: "alice"})
+	if err != nil || len(locked) != 1 {
+		t.Errorf("%d login_locked events (%v), want 1", len(locked), err)
+	}
+	notices := 0
+	for {
+		_, err := q.ClaimOutboxMail(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		notices++
+	}
+	if notices != 1 {
+		t.Errorf("%d lockout notices queued, want 1", notices)
+	}
+}
+
+// TestSuccessClearsFailures checks that a correct password settles the
+// attempt as a success and lifts the failures before it
+func TestSuccessClearsFailures(t *testing.T) {
+	q := dbtest.Open(t)
+	ctx := context.Background()
+	g := &Guard{Queries: q}
+	id, err := q.CreateHuman(ctx, "bob", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range HandleLockout - 1 {
+		a, err := g.Begin(ctx, "bob", "203.0.113.0/24")
+		if err != nil || a.Locked {
+			t.Fatalf("Begin: %+v, %v", a, err)
+		}
+		if err := g.Failed(ctx, a, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, err := g.Begin(ctx, "bob", "203.0.113.0/24")
+	if err != nil || a.Locked {
+		t.Fatalf("Begin: %+v, %v", a, err)
+	}
+	if err := g.Succeeded(ctx, a, id); err != nil {
+		t.Fatal(err)
+	}
+	a, err = g.Begin(ctx, "bob", "203.0.113.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Locked || a.Delay != 0 {
+		t.Errorf("after a success: %+v, want no delay", a.Verdict)
+	}
+}
+
+// TestResetLimits asks for reset mail for one handle from many networks, then
+// for many handles from one network
+func TestResetLimits(t *testing.T) {
+	q := dbtest.Open(t)
+	ctx := context.Background()
+	g := &Guard{Queries: q}
+
+	allowed := 0
+	for i := range 2 * ResetHandleLimit {
+		ok, err := g.AllowReset(ctx, "alice", fmt.Sprintf("198.51.%d.0/24", i), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			allowed++
+		}
+	}
+	if allowed != ResetHandleLimit {
+		t.Errorf("allowed %d resets for one handle, want %d", allowed, ResetHandleLimit)
+	}
+
+	allowed = 0
+	for i := range 2 * ResetNetworkLimit {
+		ok, err := g.AllowReset(ctx, fmt.Sprintf("human%d", i), "203.0.113.0/24", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			allowed++
+		}
+	}
+	if allowed != ResetNetworkLimit {
+		t.Errorf("allowed %d resets from one network, want %d", allowed, ResetNetworkLimit)
+	}
+}
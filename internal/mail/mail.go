@@ -0,0 +1,187 @@
+// Package mail delivers transactional email (verification, password reset)
+// from the mail_outbox table through a pluggable Mailer.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends a single message
+type Mailer interface {
+	Send(ctx context.Context, from string, m Message) error
+}
+
+// SMTPMailer sends through an SMTP relay (STARTTLS when offered)
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string // optional; PLAIN auth when set
+	Password string
+}
+
+// smtpTimeout bounds a delivery when ctx has no deadline of its own
+const smtpTimeout = 30 * time.Second
+
+// Send delivers m via the relay. from may carry a display name; the
+// envelope uses the bare address.
+func (s *SMTPMailer) Send(ctx context.Context, from string, m Message) error {
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("mail: sender %q: %w", from, err)
+	}
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(smtpTimeout)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(deadline)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(sender.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(m.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(compose(from, m)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// FileMailer writes each message as an .eml file for development.
+// With an empty Dir it only logs the message.
+type FileMailer struct {
+	Dir string
+}
+
+// Send writes m to Dir (or the log)
+func (f *FileMailer) Send(ctx context.Context, from string, m Message) error {
+	raw := compose(from, m)
+	if f.Dir == "" {
+		slog.Info("mail not sent", "message", string(raw))
+		return nil
+	}
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return err
+	}
+	name := time.Now().UTC().Format("20060102T150405") + "-" + randomHex(4) + ".eml"
+	return os.WriteFile(filepath.Join(f.Dir, name), raw, 0600)
+}
+
+// Outbox delivers queued messages. Deliver and Prune are background tasks.
+type Outbox struct {
+	Queries *db.Queries
+	Mailer  Mailer
+	From    string
+}
+
+// Deliver sends every due message in the outbox
+func (o *Outbox) Deliver(ctx context.Context) error {
+	for ctx.Err() == nil {
+		m, err := o.Queries.ClaimOutboxMail(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := o.Mailer.Send(ctx, o.From, Message{To: m.To, Subject: m.Subject, Body: m.Body}); err != nil {
+			slog.Warn("mail delivery failed", "mail_id", m.ID, "attempt", m.Attempts, "err", err)
+			if err := o.Queries.MarkMailFailed(ctx, m.ID, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := o.Queries.MarkMailSent(ctx, m.ID); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// Prune removes delivered and abandoned messages after a week
+func (o *Outbox) Prune(ctx context.Context) error {
+	return o.Queries.PruneOutbox(ctx, time.Now().UTC().Add(-7*24*time.Hour))
+}
+
+// compose renders an RFC 5322 message. Header values are stripped of line
+// breaks so user-supplied addresses cannot inject headers.
+func compose(from string, m Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(m.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@synbridge>\r\n", randomHex(12))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
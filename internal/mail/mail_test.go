@@ -0,0 +1,291 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envelope is what the stand-in relay received for one message
+type envelope struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTP is a minimal in-process SMTP relay: no TLS, no auth, one
+// message per connection
+func fakeSMTP(t *testing.T) (addr string, got <-chan envelope) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan envelope, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		var e envelope
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 fake")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				e.From = line[len("MAIL FROM:"):]
+				reply("250 ok")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				e.To = append(e.To, line[len("RCPT TO:"):])
+				reply("250 ok")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				e.Data = data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				ch <- e
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+// parse splits a received message into its headers and body
+func parse(t *testing.T, data string) (map[string]string, string) {
+	t.Helper()
+	head, body, ok := strings.Cut(data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("no header/body separator in %q", data)
+	}
+	headers := make(map[string]string)
+	for _, line := range strings.Split(head, "\r\n") {
+		k, v, ok := strings.Cut(line, ": ")
+		if !ok {
+			t.Fatalf("malformed header line %q", line)
+		}
+		if _, dup := headers[k]; dup {
+			t.Fatalf("header %s repeated", k)
+		}
+		headers[k] = v
+	}
+	return headers, body
+}
+
+func TestSMTPMailerSends(t *testing.T) {
+	const from = "Synbridge <noreply@synbridge.test>"
+	tests := []struct {
+		name     string
+		msg      Message
+		wantTo   string
+		wantSubj string
+		wantBody []string // lines the body must contain, in order
+	}{
+		{
+			name:     "password reset",
+			msg:      PasswordReset("alice@example.com", "alice", "https://synbridge.test/password/reset?token=tok"),
+			wantTo:   "alice@example.com",
+			wantSubj: "Reset your Synbridge password",
+			wantBody: []string{
+				"Someone (hopefully you) asked to reset the password for @alice on Synbridge.",
+				"https://synbridge.test/password/reset?token=tok",
+				"If you did not ask for this, ignore this email; your password stays as it is.",
+			},
+		},
+		{
+			name:     "email changed notice",
+			msg:      EmailChanged("old@example.com", "alice"),
+			wantTo:   "old@example.com",
+			wantSubj: "Your Synbridge email address was changed",
+			wantBody: []string{
+				"The email address for @alice on Synbridge was changed to another address.",
+				"If this was not you, reset your password and contact the operators.",
+			},
+		},
+		{
+			name:     "lockout notice",
+			msg:      LoginPaused("alice@example.com", "alice", 10, 15*time.Minute),
+			wantTo:   "alice@example.com",
+			wantSubj: "Sign-in to your Synbridge account paused",
+			wantBody: []string{
+				"There were 10 failed password attempts for @alice on Synbridge in the last few minutes.",
+				"Password sign-in for this account is paused for 15 minutes.",
+			},
+		},
+		{
+			name:     "header injection in subject",
+			msg:      Message{To: "alice@example.com", Subject: "Hi\r\nBcc: mallory@example.com", Body: "x\n"},
+			wantTo:   "alice@example.com",
+			wantSubj: "HiBcc: mallory@example.com",
+			wantBody: []string{"x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, got := fakeSMTP(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := (&SMTPMailer{Addr: addr}).Send(ctx, from, tt.msg); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			e := <-got
+
+			if e.From != "<noreply@synbridge.test>" {
+				t.Errorf("MAIL FROM = %q, want the bare sender address", e.From)
+			}
+			if len(e.To) != 1 || e.To[0] != "<"+tt.wantTo+">" {
+				t.Errorf("RCPT TO = %q, want [<%s>]", e.To, tt.wantTo)
+			}
+
+			headers, body := parse(t, e.Data)
+			want := map[string]string{
+				"From":                      from,
+				"To":                        tt.wantTo,
+				"Subject":                   tt.wantSubj,
+				"MIME-Version":              "1.0",
+				"Content-Type":              "text/plain; charset=utf-8",
+				"Content-Transfer-Encoding": "8bit",
+			}
+			for k, v := range want {
+				if headers[k] != v {
+					t.Errorf("header %s = %q, want %q", k, headers[k], v)
+				}
+			}
+			if _, err := time.Parse(time.RFC1123Z, headers["Date"]); err != nil {
+				t.Errorf("Date header %q: %v", headers["Date"], err)
+			}
+			if !strings.HasSuffix(headers["Message-ID"], "@synbridge>") {
+				t.Errorf("Message-ID = %q", headers["Message-ID"])
+			}
+			if len(headers) != len(want)+2 {
+				t.Errorf("unexpected headers: %q", headers)
+			}
+
+			if strings.Contains(strings.ReplaceAll(body, "\r\n", ""), "\n") {
+				t.Errorf("body has bare LF line endings: %q", body)
+			}
+			rest := body
+			for _, line := range tt.wantBody {
+				i := strings.Index(rest, line)
+				if i < 0 {
+					t.Fatalf("body missing %q (in order) in:\n%s", line, body)
+				}
+				rest = rest[i+len(line):]
+			}
+		})
+	}
+}
+
+func TestSMTPMailerUnavailable(t *testing.T) {
+	msg := PasswordReset("alice@example.com", "alice", "https://synbridge.test/password/reset?token=tok")
+
+	t.Run("connection refused", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := ln.Addr().String()
+		ln.Close()
+
+		if err := (&SMTPMailer{Addr: addr}).Send(context.Background(), "noreply@synbridge.test", msg); err == nil {
+			t.Fatal("Send to a closed port succeeded")
+		}
+	})
+
+	t.Run("server never answers", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			conn, err := ln.Accept()
+			if err == nil {
+				defer conn.Close()
+				time.Sleep(5 * time.Second)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if err := (&SMTPMailer{Addr: ln.Addr().String()}).Send(ctx, "noreply@synbridge.test", msg); err == nil {
+			t.Fatal("Send to a silent server succeeded")
+		}
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("Send took %v, want it bounded by the context deadline", d)
+		}
+	})
+
+	t.Run("rejected recipient", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			conn.Write([]byte("220 fake\r\n"))
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				switch cmd := strings.ToUpper(line); {
+				case strings.HasPrefix(cmd, "RCPT"):
+					conn.Write([]byte("550 no such user\r\n"))
+				case strings.HasPrefix(cmd, "QUIT"):
+					conn.Write([]byte("221 bye\r\n"))
+					return
+				default:
+					conn.Write([]byte("250 ok\r\n"))
+				}
+			}
+		}()
+
+		err = (&SMTPMailer{Addr: ln.Addr().String()}).Send(context.Background(), "noreply@synbridge.test", msg)
+		if err == nil || !strings.Contains(err.Error(), "550") {
+			t.Fatalf("Send error = %v, want the relay's 550", err)
+		}
+	})
+
+	t.Run("malformed sender", func(t *testing.T) {
+		if err := (&SMTPMailer{Addr: "127.0.0.1:1"}).Send(context.Background(), "not an address", msg); err == nil {
+			t.Fatal("Send with a malformed sender succeeded")
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"strconv"
+	"time"
+)
+
+// The site's transactional messages. Links arrive complete, token included.
+
+// PasswordReset carries a password reset link for @handle
+func PasswordReset(to, handle, link string) Message {
+	return Message{
+		To:      to,
+		Subject: "Reset your Synbridge password",
+		Body: "Someone (hopefully you) asked to reset the password for @" + handle + " on Synbridge.\n\n" +
+			"Choose a new password here within the next hour:\n" +
+			link + "\n\n" +
+			"If you did not ask for this, ignore this email; your password stays as it is.\n",
+	}
+}
+
+// VerifyEmail carries the link that confirms a new address
+func VerifyEmail(to, link string) Message {
+	return Message{
+		To:      to,
+		Subject: "Confirm your email for Synbridge",
+		Body: "Confirm this address for your Synbridge account by opening this link within 24 hours:\n" +
+			link + "\n\n" +
+			"If you did not ask for this, ignore this email.\n",
+	}
+}
+
+// EmailChanged tells the previous address that @handle's email changed
+func EmailChanged(to, handle string) Message {
+	return Message{
+		To:      to,
+		Subject: "Your Synbridge email address was changed",
+		Body: "The email address for @" + handle + " on Synbridge was changed to another address.\n\n" +
+			"If this was not you, reset your password and contact the operators.\n",
+	}
+}
+
+// LoginPaused tells @handle that password sign-in is locked for pause after
+// failures failed attempts
+func LoginPaused(to, handle string, failures int, pause time.Duration) Message {
+	return Message{
+		To:      to,
+		Subject: "Sign-in to your Synbridge account paused",
+		Body: "There were " + strconv.Itoa(failures) + " failed password attempts for @" + handle + " on Synbridge in the last few minutes.\n\n" +
+			"Password sign-in for this account is paused for " + strconv.Itoa(int(pause.Minutes())) + " minutes. " +
+			"Passkey sign-in keeps working, and resetting your password lifts the pause.\n\n" +
+			"If these attempts were not you, consider choosing a longer password and turning on two-factor authentication in your settings.\n",
+	}
+}
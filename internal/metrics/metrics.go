@@ -0,0 +1,148 @@
+// Package metrics exposes Prometheus metrics: request latency per chi route,
+// database pool statistics and forum activity counters. Handlers bump the
+// counters below directly; Handler serves everything on /metrics.
+//
+// There are no webhooks in Synbridge yet, so there is no delivery counter;
+// add one next to the others when outgoing webhooks land.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// registry holds every Synbridge metric plus the Go runtime and process ones
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestDuration,
+		PostsCreated,
+		AgentAPIRequests,
+		AuthFailures,
+		RateLimitRejections,
+		FrozenAgentAttempts,
+	)
+}
+
+// Label values of AuthFailures
+const (
+	AuthPassword     = "password"
+	AuthSecondFactor = "second_factor"
+	AuthPasskey      = "passkey"
+	AuthAgentKey     = "agent_key"
+)
+
+// Label values of RateLimitRejections
+const (
+	LimiterLogin         = "login"          // loginguard lockout of a handle or network
+	LimiterSecondFactor  = "second_factor"  // too many codes tried for one sign-in or one human
+	LimiterPasswordReset = "password_reset" // too many reset mails asked for one handle or network
+)
+
+// routeUnmatched labels requests no route matched, e.g. 404s
+const routeUnmatched = "unmatched"
+
+var (
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "synbridge_http_request_duration_seconds",
+		Help:    "HTTP request latency by chi route pattern, method and status.",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"route", "method", "status"})
+
+	// PostsCreated counts posts (thread openers included) by author_type: human or agent
+	PostsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synbridge_posts_created_total",
+		Help: "Posts created, by author type.",
+	}, []string{"author_type"})
+
+	// AgentAPIRequests counts authenticated agent API calls by agent ID
+	AgentAPIRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synbridge_agent_api_requests_total",
+		Help: "Authenticated agent API requests, by agent ID.",
+	}, []string{"agent_id"})
+
+	// AuthFailures counts rejected credentials by kind (Auth* constants)
+	AuthFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synbridge_auth_failures_total",
+		Help: "Rejected credentials, by kind.",
+	}, []string{"kind"})
+
+	// RateLimitRejections counts requests refused by a limiter (Limiter* constants)
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "synbridge_rate_limit_rejections_total",
+		Help: "Requests refused by a rate limiter or lockout, by limiter.",
+	}, []string{"limiter"})
+
+	// FrozenAgentAttempts counts API calls made with a frozen agent's key
+	FrozenAgentAttempts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "synbridge_frozen_agent_attempts_total",
+		Help: "Agent API requests refused because the agent is frozen.",
+	})
+)
+
+// Register adds a collector, such as PoolCollector, to the /metrics output
+func Register(c prometheus.Collector) {
+	registry.MustRegister(c)
+}
+
+// Handler serves the metrics in the Prometheus text format. With a token,
+// scrapers must send it as "Authorization: Bearer <token>"; without one the
+// handler is open and belongs on a private listener.
+func Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Middleware records request latency labelled by the chi route pattern
+// (/threads/{id}, not /threads/42), so label values stay bounded. Must be
+// used on the chi router itself.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		route := routeUnmatched
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		requestDuration.WithLabelValues(route, r.Method, strconv.Itoa(sw.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusWriter remembers the status code written
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector reports pgxpool statistics, read fresh on every scrape
+type PoolCollector struct {
+	pool *pgxpool.Pool
+}
+
+// NewPoolCollector returns a collector for pool; pass it to Register
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	return &PoolCollector{pool: pool}
+}
+
+var (
+	poolAcquiredConns = poolDesc("acquired_conns", "Connections currently in use.")
+	poolIdleConns     = poolDesc("idle_conns", "Idle connections in the pool.")
+	poolTotalConns    = poolDesc("total_conns", "All connections in the pool, including ones being opened.")
+	poolMaxConns      = poolDesc("max_conns", "Most connections the pool will open.")
+	poolAcquires      = poolDesc("acquires_total", "Successful connection acquires.")
+	poolEmptyAcquires = poolDesc("empty_acquires_total", "Acquires that had to wait for a connection.")
+	poolCanceled      = poolDesc("canceled_acquires_total", "Acquires given up before a connection was free.")
+	poolAcquireWait   = poolDesc("acquire_duration_seconds_total", "Time spent waiting for connections.")
+	poolNewConns      = poolDesc("new_conns_total", "Connections opened.")
+	poolLifetimeDrops = poolDesc("max_lifetime_destroys_total", "Connections closed for reaching DB_MAX_CONN_LIFETIME.")
+	poolIdleDrops     = poolDesc("max_idle_destroys_total", "Connections closed for idling past DB_MAX_CONN_IDLE_TIME.")
+)
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc("synbridge_db_pool_"+name, help, nil, nil)
+}
+
+// Describe implements prometheus.Collector
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		poolAcquiredConns, poolIdleConns, poolTotalConns, poolMaxConns,
+		poolAcquires, poolEmptyAcquires, poolCanceled, poolAcquireWait,
+		poolNewConns, poolLifetimeDrops, poolIdleDrops,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(poolAcquiredConns, float64(s.AcquiredConns()))
+	gauge(poolIdleConns, float64(s.IdleConns()))
+	gauge(poolTotalConns, float64(s.TotalConns()))
+	gauge(poolMaxConns, float64(s.MaxConns()))
+	counter(poolAcquires, float64(s.AcquireCount()))
+	counter(poolEmptyAcquires, float64(s.EmptyAcquireCount()))
+	counter(poolCanceled, float64(s.CanceledAcquireCount()))
+	counter(poolAcquireWait, s.AcquireDuration().Seconds())
+	counter(poolNewConns, float64(s.NewConnsCount()))
+	counter(poolLifetimeDrops, float64(s.MaxLifetimeDestroyCount()))
+	counter(poolIdleDrops, float64(s.MaxIdleDestroyCount()))
+}
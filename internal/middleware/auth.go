@@ -1,14 +1,263 @@
 package middleware
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/metrics"
+)
+
+// PrincipalKind says how a request authenticated
+type PrincipalKind int
+
+const (
+	KindHuman    PrincipalKind = iota + 1 // sb_session cookie
+	KindAgent                             // agent API key
+	KindOperator                          // ADMIN_SECRET or an sb_admin cookie
+)
+
+// Roles carried by principals
+const (
+	RoleMember = "member" // every signed-in human
+	RoleAgent  = "agent"  // every authenticated agent
+	RoleAdmin  = "admin"  // operators holding ADMIN_SECRET
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Kind    PrincipalKind
+	Human   *db.Human   // KindHuman
+	Session *db.Session // KindHuman
+	Agent   *db.Agent   // KindAgent
+	Roles   []string
+}
+
+// HasRole reports whether the principal holds role
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && slices.Contains(p.Roles, role)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns ctx carrying p. The request's access log line names p too.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	if rl, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		rl.principal = p
+	}
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the request's principal, or nil for anonymous requests
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// SessionMiddleware resolves the sb_session cookie to the signed-in human once
+// per request. It also slides the session's expiry forward as it is used;
+// remembered sessions get a fresh cookie expiry. Requests without a valid
+// session pass through anonymously.
+func SessionMiddleware(q *db.Queries) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := Cookies.Read(r, SessionCookie)
+			if err != nil || cookie.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := TokenHash(cookie.Value)
+			session, err := q.TouchSession(r.Context(), key, UserAgent(r), IPPrefix(r))
+			if err == nil {
+				if session.Remember {
+					SetSessionCookie(w, cookie.Value, session)
+				}
+			} else if errors.Is(err, pgx.ErrNoRows) {
+				session, err = q.GetSession(r.Context(), key)
+			}
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			human, err := q.GetHumanByID(r.Context(), session.HumanID)
+			if err != nil || human.SuspendedAt != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			p := &Principal{Kind: KindHuman, Human: &human, Session: &session, Roles: []string{RoleMember}}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// AgentAuthMiddleware resolves an "Authorization: Bearer <key>" agent API key.
+// Requests without a bearer token pass through; an unknown or revoked key is
+// rejected outright.
+func AgentAuthMiddleware(q *db.Queries) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rawKey, ok := bearerToken(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			keyHash := TokenHash(rawKey)
+			agent, err := q.GetAgentByKeyHash(r.Context(), keyHash)
+			if err != nil {
+				if frozen, _ := q.IsFrozenAgentKey(r.Context(), keyHash); frozen {
+					metrics.FrozenAgentAttempts.Inc()
+				} else {
+					metrics.AuthFailures.WithLabelValues(metrics.AuthAgentKey).Inc()
+				}
+				writeJSONError(w, http.StatusUnauthorized, "Invalid or revoked key")
+				return
+			}
+			metrics.AgentAPIRequests.WithLabelValues(strconv.Itoa(agent.ID)).Inc()
+
+			p := &Principal{Kind: KindAgent, Agent: &agent, Roles: []string{RoleAgent}}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// Operator browser sessions
+const (
+	operatorCookie     = "sb_admin"
+	OperatorSessionTTL = time.Hour
 )
 
-// SessionMiddleware is a placeholder for session authentication.
-// Currently passes through all requests. Will be implemented in M2.
-func SessionMiddleware(next http.Handler) http.Handler {
+// OperatorMiddleware grants RoleAdmin to requests carrying the admin secret as
+// a bearer token or ?secret=. A browser opening an admin page with ?secret=
+// trades it for a short-lived sb_admin cookie signed with sessionKey and is
+// sent back to the same URL without the secret, so the secret does not stay
+// in history, links or Referer headers. The cookie is SameSite=Strict, and a
+// state-changing request relying on it must come from the site's own pages.
+func OperatorMiddleware(secret string, sessionKey []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, _ := bearerToken(r)
+			ok := secret != "" && secretMatches(token, secret)
+			if !ok && secret != "" && secretMatches(r.URL.Query().Get("secret"), secret) {
+				if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+					c := Cookies.New(operatorCookie, signOperatorSession(sessionKey, time.Now().Add(OperatorSessionTTL)), http.SameSiteStrictMode)
+					c.MaxAge = int(OperatorSessionTTL.Seconds())
+					http.SetCookie(w, c)
+					u := *r.URL
+					q := u.Query()
+					q.Del("secret")
+					u.RawQuery = q.Encode()
+					w.Header().Set("Cache-Control", "no-store")
+					http.Redirect(w, r, u.RequestURI(), http.StatusSeeOther)
+					return
+				}
+				ok = true
+			}
+			if !ok && secret != "" {
+				if c, err := Cookies.Read(r, operatorCookie); err == nil && validOperatorSession(sessionKey, c.Value) {
+					ok = isSafeMethod(r.Method) || r.Header.Get("Sec-Fetch-Site") == "same-origin"
+				}
+			}
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			p := &Principal{Kind: KindOperator, Roles: []string{RoleAdmin}}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// signOperatorSession returns an sb_admin cookie value valid until expires
+func signOperatorSession(key []byte, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + operatorSessionMAC(key, exp)
+}
+
+func validOperatorSession(key []byte, value string) bool {
+	exp, mac, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(operatorSessionMAC(key, exp)))
+}
+
+func operatorSessionMAC(key []byte, exp string) string {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte("operator:" + exp))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// RequireHuman lets only signed-in humans through. Pages redirect to /login;
+// JSON requests get a 401.
+func RequireHuman(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := PrincipalFrom(r.Context()); p == nil || p.Kind != KindHuman {
+			if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+				writeJSONError(w, http.StatusUnauthorized, "not signed in")
+				return
+			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAgent lets only requests with a valid agent API key through
+func RequireAgent(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: Implement session validation in M2
+		if p := PrincipalFrom(r.Context()); p == nil || p.Kind != KindAgent {
+			writeJSONError(w, http.StatusUnauthorized, "Authorization: Bearer <key> required")
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequireRole lets only principals holding role through
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !PrincipalFrom(r.Context()).HasRole(role) {
+				writeJSONError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token, ok && token != ""
+}
+
+func secretMatches(given, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(secret)) == 1
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(`{"error":"` + msg + `"}`))
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOperatorMiddleware(t *testing.T) {
+	const secret = "operator secret"
+	key := []byte("operator session key")
+	h := OperatorMiddleware(secret, key)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !PrincipalFrom(r.Context()).HasRole(RoleAdmin) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// A browser link with ?secret= is traded for a cookie
+	r := httptest.NewRequest(http.MethodGet, "/admin/waitlist?status=all&secret="+strings.ReplaceAll(secret, " ", "+"), nil)
+	r.Header.Set("Accept", "text/html,application/xhtml+xml")
+	w := serve(r)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/admin/waitlist?status=all" {
+		t.Fatalf("secret link: status %d to %q, want a redirect without the secret", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].SameSite != http.SameSiteStrictMode || !cookies[0].HttpOnly {
+		t.Fatalf("secret link set cookies %v, want one strict HttpOnly sb_admin", cookies)
+	}
+	cookie := cookies[0]
+
+	tests := []struct {
+		name   string
+		method string
+		setup  func(*http.Request)
+		want   int
+	}{
+		{"nothing", http.MethodGet, func(*http.Request) {}, http.StatusUnauthorized},
+		{"bearer", http.MethodPost, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+secret) }, http.StatusOK},
+		{"wrong bearer", http.MethodGet, func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"cookie", http.MethodGet, func(r *http.Request) { r.AddCookie(cookie) }, http.StatusOK},
+		{"cookie on a same-origin post", http.MethodPost, func(r *http.Request) {
+			r.AddCookie(cookie)
+			r.Header.Set("Sec-Fetch-Site", "same-origin")
+		}, http.StatusOK},
+		{"cookie on a cross-site post", http.MethodPost, func(r *http.Request) {
+			r.AddCookie(cookie)
+			r.Header.Set("Sec-Fetch-Site", "same-site")
+		}, http.StatusUnauthorized},
+		{"cookie on a post of unknown origin", http.MethodPost, func(r *http.Request) { r.AddCookie(cookie) }, http.StatusUnauthorized},
+		{"expired cookie", http.MethodGet, func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: cookie.Name, Value: signOperatorSession(key, time.Now().Add(-time.Minute))})
+		}, http.StatusUnauthorized},
+		{"cookie signed with another key", http.MethodGet, func(r *http.Request) {
+			r.AddCookie(&http.Cookie{Name: cookie.Name, Value: signOperatorSession([]byte("other"), time.Now().Add(time.Hour))})
+		}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/admin/waitlist", nil)
+			tt.setup(r)
+			if w := serve(r); w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
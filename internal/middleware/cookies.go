@@ -0,0 +1,50 @@
+package middleware
+
+import "net/http"
+
+// CookiePolicy decides the attributes of every cookie the site sets. Cookies
+// are always HttpOnly and scoped to Path=/; SameSite is chosen per cookie.
+type CookiePolicy struct {
+	Secure bool // send only over HTTPS; off for plain-HTTP development
+
+	// HostPrefix names cookies __Host-sb_session and so on. Browsers keep
+	// such cookies only if they are Secure, Path=/ and carry no Domain, so
+	// a sibling subdomain cannot plant or overwrite them. Needs Secure.
+	HostPrefix bool
+}
+
+// Cookies is the policy in force. The server sets it from config at startup.
+var Cookies = CookiePolicy{Secure: true}
+
+// Name is the name a cookie is sent under
+func (p CookiePolicy) Name(name string) string {
+	if p.HostPrefix {
+		return "__Host-" + name
+	}
+	return name
+}
+
+// New returns the cookie name=value. Callers set MaxAge or Expires; without
+// either it lasts until the browser closes.
+func (p CookiePolicy) New(name, value string, sameSite http.SameSite) *http.Cookie {
+	return &http.Cookie{
+		Name:     p.Name(name),
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   p.Secure,
+		SameSite: sameSite,
+	}
+}
+
+// Expire returns a cookie that deletes name
+func (p CookiePolicy) Expire(name string, sameSite http.SameSite) *http.Cookie {
+	c := p.New(name, "", sameSite)
+	c.MaxAge = -1
+	return c
+}
+
+// Read returns the request's cookie name
+func (p CookiePolicy) Read(r *http.Request, name string) (*http.Cookie, error) {
+	return r.Cookie(p.Name(name))
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// CSRF form field, header and the cookie that anchors tokens before sign-in
+const (
+	CSRFField  = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+	csrfCookie = "sb_csrf"
+)
+
+// CSRF issues and checks synchronizer tokens. A token is an HMAC of the
+// request's session (or, before sign-in, of a random sb_csrf cookie), so it
+// needs no storage and dies with the session.
+type CSRF struct {
+	key     []byte
+	failure http.Handler // renders the rejection page
+}
+
+// NewCSRF signs tokens with key, which must serve no other purpose. failure
+// serves rejected form posts; JSON requests get a JSON 403 instead.
+func NewCSRF(key []byte, failure http.Handler) *CSRF {
+	return &CSRF{key: key, failure: failure}
+}
+
+type csrfKey struct{}
+
+// CSRFToken returns the token for forms rendered in this request
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfKey{}).(string)
+	return token
+}
+
+// WithCSRFToken returns ctx carrying token for CSRFToken
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfKey{}, token)
+}
+
+// Protect rejects state-changing requests without a valid token and hands
+// the token to the request's context, where the page templates put it into
+// every POST form and a csrf-token meta tag for scripts. Every request it
+// wraps is checked, whatever headers it carries; the bearer-token APIs are
+// mounted outside it. Must run after SessionMiddleware.
+func (c *CSRF) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var binding string
+		if p := PrincipalFrom(r.Context()); p != nil && p.Kind == KindHuman {
+			binding = "session:" + p.Session.ID
+		} else {
+			anchor := ""
+			if cookie, err := Cookies.Read(r, csrfCookie); err == nil && len(cookie.Value) == 64 {
+				anchor = cookie.Value
+			} else {
+				b := make([]byte, 32)
+				if _, err := rand.Read(b); err != nil {
+					http.Error(w, "Internal error", http.StatusInternalServerError)
+					return
+				}
+				anchor = hex.EncodeToString(b)
+				http.SetCookie(w, Cookies.New(csrfCookie, anchor, http.SameSiteLaxMode))
+			}
+			binding = "anon:" + anchor
+		}
+		token := c.token(binding)
+
+		if !isSafeMethod(r.Method) {
+			given := r.Header.Get(CSRFHeader)
+			if given == "" {
+				given = r.PostFormValue(CSRFField)
+			}
+			if !hmac.Equal([]byte(given), []byte(token)) {
+				if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+					writeJSONError(w, http.StatusForbidden, "CSRF token missing or invalid; reload the page")
+					return
+				}
+				w.Header().Set("Cache-Control", "no-store")
+				c.failure.ServeHTTP(&statusWriter{ResponseWriter: w, status: http.StatusForbidden}, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithCSRFToken(r.Context(), token)))
+	})
+}
+
+func (c *CSRF) token(binding string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(binding))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// statusWriter forces the status code of the failure page
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (s *statusWriter) WriteHeader(int) {
+	if !s.wrote {
+		s.wrote = true
+		s.ResponseWriter.WriteHeader(s.status)
+	}
+}
+
+func (s *statusWriter) Write(b []byte) (int, error) {
+	s.WriteHeader(0)
+	return s.ResponseWriter.Write(b)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers SecurityHeaders
+		hsts    string
+	}{
+		{"no HSTS", SecurityHeaders{}, ""},
+		{"HSTS", SecurityHeaders{HSTSMaxAge: 365 * 24 * time.Hour}, "max-age=31536000"},
+		{"HSTS with subdomains", SecurityHeaders{HSTSMaxAge: 365 * 24 * time.Hour, HSTSIncludeSubdomains: true}, "max-age=31536000; includeSubDomains"},
+		{"subdomains without HSTS", SecurityHeaders{HSTSIncludeSubdomains: true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var nonce string
+			w := httptest.NewRecorder()
+			tt.headers.Set(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				nonce = CSPNonce(r.Context())
+			})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := w.Header().Get("Strict-Transport-Security"); got != tt.hsts {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.hsts)
+			}
+			csp := w.Header().Get("Content-Security-Policy")
+			if nonce == "" || !strings.Contains(csp, "'nonce-"+nonce+"'") {
+				t.Errorf("policy %q does not carry the request's nonce %q", csp, nonce)
+			}
+			if strings.Contains(csp, "unsafe-inline") {
+				t.Errorf("policy allows inline styles or scripts: %q", csp)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// String names the kind in logs
+func (k PrincipalKind) String() string {
+	switch k {
+	case KindHuman:
+		return "human"
+	case KindAgent:
+		return "agent"
+	case KindOperator:
+		return "operator"
+	}
+	return "unknown"
+}
+
+// redactedParams are query parameters that carry credentials: the admin
+// secret, a freshly minted agent key on /agents, and reset, verification
+// and download tokens. Their values never reach the logs.
+var redactedParams = []string{"secret", "key", "token", "sig", "code", "state"}
+
+// requestLog is what AccessLog shares with the handlers below it
+type requestLog struct {
+	logger    *slog.Logger // carries the request and trace IDs
+	principal *Principal   // set by WithPrincipal once the caller is known
+}
+
+type requestLogKey struct{}
+
+// AccessLog gives every request a logger carrying chi's request ID and the
+// trace ID (see Log) and writes one JSON line per request once it is done.
+// Must run after chi's RequestID, then RealIP and the tracing middleware.
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		logger := slog.Default().With("request_id", chimiddleware.GetReqID(r.Context()))
+		if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+			logger = logger.With("trace_id", sc.TraceID().String())
+		}
+		rl := &requestLog{logger: logger}
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), requestLogKey{}, rl)))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		rl.with(rl.principal).LogAttrs(r.Context(), level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", RedactedURI(r.URL)),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("remote_ip", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		)
+	})
+}
+
+// Log returns the request-scoped logger: request ID, and the principal's
+// type and ID once authenticated. Outside a request it is slog's default.
+func Log(ctx context.Context) *slog.Logger {
+	rl, ok := ctx.Value(requestLogKey{}).(*requestLog)
+	if !ok {
+		return slog.Default()
+	}
+	return rl.with(PrincipalFrom(ctx))
+}
+
+func (rl *requestLog) with(p *Principal) *slog.Logger {
+	if p == nil {
+		return rl.logger
+	}
+	l := rl.logger.With("principal_type", p.Kind.String())
+	switch {
+	case p.Human != nil:
+		l = l.With("principal_id", strconv.Itoa(p.Human.ID))
+	case p.Agent != nil:
+		l = l.With("principal_id", strconv.Itoa(p.Agent.ID))
+	}
+	return l
+}
+
+// RedactedURI is u's path and query with credential parameters blanked
+func RedactedURI(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Path
+	}
+	q := u.Query()
+	for _, name := range redactedParams {
+		if q.Has(name) {
+			q.Set(name, "REDACTED")
+		}
+	}
+	return u.Path + "?" + q.Encode()
+}
+
+// Recoverer turns a panic into a 500 and logs it, with the stack, through the
+// request's logger
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			Log(r.Context()).Error("panic", "err", fmt.Sprint(rec), "stack", string(debug.Stack()))
+			if r.Header.Get("Connection") != "Upgrade" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
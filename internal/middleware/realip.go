@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP replaces r.RemoteAddr with the client's address as reported by a
+// trusted reverse proxy. X-Forwarded-For and X-Real-IP are only believed on
+// connections from trusted; anyone else could put any address there and
+// pick the network that sign-in limits and sessions are tied to.
+//
+// X-Forwarded-For is read from the right, skipping trusted hops: the first
+// address a trusted proxy did not add itself is the client.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		for _, p := range trusted {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			peer, err := netip.ParseAddr(host)
+			if err != nil || !isTrusted(peer) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := ""
+			if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+				hops := strings.Split(strings.Join(xff, ","), ",")
+				for i := len(hops) - 1; i >= 0; i-- {
+					addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+					if err != nil {
+						break // garbage: trust nothing further left
+					}
+					client = addr.String()
+					if !isTrusted(addr) {
+						break
+					}
+				}
+			} else if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+				client = addr.String()
+			}
+			if client != "" {
+				r.RemoteAddr = client
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+	}
+	tests := []struct {
+		name    string
+		peer    string
+		headers map[string]string
+		want    string
+	}{
+		{"no proxy", "203.0.113.7:5555", nil, "203.0.113.7:5555"},
+		{"spoofed forwarded-for from a client", "203.0.113.7:5555", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7:5555"},
+		{"spoofed real-ip from a client", "203.0.113.7:5555", map[string]string{"X-Real-IP": "198.51.100.1"}, "203.0.113.7:5555"},
+		{"trusted proxy", "127.0.0.1:40000", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy, real-ip", "127.0.0.1:40000", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"client prepends a fake hop", "127.0.0.1:40000", map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "127.0.0.1:40000", map[string]string{"X-Forwarded-For": "198.51.100.1, 10.1.2.3"}, "198.51.100.1"},
+		{"IPv6 client", "127.0.0.1:40000", map[string]string{"X-Forwarded-For": "2001:db8::1"}, "2001:db8::1"},
+		{"garbage hop", "127.0.0.1:40000", map[string]string{"X-Forwarded-For": "198.51.100.1, nonsense"}, "127.0.0.1:40000"},
+		{"trusted proxy without headers", "127.0.0.1:40000", nil, "127.0.0.1:40000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := RealIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r.RemoteAddr }))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.peer
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
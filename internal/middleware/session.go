@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+)
+
+// SessionCookie is the name of the human session cookie
+const SessionCookie = "sb_session"
+
+// maxUserAgent bounds the user agent stored with a session
+const maxUserAgent = 300
+
+// SetSessionCookie writes the session cookie. Remembered sessions persist until
+// the session's expiry; others last until the browser closes.
+func SetSessionCookie(w http.ResponseWriter, token string, s db.Session) {
+	c := Cookies.New(SessionCookie, token, http.SameSiteLaxMode)
+	if s.Remember {
+		c.Expires = s.ExpiresAt
+	}
+	http.SetCookie(w, c)
+}
+
+// ClearSessionCookie removes the session cookie
+func ClearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, Cookies.Expire(SessionCookie, http.SameSiteLaxMode))
+}
+
+// TokenHash returns the stored form (hex sha256) of a session token, API key
+// or any other secret token
+func TokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// UserAgent returns the request's user agent, truncated for storage
+func UserAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgent {
+		ua = ua[:maxUserAgent]
+	}
+	return ua
+}
+
+// IPPrefix returns the client's network rather than its address: the /24 for
+// IPv4 and the /48 for IPv6. Expects RealIP to have run.
+func IPPrefix(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.String()
+}
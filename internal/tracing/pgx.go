@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"runtime"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer is a pgx tracer that records a client span per query and per
+// pool acquire. Spans are named after the db.Queries method that ran the
+// query (db.GetThread, db.ListPosts), so a trace reads like the handler's
+// code. Queries outside a recorded trace, such as background jobs, are not
+// traced. Set it as the pool's ConnConfig.Tracer.
+type QueryTracer struct{}
+
+var (
+	_ pgx.QueryTracer       = QueryTracer{}
+	_ pgxpool.AcquireTracer = QueryTracer{}
+)
+
+// TraceQueryStart implements pgx.QueryTracer
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx
+	}
+	ctx, _ = tracer().Start(ctx, queryName(data.SQL), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBQueryText(data.SQL),
+		))
+	return ctx
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	if data.Err != nil && data.Err != pgx.ErrNoRows {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, "query failed")
+	}
+	span.End()
+}
+
+// TraceAcquireStart implements pgxpool.AcquireTracer: time spent waiting for
+// a free connection shows up as its own span
+func (QueryTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx
+	}
+	ctx, _ = tracer().Start(ctx, "db.pool.acquire")
+	return ctx
+}
+
+// TraceAcquireEnd implements pgxpool.AcquireTracer
+func (QueryTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, "acquire failed")
+	}
+	span.End()
+}
+
+// queryName names a query span after the db.Queries method on the stack, or
+// after the SQL verb when the query was not run through one
+func queryName(sql string) string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		f, more := frames.Next()
+		if _, method, ok := strings.Cut(f.Function, "/internal/db.(*Queries)."); ok {
+			method, _, _ = strings.Cut(method, ".") // closures: GetThread.func1
+			return "db." + method
+		}
+		if !more {
+			break
+		}
+	}
+	verb, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	return "db." + strings.ToUpper(verb)
+}
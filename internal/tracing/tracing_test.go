@@ -0,0 +1,169 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/BioAILogic/agentbridge/internal/db"
+	"github.com/BioAILogic/agentbridge/internal/db/dbtest"
+)
+
+// setupInMemory installs a tracer provider that keeps every span in the
+// returned exporter until the test ends
+func setupInMemory(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(serviceResource()),
+	)
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() {
+		tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+// serve sends one GET through Middleware on a chi router whose only route is
+// pattern
+func serve(pattern, target string, h http.HandlerFunc) *http.Request {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get(pattern, h)
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	return req
+}
+
+// span returns the one span called name
+func span(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	var found []tracetest.SpanStub
+	for _, s := range spans {
+		if s.Name == name {
+			found = append(found, s)
+		}
+	}
+	if len(found) != 1 {
+		var names []string
+		for _, s := range spans {
+			names = append(names, s.Name)
+		}
+		t.Fatalf("%d spans called %q among %v", len(found), name, names)
+	}
+	return found[0]
+}
+
+func checkChild(t *testing.T, parent, child tracetest.SpanStub) {
+	t.Helper()
+	if child.Parent.SpanID() != parent.SpanContext.SpanID() || child.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+		t.Errorf("%s is not a child of %s", child.Name, parent.Name)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := setupInMemory(t)
+
+	serve("/api/threads/{id}", "/api/threads/7", func(w http.ResponseWriter, r *http.Request) {
+		// what pgx does around a query made with the request's context
+		ctx := QueryTracer{}.TraceQueryStart(r.Context(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+		QueryTracer{}.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	spans := exporter.GetSpans()
+	server := span(t, spans, "GET /api/threads/{id}")
+	if server.SpanKind != trace.SpanKindServer {
+		t.Errorf("server span kind = %v", server.SpanKind)
+	}
+	attrs := map[string]string{}
+	for _, a := range server.Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	for k, v := range map[string]string{"http.route": "/api/threads/{id}", "url.path": "/api/threads/7", "http.response.status_code": "404"} {
+		if attrs[k] != v {
+			t.Errorf("%s = %q, want %q", k, attrs[k], v)
+		}
+	}
+
+	query := span(t, spans, "db.SELECT")
+	if query.SpanKind != trace.SpanKindClient {
+		t.Errorf("query span kind = %v", query.SpanKind)
+	}
+	checkChild(t, server, query)
+}
+
+func TestMiddlewareContinuesCallerTrace(t *testing.T) {
+	exporter := setupInMemory(t)
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/", func(http.ResponseWriter, *http.Request) {})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	server := span(t, exporter.GetSpans(), "GET /")
+	if got := server.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the caller's", got)
+	}
+	if got := server.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span = %s, want the caller's", got)
+	}
+}
+
+func TestQueryOutsideTrace(t *testing.T) {
+	exporter := setupInMemory(t)
+	ctx := QueryTracer{}.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	QueryTracer{}.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("background query recorded %d spans", len(spans))
+	}
+}
+
+// TestQuerySpans runs a real query through pgx, so the span is named after
+// the db.Queries method and hangs under the request
+func TestQuerySpans(t *testing.T) {
+	cfg := dbtest.Migrated(t).Config()
+	cfg.ConnConfig.Tracer = QueryTracer{}
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	q := db.New(pool)
+
+	exporter := setupInMemory(t)
+	serve("/spaces", "/spaces", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := q.ListSpaces(r.Context()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	spans := exporter.GetSpans()
+	server := span(t, spans, "GET /spaces")
+	query := span(t, spans, "db.ListSpaces")
+	checkChild(t, server, query)
+	checkChild(t, server, span(t, spans, "db.pool.acquire"))
+	for _, a := range query.Attributes {
+		if a.Key == "db.query.text" && a.Value.AsString() == "" {
+			t.Error("query span lacks the SQL")
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package fakeidp is a stand-in for X's OAuth 2.0 provider. Its consent page
+// lets you sign in as any username, and it enforces what the real provider
+// does for public clients: registered redirect URI, one-time codes and the
+// PKCE S256 check. Never expose it outside development.
+package fakeidp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"hash/fnv"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths the server answers on, mirroring X's
+const (
+	AuthorizePath = "/i/oauth2/authorize"
+	TokenPath     = "/2/oauth2/token"
+	UserInfoPath  = "/2/users/me"
+)
+
+const codeTTL = 5 * time.Minute
+
+type grant struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	username    string
+	expiresAt   time.Time
+}
+
+// Server is an in-memory identity provider
+type Server struct {
+	ClientID    string // accept only this client; empty accepts any
+	RedirectURI string // accept only this callback; empty accepts any
+
+	mu     sync.Mutex
+	codes  map[string]grant
+	tokens map[string]string // access token → username
+}
+
+// New returns a Server that accepts clientID and redirectURI (empty for any)
+func New(clientID, redirectURI string) *Server {
+	return &Server{
+		ClientID:    clientID,
+		RedirectURI: redirectURI,
+		codes:       map[string]grant{},
+		tokens:      map[string]string{},
+	}
+}
+
+// ServeHTTP routes to the authorize, token and users/me endpoints
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case AuthorizePath:
+		s.authorize(w, r)
+	case TokenPath:
+		s.token(w, r)
+	case UserInfoPath:
+		s.userInfo(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorize shows the consent page (GET) and issues a code (POST)
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if r.Method == http.MethodPost {
+		r.ParseForm()
+		q = r.Form
+	}
+	clientID, redirectURI, state := q.Get("client_id"), q.Get("redirect_uri"), q.Get("state")
+	challenge, method := q.Get("code_challenge"), q.Get("code_challenge_method")
+
+	if q.Get("response_type") != "code" || clientID == "" || redirectURI == "" {
+		http.Error(w, "invalid_request: response_type=code, client_id and redirect_uri are required", http.StatusBadRequest)
+		return
+	}
+	if (s.ClientID != "" && clientID != s.ClientID) || (s.RedirectURI != "" && redirectURI != s.RedirectURI) {
+		http.Error(w, "unauthorized_client: unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if challenge == "" || method != "S256" {
+		http.Error(w, "invalid_request: PKCE with code_challenge_method=S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		hidden := ""
+		for _, k := range []string{"response_type", "client_id", "redirect_uri", "state", "code_challenge", "code_challenge_method", "scope"} {
+			hidden += `<input type="hidden" name="` + k + `" value="` + html.EscapeString(q.Get(k)) + `">`
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="UTF-8"><title>Fake X — authorize</title></head>
+<body style="font-family:sans-serif;max-width:420px;margin:4rem auto;">
+<h1>Fake X sign-in</h1>
+<p><code>` + html.EscapeString(clientID) + `</code> wants to know who you are (` + html.EscapeString(q.Get("scope")) + `).</p>
+<form method="POST" action="` + AuthorizePath + `">
+  ` + hidden + `
+  <label>Username <input name="username" autofocus required></label>
+  <button type="submit" name="decision" value="allow">Authorize</button>
+  <button type="submit" name="decision" value="deny" formnovalidate>Cancel</button>
+</form>
+</body>
+</html>`))
+		return
+	}
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid_request: bad redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := target.Query()
+	params.Set("state", state)
+	if q.Get("decision") != "allow" {
+		params.Set("error", "access_denied")
+		target.RawQuery = params.Encode()
+		http.Redirect(w, r, target.String(), http.StatusFound)
+		return
+	}
+	username := strings.TrimPrefix(strings.TrimSpace(q.Get("username")), "@")
+	if username == "" {
+		http.Error(w, "username required", http.StatusBadRequest)
+		return
+	}
+
+	code := randomToken()
+	s.mu.Lock()
+	s.codes[code] = grant{clientID, redirectURI, challenge, username, time.Now().Add(codeTTL)}
+	s.mu.Unlock()
+	params.Set("code", code)
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// token redeems a code for an access token
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.ParseForm()
+	clientID := r.PostForm.Get("client_id")
+	if id, _, ok := r.BasicAuth(); ok {
+		clientID = id
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	g, ok := s.codes[code]
+	delete(s.codes, code) // codes work once, even when the exchange fails
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case !ok || time.Now().After(g.expiresAt):
+		tokenError(w, "invalid_grant")
+	case g.clientID != clientID || g.redirectURI != r.PostForm.Get("redirect_uri"):
+		tokenError(w, "invalid_grant")
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge:
+		tokenError(w, "invalid_grant")
+	default:
+		access := randomToken()
+		s.mu.Lock()
+		s.tokens[access] = g.username
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"token_type":   "bearer",
+			"access_token": access,
+			"expires_in":   7200,
+			"scope":        "users.read tweet.read",
+		})
+	}
+}
+
+// userInfo answers like X API v2 GET /2/users/me
+func (s *Server) userInfo(w http.ResponseWriter, r *http.Request) {
+	access, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	username, ok := s.tokens[access]
+	s.mu.Unlock()
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"title":"Unauthorized","status":401}`))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"data": map[string]string{"id": AccountID(username), "username": username},
+	})
+}
+
+// AccountID is the stable numeric id the fake provider gives username
+func AccountID(username string) string {
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToLower(username)))
+	return strconv.FormatUint(h.Sum64()>>1, 10)
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
+
+func randomToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,130 @@
+// Package xverify proves that a human controls the X (Twitter) handle their
+// invitation was bound to. It runs an OAuth 2.0 authorization code flow with
+// PKCE against a configurable identity provider, asks it who signed in and
+// throws the tokens away: only the answer is kept, as a receipt.
+//
+// The endpoints default to X's own. Point them at a fake IdP (see
+// xverify/fakeidp and cmd/fakeidp) for development and tests.
+package xverify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Endpoints of X's OAuth 2.0 provider
+const (
+	DefaultAuthURL     = "https://x.com/i/oauth2/authorize"
+	DefaultTokenURL    = "https://api.x.com/2/oauth2/token"
+	DefaultUserInfoURL = "https://api.x.com/2/users/me"
+)
+
+// Provider names the identity provider in verification receipts
+const Provider = "x"
+
+// Config describes the identity provider and this app's registration with it
+type Config struct {
+	ClientID     string
+	ClientSecret string // empty for public clients; PKCE protects the code either way
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	RedirectURL  string // this app's callback, registered with the provider
+}
+
+// Account is what the provider says about the signed-in user
+type Account struct {
+	ID       string // stable account id; survives renames
+	Username string // handle without the @
+}
+
+// Verifier runs the verification flow
+type Verifier struct {
+	oauth       *oauth2.Config
+	userInfoURL string
+	client      *http.Client
+}
+
+// New returns a Verifier for cfg, filling in X's endpoints where unset
+func New(cfg Config) *Verifier {
+	if cfg.AuthURL == "" {
+		cfg.AuthURL = DefaultAuthURL
+	}
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = DefaultTokenURL
+	}
+	if cfg.UserInfoURL == "" {
+		cfg.UserInfoURL = DefaultUserInfoURL
+	}
+	return &Verifier{
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			Endpoint:     oauth2.Endpoint{AuthURL: cfg.AuthURL, TokenURL: cfg.TokenURL},
+			RedirectURL:  cfg.RedirectURL,
+			// X only serves /2/users/me to tokens that can also read posts.
+			// No offline.access: there is never a refresh token to keep.
+			Scopes: []string{"users.read", "tweet.read"},
+		},
+		userInfoURL: cfg.UserInfoURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewCodeVerifier returns a fresh PKCE code verifier to keep until the callback
+func NewCodeVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// AuthURL returns where to send the human: the provider's consent page for
+// state, with the S256 challenge of verifier
+func (v *Verifier) AuthURL(state, verifier string) string {
+	return v.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange redeems the authorization code and returns the account that
+// granted it. The access token is used once and dropped.
+func (v *Verifier) Exchange(ctx context.Context, code, verifier string) (Account, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, v.client)
+	token, err := v.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Account{}, fmt.Errorf("exchange code: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.userInfoURL, nil)
+	if err != nil {
+		return Account{}, err
+	}
+	token.SetAuthHeader(req)
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return Account{}, fmt.Errorf("fetch account: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Account{}, fmt.Errorf("fetch account: %s", resp.Status)
+	}
+
+	// X API v2 shape: {"data": {"id": "...", "username": "..."}}
+	var body struct {
+		Data struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body); err != nil {
+		return Account{}, fmt.Errorf("decode account: %w", err)
+	}
+	if body.Data.ID == "" || body.Data.Username == "" {
+		return Account{}, errors.New("provider returned no account")
+	}
+	return Account{ID: body.Data.ID, Username: strings.TrimPrefix(body.Data.Username, "@")}, nil
+}
@@ -0,0 +1,139 @@
+package xverify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/BioAILogic/agentbridge/internal/xverify/fakeidp"
+)
+
+const (
+	testClientID = "synbridge-test"
+	testCallback = "https://synbridge.test/settings/x/callback"
+)
+
+// newTestVerifier starts a fake IdP and returns a Verifier pointed at it
+func newTestVerifier(t *testing.T) *Verifier {
+	t.Helper()
+	idp := httptest.NewServer(fakeidp.New(testClientID, testCallback))
+	t.Cleanup(idp.Close)
+	return New(Config{
+		ClientID:    testClientID,
+		AuthURL:     idp.URL + fakeidp.AuthorizePath,
+		TokenURL:    idp.URL + fakeidp.TokenPath,
+		UserInfoURL: idp.URL + fakeidp.UserInfoPath,
+		RedirectURL: testCallback,
+	})
+}
+
+// consent submits the fake IdP's consent page for authURL as username and
+// returns the callback the human is sent back to
+func consent(t *testing.T, authURL, username string) url.Values {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := u.Query()
+	form.Set("username", username)
+	form.Set("decision", "allow")
+	u.RawQuery = ""
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.PostForm(u.String(), form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("consent: status %d", resp.StatusCode)
+	}
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := back.Scheme + "://" + back.Host + back.Path; got != testCallback {
+		t.Fatalf("sent back to %s, want %s", got, testCallback)
+	}
+	return back.Query()
+}
+
+func TestAuthURLCarriesPKCE(t *testing.T) {
+	v := newTestVerifier(t)
+	verifier := NewCodeVerifier()
+	u, err := url.Parse(v.AuthURL("the-state", verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	sum := sha256.Sum256([]byte(verifier))
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testCallback,
+		"state":                 "the-state",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+		"code_challenge_method": "S256",
+		"scope":                 "users.read tweet.read",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s = %q, want %q", k, q.Get(k), v)
+		}
+	}
+	if strings.Contains(u.RawQuery, verifier) {
+		t.Error("the code verifier itself is in the authorization URL")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("verified", func(t *testing.T) {
+		v := newTestVerifier(t)
+		verifier := NewCodeVerifier()
+		back := consent(t, v.AuthURL("s1", verifier), "@Alice")
+		if back.Get("state") != "s1" {
+			t.Fatalf("state = %q, want s1", back.Get("state"))
+		}
+		account, err := v.Exchange(ctx, back.Get("code"), verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account != (Account{ID: fakeidp.AccountID("Alice"), Username: "Alice"}) {
+			t.Errorf("account = %+v", account)
+		}
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		v := newTestVerifier(t)
+		back := consent(t, v.AuthURL("s1", NewCodeVerifier()), "alice")
+		if _, err := v.Exchange(ctx, back.Get("code"), NewCodeVerifier()); err == nil {
+			t.Fatal("code redeemed with another flow's verifier")
+		}
+	})
+
+	t.Run("code used twice", func(t *testing.T) {
+		v := newTestVerifier(t)
+		verifier := NewCodeVerifier()
+		back := consent(t, v.AuthURL("s1", verifier), "alice")
+		if _, err := v.Exchange(ctx, back.Get("code"), verifier); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := v.Exchange(ctx, back.Get("code"), verifier); err == nil {
+			t.Fatal("code redeemed twice")
+		}
+	})
+
+	t.Run("made-up code", func(t *testing.T) {
+		v := newTestVerifier(t)
+		if _, err := v.Exchange(ctx, "not-a-code", NewCodeVerifier()); err == nil {
+			t.Fatal("made-up code redeemed")
+		}
+	})
+}
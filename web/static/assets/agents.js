@@ -0,0 +1,12 @@
+// Copy buttons on the new agent banner: data-copy names the element whose
+// text goes to the clipboard.
+document.querySelectorAll('[data-copy]').forEach(function (btn) {
+  var label = btn.textContent;
+  btn.addEventListener('click', function () {
+    var text = document.getElementById(btn.dataset.copy).innerText;
+    navigator.clipboard.writeText(text).then(function () {
+      btn.textContent = 'Copied!';
+      setTimeout(function () { btn.textContent = label; }, 2000);
+    });
+  });
+});
@@ -0,0 +1,18 @@
+// Login and registration pages: show ?error= and ?notice= messages, and
+// prefill an invitation link (/register?code=...&handle=...).
+(function () {
+  const params = new URLSearchParams(window.location.search);
+  for (const name of ['error', 'notice']) {
+    const el = document.getElementById(name);
+    const msg = params.get(name);
+    if (el && msg) {
+      el.textContent = msg.replace(/\+/g, ' ');
+      el.classList.add('visible');
+    }
+  }
+  const code = document.getElementById('invite_code');
+  if (code) {
+    if (params.get('code')) code.value = params.get('code');
+    if (params.get('handle')) document.getElementById('handle').value = params.get('handle');
+  }
+})();
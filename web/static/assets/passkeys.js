@@ -0,0 +1,105 @@
+// Passkey sign-in (login page) and registration (settings page).
+// The server speaks WebAuthn JSON with base64url-encoded binary fields.
+(function () {
+  if (!window.PublicKeyCredential) return;
+
+  function toBytes(s) {
+    s = s.replace(/-/g, '+').replace(/_/g, '/');
+    while (s.length % 4) s += '=';
+    return Uint8Array.from(atob(s), c => c.charCodeAt(0));
+  }
+  function toB64url(buf) {
+    let s = '';
+    new Uint8Array(buf).forEach(b => { s += String.fromCharCode(b); });
+    return btoa(s).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+  }
+
+  function csrfToken() {
+    const meta = document.querySelector('meta[name="csrf-token"]');
+    return meta ? meta.content : '';
+  }
+
+  async function post(url, body) {
+    const res = await fetch(url, {
+      method: 'POST',
+      credentials: 'same-origin',
+      headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken() },
+      body: JSON.stringify(body || {})
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) throw new Error(data.error || 'Request failed');
+    return data;
+  }
+
+  function showError(el, msg) {
+    if (!el) { alert(msg); return; }
+    el.textContent = msg;
+    el.classList.add('visible');
+  }
+
+  // Sign in
+  const loginBtn = document.getElementById('passkey-login');
+  if (loginBtn) {
+    loginBtn.hidden = false;
+    loginBtn.addEventListener('click', async () => {
+      const errorEl = document.getElementById('error');
+      try {
+        const remember = document.getElementById('remember');
+        const opts = await post('/login/passkey/begin', { remember: !!(remember && remember.checked) });
+        const pk = opts.publicKey;
+        pk.challenge = toBytes(pk.challenge);
+        (pk.allowCredentials || []).forEach(c => { c.id = toBytes(c.id); });
+        const cred = await navigator.credentials.get({ publicKey: pk });
+        const done = await post('/login/passkey/finish', {
+          id: cred.id,
+          rawId: toB64url(cred.rawId),
+          type: cred.type,
+          response: {
+            clientDataJSON: toB64url(cred.response.clientDataJSON),
+            authenticatorData: toB64url(cred.response.authenticatorData),
+            signature: toB64url(cred.response.signature),
+            userHandle: cred.response.userHandle ? toB64url(cred.response.userHandle) : null
+          }
+        });
+        window.location = done.redirect;
+      } catch (e) {
+        showError(errorEl, e.name === 'NotAllowedError' ? 'Passkey sign-in was cancelled.' : e.message);
+      }
+    });
+  }
+
+  // Register
+  const form = document.getElementById('passkey-form');
+  if (form) {
+    form.addEventListener('submit', async ev => {
+      ev.preventDefault();
+      const errorEl = document.getElementById('passkey-error');
+      try {
+        const field = name => form.elements[name] ? form.elements[name].value : '';
+        const opts = await post('/settings/passkeys/begin', {
+          name: document.getElementById('passkey-name').value,
+          password: field('password'),
+          totp_code: field('totp_code')
+        });
+        const pk = opts.publicKey;
+        pk.challenge = toBytes(pk.challenge);
+        pk.user.id = toBytes(pk.user.id);
+        (pk.excludeCredentials || []).forEach(c => { c.id = toBytes(c.id); });
+        const cred = await navigator.credentials.create({ publicKey: pk });
+        const done = await post('/settings/passkeys/finish', {
+          id: cred.id,
+          rawId: toB64url(cred.rawId),
+          type: cred.type,
+          response: {
+            clientDataJSON: toB64url(cred.response.clientDataJSON),
+            attestationObject: toB64url(cred.response.attestationObject),
+            transports: cred.response.getTransports ? cred.response.getTransports() : []
+          }
+        });
+        window.location = done.redirect;
+      } catch (e) {
+        showError(errorEl, e.name === 'InvalidStateError' ? 'This device already has a passkey for your account.' : e.message);
+      }
+    });
+  }
+})();
@@ -0,0 +1,14 @@
+// Reply buttons prefix the reply box with @author and scroll to it.
+document.querySelectorAll('.reply-btn[data-author]').forEach(function (btn) {
+  btn.addEventListener('click', function () {
+    var ta = document.getElementById('reply-textarea');
+    if (!ta) return;
+    var prefix = '@' + btn.dataset.author + ' ';
+    if (!ta.value.startsWith(prefix)) {
+      ta.value = prefix + ta.value;
+    }
+    ta.focus();
+    ta.setSelectionRange(ta.value.length, ta.value.length);
+    document.getElementById('reply-section').scrollIntoView({behavior: 'smooth', block: 'start'});
+  });
+});
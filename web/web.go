@@ -0,0 +1,22 @@
+// Package web holds the HTML templates and static assets, compiled into the
+// binary so a deploy is the binary alone.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// Templates are the page templates under templates/: layout.html and
+// partials.html shared by every page, plus one file per page. The public
+// pages (landing, FAQ, login, register) stand alone under templates/public.
+//
+//go:embed templates
+var Templates embed.FS
+
+//go:embed static
+var static embed.FS
+
+// Static is the static/ tree; assets/ holds the stylesheets, scripts and
+// images served under /assets
+var Static, _ = fs.Sub(static, "static")